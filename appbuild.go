@@ -2,8 +2,13 @@ package modmake
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/saylorsolutions/modmake/assert"
@@ -45,22 +50,58 @@ func (pf AppPackageFunc) Then(other AppPackageFunc) AppPackageFunc {
 }
 
 // PackageTar will package the binary into a tar.gz.
+// When used with an [AppBuild], the other binaries and included files (see [AppBuild.Binary] and [AppBuild.Include]) are packaged with it, but nothing else in the build directory is.
+// A different compression may be passed, like [TarZstd] to create a tar.zst, and the archive's extension will match.
 // This is the default for non-windows builds.
-func PackageTar() AppPackageFunc {
+//
+// Creating a .tar.zst or .tar.xz runs the host's "zstd" or "xz" executable, so packaging depends on that tool being installed on the host running the build.
+// If the executable isn't on the PATH, then packaging fails with [ErrCompressorNotFound] before the archive is created.
+func PackageTar(compression ...TarCompression) AppPackageFunc {
+	if len(compression) > 1 {
+		panic("only one compression may be specified")
+	}
+	comp := TarGzip
+	if len(compression) == 1 {
+		compression[0].validate()
+		if compression[0] == TarBzip2 {
+			panic("bzip2 compression is only supported for extraction")
+		}
+		comp = compression[0]
+	}
 	return func(binaryPath, destDir PathString, app, variant, version string) Task {
-		return Tar(destDir.Join(fmt.Sprintf("%s_%s_%s.tar.gz", app, variant, version))).
-			AddFileWithPath(binaryPath, binaryPath.Base()).
-			Create()
+		return func(ctx context.Context) error {
+			tarball := Tar(destDir.Join(fmt.Sprintf("%s_%s_%s%s", app, variant, version, comp.Ext()))).
+				Compression(comp)
+			dir := binaryPath.Dir()
+			for _, path := range packageContents(ctx, binaryPath) {
+				if source := dir.JoinPath(path); source.IsDir() {
+					tarball.AddDir(source, path)
+				} else {
+					tarball.AddFileWithPath(source, path)
+				}
+			}
+			return tarball.Create().Run(ctx)
+		}
 	}
 }
 
 // PackageZip will package the binary into a zip.
+// When used with an [AppBuild], the other binaries and included files (see [AppBuild.Binary] and [AppBuild.Include]) are packaged with it, but nothing else in the build directory is.
 // This is the default for windows builds.
 func PackageZip() AppPackageFunc {
 	return func(binaryPath, destDir PathString, app, variant, version string) Task {
-		return Zip(destDir.Join(fmt.Sprintf("%s_%s_%s.zip", app, variant, version))).
-			AddFileWithPath(binaryPath, binaryPath.Base()).
-			Create()
+		return func(ctx context.Context) error {
+			zipFile := Zip(destDir.Join(fmt.Sprintf("%s_%s_%s.zip", app, variant, version)))
+			dir := binaryPath.Dir()
+			for _, path := range packageContents(ctx, binaryPath) {
+				if source := dir.JoinPath(path); source.IsDir() {
+					zipFile.AddDir(source, path)
+				} else {
+					zipFile.AddFileWithPath(source, path)
+				}
+			}
+			return zipFile.Create().Run(ctx)
+		}
 	}
 }
 
@@ -78,14 +119,40 @@ func PackageGoInstall() AppPackageFunc {
 // AppBuild is a somewhat opinionated abstraction over the common pattern of building a static executable, including packaging.
 // The build step may be customized as needed, and different OS/Arch variants may be created as needed.
 // Each built executable will be output to ${MODROOT}/build/${APP}_${VARIANT_NAME}/${APP}
-// Default packaging will write a zip or tar.gz to ${MODROOT}/dist/${APP}/${APP}_${VARIANT_NAME}_${VERSION}.(zip|tar.gz)
+// Default packaging will write a zip or tar.gz to ${MODROOT}/dist/${APP}/${APP}_${VARIANT_NAME}_${VERSION}.(zip|tar.gz), see [PackageTar] for other compression options.
 // Each variant may override or remove its packaging step.
+//
+// Additional executables may be built alongside the primary executable with [AppBuild.Binary], and extra files may be packaged with [AppBuild.Include].
 type AppBuild struct {
 	mainPath, version  string
 	buildFunc          AppBuildFunc
 	variants           []*AppVariant
 	appName            string
 	installPackageFunc AppPackageFunc
+	winres             *WindowsResources
+	extraBinaries      []appBinary
+	files              []appFile
+	pgo                string
+	pgoWorkflow        *PGOWorkflow
+	sizeBaselineDir    PathString
+	sizeBudget         sizeBudget
+}
+
+// sizeBudget holds the limits applied by a SizeCheck.
+type sizeBudget struct {
+	maxBytes  int64
+	maxGrowth float64
+}
+
+// appBinary is an executable built from a main package.
+type appBinary struct {
+	name, mainPath string
+}
+
+// appFile is a file or directory staged alongside built executables.
+type appFile struct {
+	source, archivePath PathString
+	mode                fs.FileMode
 }
 
 // NewAppBuild creates a new AppBuild with the given details.
@@ -131,6 +198,114 @@ func (a *AppBuild) Install(pf AppPackageFunc) *AppBuild {
 	return a
 }
 
+// Binary adds another main package that will be built for every variant, alongside the primary executable.
+// The executable will be named after the given name, with a ".exe" suffix for windows variants, and will be packaged with the primary executable.
+// If mainPath is not prefixed with the module name, then it will be added.
+func (a *AppBuild) Binary(name, mainPath string) *AppBuild {
+	assert.NotEmpty(&name)
+	assert.NotEmpty(&mainPath)
+	for _, bin := range a.binaries() {
+		if bin.name == name {
+			panic("binary " + name + " already exists")
+		}
+	}
+	if !strings.HasPrefix(mainPath, Go().ModuleName()) {
+		mainPath = Go().ToModulePath(mainPath)
+	}
+	a.extraBinaries = append(a.extraBinaries, appBinary{name: name, mainPath: mainPath})
+	return a
+}
+
+// Include adds a file or directory that will be staged alongside the executables of every variant, and packaged with them.
+// The archivePath is the path relative to the root of the package, and directories are included recursively.
+// File modes are preserved from the source.
+func (a *AppBuild) Include(source, archivePath PathString) *AppBuild {
+	return a.IncludeMode(source, archivePath, 0)
+}
+
+// IncludeMode is like [AppBuild.Include], but sets the mode of each included file.
+// A mode of 0 preserves the source file's mode.
+func (a *AppBuild) IncludeMode(source, archivePath PathString, mode fs.FileMode) *AppBuild {
+	a.files = append(a.files, newAppFile(source, archivePath, mode))
+	return a
+}
+
+func newAppFile(source, archivePath PathString, mode fs.FileMode) appFile {
+	if len(source) == 0 {
+		panic("empty source path")
+	}
+	if len(archivePath) == 0 {
+		panic("empty archive path")
+	}
+	clean := filepath.Clean(archivePath.String())
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		panic("archive path '" + archivePath.String() + "' must be relative to the package root")
+	}
+	return appFile{source: source, archivePath: Path(clean), mode: mode.Perm()}
+}
+
+// PGO sets the profile used for profile-guided optimization of all variants, as with [GoBuild.PGO].
+func (a *AppBuild) PGO(profile string) *AppBuild {
+	a.pgo = strings.TrimSpace(profile)
+	return a
+}
+
+// CollectProfile adds a "pgo-${APP}" step to the generated [Build] that collects a profile with the given [PGOWorkflow], and reports its effect.
+// The step isn't a dependency of any other step, so it must be run explicitly.
+func (a *AppBuild) CollectProfile(workflow *PGOWorkflow) *AppBuild {
+	a.pgoWorkflow = workflow
+	return a
+}
+
+// SizeBaseline enables size checks of every executable after it's built with [SizeCheck], comparing against baselines stored in the given directory.
+// Baselines are named "${BINARY}_${VARIANT}.json", and may be updated with the generated "update-size-baseline-${APP}" step.
+func (a *AppBuild) SizeBaseline(baselineDir PathString) *AppBuild {
+	if len(baselineDir) == 0 {
+		panic("empty baseline directory")
+	}
+	a.sizeBaselineDir = baselineDir
+	return a
+}
+
+// SizeBudget enables size checks of every executable after it's built, failing the build if an executable is larger than maxBytes, or has grown more than maxGrowth percent since its baseline.
+// A zero value disables the corresponding budget, and [AppVariant.SizeBudget] may override this for a specific variant.
+// See [SizeCheck.MaxBytes] and [SizeCheck.MaxGrowth] for details.
+func (a *AppBuild) SizeBudget(maxBytes int64, maxGrowth float64) *AppBuild {
+	a.sizeBudget = sizeBudget{maxBytes: maxBytes, maxGrowth: maxGrowth}
+	return a
+}
+
+func (a *AppBuild) sizeChecksEnabled(v *AppVariant) bool {
+	return len(a.sizeBaselineDir) > 0 || a.sizeBudget != (sizeBudget{}) || v.sizeBudget != nil
+}
+
+func (a *AppBuild) sizeCheck(v *AppVariant, bin appBinary) *SizeCheck {
+	budget := a.sizeBudget
+	if v.sizeBudget != nil {
+		budget = *v.sizeBudget
+	}
+	check := CheckSize(a.binaryOutput(v, bin)).MaxBytes(budget.maxBytes).MaxGrowth(budget.maxGrowth)
+	if len(a.sizeBaselineDir) > 0 {
+		check.Baseline(a.sizeBaselineDir.Join(fmt.Sprintf("%s_%s.json", bin.name, v.variant)))
+	}
+	return check
+}
+
+// sizeCheckTask checks the size of all binaries built for the variant.
+func (a *AppBuild) sizeCheckTask(v *AppVariant) Task {
+	var checks Task
+	for _, bin := range a.binaries() {
+		checks = checks.Then(a.sizeCheck(v, bin))
+	}
+	return checks
+}
+
+// WindowsResources sets the [WindowsResources] embedded in all windows variants, unless overridden by [AppVariant.WindowsResources].
+func (a *AppBuild) WindowsResources(res *WindowsResources) *AppBuild {
+	a.winres = res
+	return a
+}
+
 func (a *AppBuild) buildName(v *AppVariant) string {
 	return "build-" + a.appName + "_" + v.variant
 }
@@ -139,28 +314,216 @@ func (a *AppBuild) packageName(v *AppVariant) string {
 	return "package-" + a.appName + "_" + v.variant
 }
 
+// binaries returns the primary executable, followed by any added with [AppBuild.Binary].
+func (a *AppBuild) binaries() []appBinary {
+	return append([]appBinary{{name: a.appName, mainPath: a.mainPath}}, a.extraBinaries...)
+}
+
+// binaryOutput returns the build output path of a binary, which is in the same directory as the variant's primary executable.
+func (a *AppBuild) binaryOutput(v *AppVariant, bin appBinary) PathString {
+	if bin.name == a.appName {
+		return v.buildOutput
+	}
+	name := bin.name
+	if v.os == "windows" {
+		name += ".exe"
+	}
+	return v.buildOutput.Dir().Join(name)
+}
+
 func (a *AppBuild) goBuild(v *AppVariant) *GoBuild {
-	gb := Go().Build(a.mainPath)
+	return a.goBuildArch(v, a.binaries()[0], v.arch, v.buildOutput)
+}
+
+func (a *AppBuild) goBuildArch(v *AppVariant, bin appBinary, arch string, output PathString) *GoBuild {
+	gb := Go().Build(bin.mainPath)
+	if len(a.pgo) > 0 {
+		gb.PGO(a.pgo)
+	}
 	if v.buildFunc != nil {
 		v.buildFunc(gb)
 	}
 	gb.
-		OutputFilename(v.buildOutput).
+		OutputFilename(output).
 		OS(v.os).
-		Arch(v.arch)
+		Arch(arch)
+	envKeys := keySlice(v.env)
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		gb.Env(key, v.env[key])
+	}
 	if a.buildFunc != nil {
 		a.buildFunc(gb)
 	}
 	return gb
 }
 
+// universalArchs are the architectures merged into a universal darwin binary.
+var universalArchs = []string{"amd64", "arm64"}
+
+func (a *AppBuild) thinOutput(v *AppVariant, bin appBinary, arch string) PathString {
+	return Path(BuildPath, fmt.Sprintf("%s_%s_%s", a.appName, v.variant, arch), a.binaryOutput(v, bin).Base().String())
+}
+
+// mainDir returns the filesystem path to a binary's main package directory.
+func (a *AppBuild) mainDir(bin appBinary) PathString {
+	return modulePathDir(bin.mainPath)
+}
+
+// modulePathDir translates a module path within the current module to a filesystem path.
+func modulePathDir(modulePath string) PathString {
+	rel := strings.TrimPrefix(strings.TrimPrefix(modulePath, Go().ModuleName()), "/")
+	return Go().ModuleRoot().Join(rel)
+}
+
+// sysoPath returns the path of the resource object written to a binary's main package for a windows variant.
+func (a *AppBuild) sysoPath(v *AppVariant, bin appBinary) PathString {
+	return a.mainDir(bin).Join(fmt.Sprintf("zz_modmake_%s_windows_%s.syso", bin.name, v.arch))
+}
+
+// buildTask builds all binaries for the variant, and stages any included files alongside them.
+func (a *AppBuild) buildTask(v *AppVariant) Task {
+	var steps []Runner
+	for _, bin := range a.binaries() {
+		steps = append(steps, a.binaryTask(v, bin))
+	}
+	if len(a.files) > 0 || len(v.files) > 0 {
+		steps = append(steps, a.stageTask(v))
+	}
+	return Script(steps...)
+}
+
+func (a *AppBuild) binaryTask(v *AppVariant, bin appBinary) Task {
+	output := a.binaryOutput(v, bin)
+	if !v.universal {
+		gb := a.goBuildArch(v, bin, v.arch, output)
+		res := v.winres
+		if res == nil {
+			res = a.winres
+		}
+		if v.os != "windows" || res == nil {
+			return gb.Task()
+		}
+		syso := a.sysoPath(v, bin)
+		return res.WriteSyso(syso, v.arch, bin.name, a.version).
+			Then(gb).
+			Finally(func(err error) error {
+				if rerr := syso.Remove(); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+					return errors.Join(err, fmt.Errorf("failed to remove resource object '%s': %w", syso, rerr))
+				}
+				return nil
+			})
+	}
+	var (
+		steps []Runner
+		thin  []PathString
+	)
+	for _, arch := range universalArchs {
+		thinOutput := a.thinOutput(v, bin, arch)
+		steps = append(steps, a.goBuildArch(v, bin, arch, thinOutput))
+		thin = append(thin, thinOutput)
+	}
+	return Script(append(steps, MachOUniversal(output, thin...))...)
+}
+
+// stageTask copies included files into the variant's build directory, so they can be packaged with the executables.
+func (a *AppBuild) stageTask(v *AppVariant) Task {
+	files := append(append([]appFile{}, a.files...), v.files...)
+	return func(ctx context.Context) error {
+		_, log := WithGroup(ctx, "stage files")
+		stageDir := v.buildOutput.Dir()
+		for _, file := range files {
+			target := stageDir.JoinPath(file.archivePath)
+			err := filepath.WalkDir(file.source.String(), func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(file.source.String(), path)
+				if err != nil {
+					return err
+				}
+				dest := target.Join(rel)
+				if d.IsDir() {
+					return dest.MkdirAll(0755)
+				}
+				return stageFile(Path(path), dest, file.mode)
+			})
+			if err != nil {
+				return log.WrapErr(fmt.Errorf("failed to stage '%s' as '%s': %w", file.source, file.archivePath, err))
+			}
+			log.Debug("Staged '%s' as '%s'", file.source, file.archivePath)
+		}
+		return nil
+	}
+}
+
+// stageFile copies a single file, applying the given mode, or the source file's mode if zero.
+func stageFile(source, dest PathString, mode fs.FileMode) error {
+	if mode == 0 {
+		fi, err := source.Stat()
+		if err != nil {
+			return err
+		}
+		mode = fi.Mode().Perm()
+	}
+	if err := dest.Dir().MkdirAll(0755); err != nil {
+		return err
+	}
+	if err := source.CopyTo(dest); err != nil {
+		return err
+	}
+	return os.Chmod(dest.String(), mode)
+}
+
+func (a *AppBuild) cleanTask(v *AppVariant) Task {
+	dirs := []PathString{v.buildOutput.Dir()}
+	if v.universal {
+		for _, arch := range universalArchs {
+			dirs = append(dirs, a.thinOutput(v, a.binaries()[0], arch).Dir())
+		}
+	}
+	var clean Task
+	for _, dir := range dirs {
+		clean = clean.Then(RemoveDir(dir)).Then(MkdirAll(dir, 0755))
+	}
+	return clean
+}
+
 func (a *AppBuild) pkgTask(v *AppVariant) Task {
 	if v.packageFunc != nil {
-		return v.packageFunc(v.buildOutput, Path(DistPath, a.appName), a.appName, v.variant, a.version)
+		pkg := v.packageFunc(v.buildOutput, Path(DistPath, a.appName), a.appName, v.variant, a.version)
+		contents := a.packageContents(v)
+		return func(ctx context.Context) error {
+			return pkg.Run(context.WithValue(ctx, packageContentsKey, contents))
+		}
 	}
 	return nil
 }
 
+type packageContentsKeyType string
+
+const packageContentsKey = packageContentsKeyType("package contents")
+
+// packageContents returns the paths of the variant's executables and included files, relative to its build directory.
+func (a *AppBuild) packageContents(v *AppVariant) []PathString {
+	var contents []PathString
+	for _, bin := range a.binaries() {
+		contents = append(contents, a.binaryOutput(v, bin).Base())
+	}
+	for _, file := range append(append([]appFile{}, a.files...), v.files...) {
+		contents = append(contents, file.archivePath)
+	}
+	return contents
+}
+
+// packageContents returns the paths to package relative to the binary's directory, which is only the binary itself unless run by an [AppBuild].
+func packageContents(ctx context.Context, binaryPath PathString) []PathString {
+	if contents, ok := ctx.Value(packageContentsKey).([]PathString); ok {
+		return contents
+	}
+	return []PathString{binaryPath.Base()}
+}
+
 // AsBuild generates a modmake [Build] from the steps in this [AppBuild].
 // This is useful in the case where additional customization is needed.
 // Note that changing the [AppBuild] after calling this method has no effect on the generated Build.
@@ -174,11 +537,12 @@ func (a *AppBuild) AsBuild() *Build {
 	)
 	for _, v := range a.variants {
 		buildStep := NewStep(a.buildName(v), fmt.Sprintf("Builds %s for %s/%s", a.appName, v.os, v.arch))
-		buildStep.Does(a.goBuild(v))
+		buildStep.Does(a.buildTask(v))
+		if a.sizeChecksEnabled(v) {
+			buildStep.AfterRun(a.sizeCheckTask(v))
+		}
 		b.AddStep(buildStep)
-		b.Build().DependsOnRunner("clean-"+a.buildName(v), "Removes previous build output",
-			RemoveDir(v.buildOutput.Dir()).Then(MkdirAll(v.buildOutput.Dir(), 0755)),
-		)
+		b.Build().DependsOnRunner("clean-"+a.buildName(v), "Removes previous build output", a.cleanTask(v))
 		b.Build().DependsOn(buildStep)
 
 		pkgTask := a.pkgTask(v)
@@ -192,9 +556,34 @@ func (a *AppBuild) AsBuild() *Build {
 			b.Package().DependsOn(pkgStep)
 		}
 	}
+	if len(a.sizeBaselineDir) > 0 {
+		var baselines Task
+		for _, v := range a.variants {
+			for _, bin := range a.binaries() {
+				baselines = baselines.Then(a.sizeCheck(v, bin).WriteBaseline())
+			}
+		}
+		b.AddStep(NewStep("update-size-baseline-"+a.appName, "Records the current size of each "+a.appName+" executable as its baseline").
+			Does(baselines).
+			DependsOn(b.Build()))
+	}
+	if a.pgoWorkflow != nil {
+		b.AddStep(NewStep("pgo-"+a.appName, "Collects a CPU profile for profile-guided optimization of "+a.appName).
+			Does(a.pgoWorkflow.Collect().Then(a.pgoWorkflow.Report())))
+	}
 	installVariant := a.NamedVariant("install", runtime.GOOS, runtime.GOARCH).Package(a.installPackageFunc)
-	installStep := NewStep("install", "Installs "+a.appName).Does(a.pkgTask(installVariant))
-	installStep.BeforeRun(a.goBuild(installVariant))
+	installVariant.install = true
+	var (
+		installBuild Task
+		install      Task
+	)
+	for _, bin := range a.binaries() {
+		output := a.binaryOutput(installVariant, bin)
+		installBuild = installBuild.Then(a.goBuildArch(installVariant, bin, installVariant.arch, output))
+		install = install.Then(a.installPackageFunc(output, Path(DistPath, a.appName), a.appName, installVariant.variant, a.version))
+	}
+	installStep := NewStep("install", "Installs "+a.appName).Does(install)
+	installStep.BeforeRun(installBuild)
 	b.AddStep(installStep)
 	return b
 }
@@ -210,6 +599,12 @@ type AppVariant struct {
 	buildOutput, distDir PathString
 	buildFunc            AppBuildFunc
 	packageFunc          AppPackageFunc
+	universal            bool
+	winres               *WindowsResources
+	files                []appFile
+	env                  map[string]string
+	sizeBudget           *sizeBudget
+	install              bool // install is true for the variant generated for the install step, which isn't distributed.
 }
 
 // HostVariant creates an AppVariant with the current host's GOOS and GOARCH settings.
@@ -249,6 +644,14 @@ func (a *AppBuild) NamedVariant(variant, os, arch string) *AppVariant {
 	return v
 }
 
+// UniversalDarwin creates an AppVariant named "darwin_universal" that builds both darwin/amd64 and darwin/arm64 executables, and merges them into a single universal binary with [MachOUniversal].
+// The universal binary is packaged like any other variant, so there's no need to merge them with lipo on a Mac.
+func (a *AppBuild) UniversalDarwin() *AppVariant {
+	v := a.NamedVariant("darwin_universal", "darwin", universalDarwin)
+	v.universal = true
+	return v
+}
+
 // Build sets the AppBuildFunc specific to this variant.
 // [AppBuildFunc.Then] may be used to combine multiple build customizations.
 func (v *AppVariant) Build(bf AppBuildFunc) *AppVariant {
@@ -263,6 +666,45 @@ func (v *AppVariant) Package(pf AppPackageFunc) *AppVariant {
 	return v
 }
 
+// WindowsResources sets the [WindowsResources] embedded in this variant's executable, overriding any set with [AppBuild.WindowsResources].
+// The resources are written to a .syso file in the main package directory before building, and removed afterward.
+// This has no effect on variants that don't target windows.
+func (v *AppVariant) WindowsResources(res *WindowsResources) *AppVariant {
+	v.winres = res
+	return v
+}
+
+// SizeBudget overrides the size budget set with [AppBuild.SizeBudget] for this variant.
+func (v *AppVariant) SizeBudget(maxBytes int64, maxGrowth float64) *AppVariant {
+	v.sizeBudget = &sizeBudget{maxBytes: maxBytes, maxGrowth: maxGrowth}
+	return v
+}
+
+// Env sets an environment variable for this variant's build, like GOARM or GOAMD64.
+func (v *AppVariant) Env(key, value string) *AppVariant {
+	key = strings.TrimSpace(key)
+	if len(key) == 0 {
+		panic("empty environment variable name")
+	}
+	if v.env == nil {
+		v.env = map[string]string{}
+	}
+	v.env[key] = value
+	return v
+}
+
+// Include adds a file or directory that will be staged and packaged with this variant's executables, in addition to those added with [AppBuild.Include].
+func (v *AppVariant) Include(source, archivePath PathString) *AppVariant {
+	return v.IncludeMode(source, archivePath, 0)
+}
+
+// IncludeMode is like [AppVariant.Include], but sets the mode of each included file.
+// A mode of 0 preserves the source file's mode.
+func (v *AppVariant) IncludeMode(source, archivePath PathString, mode fs.FileMode) *AppVariant {
+	v.files = append(v.files, newAppFile(source, archivePath, mode))
+	return v
+}
+
 // NoPackage will mark this variant as one that doesn't include a packaging step.
 func (v *AppVariant) NoPackage() *AppVariant {
 	v.packageFunc = nil
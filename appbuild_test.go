@@ -161,3 +161,59 @@ func TestAppPackageFunc_Then(t *testing.T) {
 	})
 	a.Then(b)(Path(""), Path(""), "", "", "")
 }
+
+func TestAppBuild_BinaryAndInclude(t *testing.T) {
+	tmp := Path(t.TempDir())
+	prevBuild, prevDist := BuildPath, DistPath
+	BuildPath, DistPath = tmp.Join("build").String(), tmp.Join("dist").String()
+	defer func() {
+		BuildPath, DistPath = prevBuild, prevDist
+	}()
+	license := tmp.Join("LICENSE")
+	require.NoError(t, license.WriteFile([]byte("license"), 0644))
+	configDir := tmp.Join("config")
+	require.NoError(t, configDir.Join("nested").MkdirAll(0755))
+	require.NoError(t, configDir.Join("app.yaml").WriteFile([]byte("key: value"), 0644))
+	require.NoError(t, configDir.Join("nested", "other.yaml").WriteFile([]byte("key: value"), 0644))
+
+	a := NewAppBuild("testingbuild", "testingbuild", "1.0.0").
+		Binary("othertool", "testingbuild").
+		Include(license, "LICENSE").
+		IncludeMode(configDir, Path("etc", "config"), 0600)
+	assert.Panics(t, func() {
+		a.Binary("othertool", "testingbuild")
+	}, "Duplicate binaries should be rejected")
+	assert.Panics(t, func() {
+		a.Include(license, "../LICENSE")
+	}, "Archive paths outside the package should be rejected")
+
+	v := a.Variant("linux", "amd64").Build(func(gb *GoBuild) {
+		gb.CgoEnabled(false)
+	})
+	assert.Equal(t, tmp.Join("build", "testingbuild_linux_amd64", "othertool"), a.binaryOutput(v, a.binaries()[1]))
+	ctx := context.Background()
+	distDir := Path(DistPath, "testingbuild")
+	stray := v.buildOutput.Dir().Join("stray.txt")
+	require.NoError(t, a.cleanTask(v).Then(a.buildTask(v)).Then(Task(func(ctx context.Context) error {
+		return stray.WriteFile([]byte("stray"), 0644)
+	})).Then(MkdirAll(distDir, 0755)).Then(a.pkgTask(v)).Run(ctx))
+
+	extracted := tmp.Join("extracted")
+	require.NoError(t, Tar(distDir.Join("testingbuild_linux_amd64_1.0.0.tar.gz")).Extract(extracted).Run(ctx))
+	for _, file := range []PathString{
+		extracted.Join("testingbuild"),
+		extracted.Join("othertool"),
+		extracted.Join("LICENSE"),
+		extracted.Join("etc", "config", "app.yaml"),
+		extracted.Join("etc", "config", "nested", "other.yaml"),
+	} {
+		assert.True(t, file.IsFile(), "%s should have been packaged", file)
+	}
+	assert.False(t, extracted.Join("stray.txt").Exists(), "Other files in the build directory should not be packaged")
+	fi, err := v.buildOutput.Dir().Join("etc", "config", "app.yaml").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, "-rw-------", fi.Mode().String(), "Mode should be overridden")
+	fi, err = v.buildOutput.Dir().Join("LICENSE").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, "-rw-r--r--", fi.Mode().String(), "Mode should be preserved")
+}
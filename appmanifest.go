@@ -0,0 +1,365 @@
+package modmake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var homebrewTemplate = template.Must(template.New("homebrew").Parse(`class {{ .ClassName }} < Formula
+  desc {{ printf "%q" .Description }}
+  homepage {{ printf "%q" .Homepage }}
+  version {{ printf "%q" .Version }}
+{{- if .License }}
+  license {{ printf "%q" .License }}
+{{- end }}
+{{- range .Platforms }}
+
+  on_{{ .Name }} do
+{{- range .Archives }}
+    if Hardware::CPU.{{ .CPU }}?
+      url {{ printf "%q" .URL }}
+      sha256 {{ printf "%q" .SHA256 }}
+    end
+{{- end }}
+  end
+{{- end }}
+
+  def install
+{{- range .Binaries }}
+    bin.install {{ printf "%q" . }}
+{{- end }}
+  end
+
+  test do
+{{- range .Binaries }}
+    assert_predicate bin/{{ printf "%q" . }}, :exist?
+{{- end }}
+  end
+end
+`))
+
+// AppManifests renders package manager manifests for the packaged variants of an [AppBuild].
+// Archives are expected to be produced by [PackageTar] or [PackageZip], and must exist when the manifests are written.
+// Use [AppBuild.Manifests] to create an AppManifests.
+type AppManifests struct {
+	app         *AppBuild
+	urlTemplate string
+	description string
+	homepage    string
+	license     string
+	variants    map[string]bool
+}
+
+// Manifests creates an AppManifests for this AppBuild.
+// The urlTemplate is used to produce each archive's download URL with [F], and may reference the following variables:
+//   - APP: The application name.
+//   - VERSION: The application version.
+//   - VARIANT: The variant name.
+//   - OS: The variant's GOOS.
+//   - ARCH: The variant's GOARCH.
+//   - FILENAME: The file name of the variant's archive.
+//
+// For example, "https://github.com/me/myapp/releases/download/v${VERSION}/${FILENAME}".
+func (a *AppBuild) Manifests(urlTemplate string) *AppManifests {
+	if len(strings.TrimSpace(urlTemplate)) == 0 {
+		panic("empty URL template")
+	}
+	return &AppManifests{
+		app:         a,
+		urlTemplate: urlTemplate,
+		description: a.appName,
+	}
+}
+
+// Description sets the description of the application, which defaults to the application name.
+func (m *AppManifests) Description(description string) *AppManifests {
+	m.description = description
+	return m
+}
+
+// Homepage sets the URL of the application's homepage.
+func (m *AppManifests) Homepage(homepage string) *AppManifests {
+	m.homepage = homepage
+	return m
+}
+
+// License sets the SPDX license identifier of the application.
+func (m *AppManifests) License(license string) *AppManifests {
+	m.license = license
+	return m
+}
+
+// Variants limits the manifests to the named variants.
+// This is needed when more than one packaged variant targets the same OS and architecture, like variants for different GOAMD64 levels, since a manifest can only reference one archive for each platform.
+func (m *AppManifests) Variants(names ...string) *AppManifests {
+	if m.variants == nil {
+		m.variants = map[string]bool{}
+	}
+	for _, name := range names {
+		if len(name) == 0 {
+			panic("empty variant name")
+		}
+		m.variants[name] = true
+	}
+	return m
+}
+
+// errDuplicatePlatform returns an error for two variants that would be used for the same platform in a manifest.
+func errDuplicatePlatform(first, second *AppVariant, platform string) error {
+	return fmt.Errorf("variants '%s' and '%s' both provide %s, use Variants to select one of them", first.variant, second.variant, platform)
+}
+
+type manifestArchive struct {
+	variant *AppVariant
+	file    PathString
+	url     string
+	sha256  string
+}
+
+func (m *AppManifests) archives(filter func(v *AppVariant) bool) ([]manifestArchive, error) {
+	var archives []manifestArchive
+	for _, v := range m.app.variants {
+		if v.packageFunc == nil || v.install || !filter(v) || (m.variants != nil && !m.variants[v.variant]) {
+			continue
+		}
+		exts := []string{TarGzip.Ext(), TarZstd.Ext(), TarXz.Ext(), TarNoCompression.Ext()}
+		if v.os == "windows" {
+			exts = []string{".zip"}
+		}
+		base := fmt.Sprintf("%s_%s_%s", m.app.appName, v.variant, m.app.version)
+		filename := base + exts[0]
+		for _, ext := range exts {
+			if v.distDir.Join(base + ext).IsFile() {
+				filename = base + ext
+				break
+			}
+		}
+		file := v.distDir.Join(filename)
+		sum, err := file.SHA256()
+		if err != nil {
+			return nil, fmt.Errorf("unable to checksum archive for variant '%s', ensure it has been packaged: %w", v.variant, err)
+		}
+		url, err := FErr(m.urlTemplate, EnvMap{
+			"APP":      m.app.appName,
+			"VERSION":  m.app.version,
+			"VARIANT":  v.variant,
+			"OS":       v.os,
+			"ARCH":     v.arch,
+			"FILENAME": filename,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL template for variant '%s': %w", v.variant, err)
+		}
+		archives = append(archives, manifestArchive{
+			variant: v,
+			file:    file,
+			url:     url,
+			sha256:  sum,
+		})
+	}
+	return archives, nil
+}
+
+// binaryNames returns the names of all executables included in a package for the given OS.
+func (m *AppManifests) binaryNames(os string) []string {
+	var names []string
+	for _, bin := range m.app.binaries() {
+		if os == "windows" {
+			names = append(names, bin.name+".exe")
+			continue
+		}
+		names = append(names, bin.name)
+	}
+	return names
+}
+
+// Homebrew creates a Task that writes a Homebrew formula named "${APP}.rb" to the tap directory.
+// Packaged darwin and linux variants with the amd64 or arm64 architecture are included, as well as a universal darwin variant created with [AppBuild.UniversalDarwin].
+// An error is returned if more than one variant provides the same platform, use [AppManifests.Variants] to select one.
+func (m *AppManifests) Homebrew(tapDir PathString) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "homebrew formula")
+		archives, err := m.archives(func(v *AppVariant) bool {
+			return v.os == "darwin" || v.os == "linux"
+		})
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if len(archives) == 0 {
+			return log.WrapErr(errors.New("no packaged darwin or linux variants to include in the formula"))
+		}
+		type (
+			archive struct {
+				CPU, URL, SHA256 string
+			}
+			platform struct {
+				Name     string
+				Archives []archive
+			}
+		)
+		platforms := []*platform{{Name: "macos"}, {Name: "linux"}}
+		used := map[string]*AppVariant{}
+		for _, a := range archives {
+			var cpus []string
+			switch a.variant.arch {
+			case "amd64":
+				cpus = []string{"intel"}
+			case "arm64":
+				cpus = []string{"arm"}
+			case universalDarwin:
+				cpus = []string{"intel", "arm"}
+			default:
+				log.Warn("Skipping variant '%s', architecture '%s' is not supported by Homebrew", a.variant.variant, a.variant.arch)
+				continue
+			}
+			p := platforms[0]
+			if a.variant.os == "linux" {
+				p = platforms[1]
+			}
+			for _, cpu := range cpus {
+				slot := a.variant.os + "/" + cpu
+				if other, ok := used[slot]; ok {
+					return log.WrapErr(errDuplicatePlatform(other, a.variant, slot))
+				}
+				used[slot] = a.variant
+				p.Archives = append(p.Archives, archive{CPU: cpu, URL: a.url, SHA256: a.sha256})
+			}
+		}
+		var included []*platform
+		for _, p := range platforms {
+			if len(p.Archives) > 0 {
+				included = append(included, p)
+			}
+		}
+		data := map[string]any{
+			"ClassName":   homebrewClassName(m.app.appName),
+			"Description": m.description,
+			"Homepage":    m.homepage,
+			"Version":     m.app.version,
+			"License":     m.license,
+			"Platforms":   included,
+			"Binaries":    m.binaryNames("darwin"),
+		}
+		output := tapDir.Join(m.app.appName + ".rb")
+		if err := writeManifest(output, func(w io.Writer) error {
+			return homebrewTemplate.Execute(w, data)
+		}); err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Wrote Homebrew formula to '%s'", output)
+		return nil
+	}
+}
+
+// Scoop creates a Task that writes a Scoop manifest named "${APP}.json" to the bucket directory.
+// Packaged windows variants with the amd64, 386, or arm64 architecture are included.
+// An error is returned if more than one variant provides the same architecture, use [AppManifests.Variants] to select one.
+func (m *AppManifests) Scoop(bucketDir PathString) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "scoop manifest")
+		archives, err := m.archives(func(v *AppVariant) bool {
+			return v.os == "windows"
+		})
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if len(archives) == 0 {
+			return log.WrapErr(errors.New("no packaged windows variants to include in the manifest"))
+		}
+		type architecture struct {
+			URL  string   `json:"url"`
+			Hash string   `json:"hash"`
+			Bin  []string `json:"bin"`
+		}
+		manifest := struct {
+			Version      string                  `json:"version"`
+			Description  string                  `json:"description,omitempty"`
+			Homepage     string                  `json:"homepage,omitempty"`
+			License      string                  `json:"license,omitempty"`
+			Architecture map[string]architecture `json:"architecture"`
+		}{
+			Version:      m.app.version,
+			Description:  m.description,
+			Homepage:     m.homepage,
+			License:      m.license,
+			Architecture: map[string]architecture{},
+		}
+		used := map[string]*AppVariant{}
+		for _, a := range archives {
+			var arch string
+			switch a.variant.arch {
+			case "amd64":
+				arch = "64bit"
+			case "386":
+				arch = "32bit"
+			case "arm64":
+				arch = "arm64"
+			default:
+				log.Warn("Skipping variant '%s', architecture '%s' is not supported by Scoop", a.variant.variant, a.variant.arch)
+				continue
+			}
+			if other, ok := used[arch]; ok {
+				return log.WrapErr(errDuplicatePlatform(other, a.variant, "windows/"+a.variant.arch))
+			}
+			used[arch] = a.variant
+			manifest.Architecture[arch] = architecture{
+				URL:  a.url,
+				Hash: a.sha256,
+				Bin:  m.binaryNames("windows"),
+			}
+		}
+		output := bucketDir.Join(m.app.appName + ".json")
+		if err := writeManifest(output, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "    ")
+			return enc.Encode(manifest)
+		}); err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Wrote Scoop manifest to '%s'", output)
+		return nil
+	}
+}
+
+func writeManifest(output PathString, write func(w io.Writer) error) error {
+	if err := output.Dir().MkdirAll(0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	f, err := output.Create()
+	if err != nil {
+		return fmt.Errorf("failed to create manifest '%s': %w", output, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := write(f); err != nil {
+		return fmt.Errorf("failed to write manifest '%s': %w", output, err)
+	}
+	return nil
+}
+
+// homebrewClassName converts an application name to the class name Homebrew expects, like "my-app" to "MyApp".
+func homebrewClassName(app string) string {
+	var (
+		buf   strings.Builder
+		upper = true
+	)
+	for _, r := range app {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
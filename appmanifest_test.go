@@ -0,0 +1,200 @@
+package modmake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppManifests(t *testing.T) {
+	tmp := Path(t.TempDir())
+	origDist := DistPath
+	DistPath = tmp.Join("dist").String()
+	defer func() {
+		DistPath = origDist
+	}()
+
+	a := NewAppBuild("my-app", "cmd/modmake", "1.2.3")
+	a.HostVariant()
+	a.Variant("darwin", "amd64")
+	a.Variant("darwin", "arm64")
+	a.Variant("linux", "amd64")
+	a.Variant("windows", "amd64")
+	a.Variant("windows", "arm64")
+	distDir := Path(DistPath, "my-app")
+	require.NoError(t, distDir.MkdirAll(0755))
+	sums := map[string]string{}
+	for _, v := range a.variants {
+		if v.packageFunc == nil {
+			continue
+		}
+		ext := ".tar.gz"
+		if v.os == "windows" {
+			ext = ".zip"
+		}
+		archive := distDir.Join(fmt.Sprintf("my-app_%s_1.2.3%s", v.variant, ext))
+		require.NoError(t, archive.WriteFile([]byte(v.variant), 0644))
+		sum, err := archive.SHA256()
+		require.NoError(t, err)
+		sums[v.variant] = sum
+	}
+
+	m := a.Manifests("https://example.com/download/v${VERSION}/${FILENAME}").
+		Description("An app for testing").
+		Homepage("https://example.com").
+		License("MIT")
+	require.NoError(t, m.Homebrew(tmp.Join("tap")).Run(context.Background()))
+	require.NoError(t, m.Scoop(tmp.Join("bucket")).Run(context.Background()))
+
+	formula, err := tmp.Join("tap", "my-app.rb").ReadFile()
+	require.NoError(t, err)
+	expected := fmt.Sprintf(`class MyApp < Formula
+  desc "An app for testing"
+  homepage "https://example.com"
+  version "1.2.3"
+  license "MIT"
+
+  on_macos do
+    if Hardware::CPU.intel?
+      url "https://example.com/download/v1.2.3/my-app_darwin_amd64_1.2.3.tar.gz"
+      sha256 "%s"
+    end
+    if Hardware::CPU.arm?
+      url "https://example.com/download/v1.2.3/my-app_darwin_arm64_1.2.3.tar.gz"
+      sha256 "%s"
+    end
+  end
+
+  on_linux do
+    if Hardware::CPU.intel?
+      url "https://example.com/download/v1.2.3/my-app_linux_amd64_1.2.3.tar.gz"
+      sha256 "%s"
+    end
+  end
+
+  def install
+    bin.install "my-app"
+  end
+
+  test do
+    assert_predicate bin/"my-app", :exist?
+  end
+end
+`, sums["darwin_amd64"], sums["darwin_arm64"], sums["linux_amd64"])
+	assert.Equal(t, expected, string(formula))
+
+	data, err := tmp.Join("bucket", "my-app.json").ReadFile()
+	require.NoError(t, err)
+	var manifest map[string]any
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, "1.2.3", manifest["version"])
+	assert.Equal(t, "MIT", manifest["license"])
+	assert.Equal(t, map[string]any{
+		"64bit": map[string]any{
+			"url":  "https://example.com/download/v1.2.3/my-app_windows_amd64_1.2.3.zip",
+			"hash": sums["windows_amd64"],
+			"bin":  []any{"my-app.exe"},
+		},
+		"arm64": map[string]any{
+			"url":  "https://example.com/download/v1.2.3/my-app_windows_arm64_1.2.3.zip",
+			"hash": sums["windows_arm64"],
+			"bin":  []any{"my-app.exe"},
+		},
+	}, manifest["architecture"])
+}
+
+func TestAppManifests_MissingArchive(t *testing.T) {
+	tmp := Path(t.TempDir())
+	origDist := DistPath
+	DistPath = tmp.Join("dist").String()
+	defer func() {
+		DistPath = origDist
+	}()
+
+	a := NewAppBuild("my-app", "cmd/modmake", "1.2.3")
+	a.Variant("linux", "amd64")
+	err := a.Manifests("https://example.com/${FILENAME}").Homebrew(tmp).Run(context.Background())
+	assert.ErrorContains(t, err, "linux_amd64")
+
+	require.NoError(t, Path(DistPath, "my-app").MkdirAll(0755))
+	require.NoError(t, Path(DistPath, "my-app", "my-app_linux_amd64_1.2.3.tar.gz").WriteFile([]byte("archive"), 0644))
+	assert.NotPanics(t, func() {
+		err = a.Manifests("https://${MODMAKE_MISSING_HOST?}/${FILENAME}").Homebrew(tmp).Run(context.Background())
+	})
+	assert.ErrorIs(t, err, ErrMissingVariable)
+}
+
+func TestAppManifests_AsBuild(t *testing.T) {
+	tmp := Path(t.TempDir())
+	origDist := DistPath
+	DistPath = tmp.Join("dist").String()
+	defer func() {
+		DistPath = origDist
+	}()
+
+	a := NewAppBuild("my-app", "cmd/modmake", "1.2.3")
+	a.Variant("linux", "amd64")
+	a.Variant("darwin", "arm64")
+	a.AsBuild()
+	distDir := Path(DistPath, "my-app")
+	require.NoError(t, distDir.MkdirAll(0755))
+	for _, variant := range []string{"linux_amd64", "darwin_arm64"} {
+		require.NoError(t, distDir.Join(fmt.Sprintf("my-app_%s_1.2.3.tar.gz", variant)).WriteFile([]byte(variant), 0644))
+	}
+
+	m := a.Manifests("https://example.com/${FILENAME}")
+	require.NoError(t, m.Homebrew(tmp.Join("tap")).Run(context.Background()), "The install variant shouldn't be included in manifests")
+	formula, err := tmp.Join("tap", "my-app.rb").ReadFile()
+	require.NoError(t, err)
+	assert.NotContains(t, string(formula), "my-app_install_")
+}
+
+func TestAppManifests_DuplicatePlatform(t *testing.T) {
+	tmp := Path(t.TempDir())
+	origDist := DistPath
+	DistPath = tmp.Join("dist").String()
+	defer func() {
+		DistPath = origDist
+	}()
+
+	a := NewAppBuild("my-app", "cmd/modmake", "1.2.3")
+	a.NamedVariant("linux_amd64_v1", "linux", "amd64")
+	a.NamedVariant("linux_amd64_v3", "linux", "amd64")
+	a.NamedVariant("windows_amd64_v1", "windows", "amd64")
+	a.NamedVariant("windows_amd64_v3", "windows", "amd64")
+	distDir := Path(DistPath, "my-app")
+	require.NoError(t, distDir.MkdirAll(0755))
+	for _, variant := range []string{"linux_amd64_v1", "linux_amd64_v3"} {
+		require.NoError(t, distDir.Join(fmt.Sprintf("my-app_%s_1.2.3.tar.gz", variant)).WriteFile([]byte(variant), 0644))
+	}
+	for _, variant := range []string{"windows_amd64_v1", "windows_amd64_v3"} {
+		require.NoError(t, distDir.Join(fmt.Sprintf("my-app_%s_1.2.3.zip", variant)).WriteFile([]byte(variant), 0644))
+	}
+
+	m := a.Manifests("https://example.com/${FILENAME}")
+	err := m.Homebrew(tmp.Join("tap")).Run(context.Background())
+	assert.ErrorContains(t, err, "variants 'linux_amd64_v1' and 'linux_amd64_v3' both provide linux/intel")
+	err = m.Scoop(tmp.Join("bucket")).Run(context.Background())
+	assert.ErrorContains(t, err, "variants 'windows_amd64_v1' and 'windows_amd64_v3' both provide windows/amd64")
+
+	m.Variants("linux_amd64_v3", "windows_amd64_v1")
+	require.NoError(t, m.Homebrew(tmp.Join("tap")).Run(context.Background()))
+	formula, err := tmp.Join("tap", "my-app.rb").ReadFile()
+	require.NoError(t, err)
+	assert.Contains(t, string(formula), "my-app_linux_amd64_v3_1.2.3.tar.gz")
+	assert.NotContains(t, string(formula), "my-app_linux_amd64_v1_1.2.3.tar.gz")
+	require.NoError(t, m.Scoop(tmp.Join("bucket")).Run(context.Background()))
+	manifest, err := tmp.Join("bucket", "my-app.json").ReadFile()
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), "my-app_windows_amd64_v1_1.2.3.zip")
+}
+
+func TestHomebrewClassName(t *testing.T) {
+	assert.Equal(t, "MyApp", homebrewClassName("my-app"))
+	assert.Equal(t, "Modmake", homebrewClassName("modmake"))
+	assert.Equal(t, "Tool2go", homebrewClassName("tool_2go"))
+}
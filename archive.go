@@ -0,0 +1,302 @@
+package modmake
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveDirOption customizes how [TarArchive.AddDir] and [ZipArchive.AddDir] add files from a directory.
+type ArchiveDirOption func(opts *archiveDirOptions)
+
+type symlinkHandling int
+
+const (
+	symlinkPreserve symlinkHandling = iota
+	symlinkFollow
+	symlinkSkip
+)
+
+type archiveModeOverride struct {
+	pattern string
+	mode    fs.FileMode
+}
+
+type archiveDirOptions struct {
+	include  []string
+	exclude  []string
+	symlinks symlinkHandling
+	modes    []archiveModeOverride
+}
+
+// DirInclude only adds files that match at least one of the patterns.
+// Patterns are matched against the slash separated path relative to the directory, see [MatchGlob] for the syntax.
+func DirInclude(patterns ...string) ArchiveDirOption {
+	mustValidateGlobs(patterns)
+	return func(opts *archiveDirOptions) {
+		opts.include = append(opts.include, patterns...)
+	}
+}
+
+// DirExclude skips files and directories that match any of the patterns.
+// Patterns are matched in the same way as [DirInclude], and an excluded directory is not walked.
+func DirExclude(patterns ...string) ArchiveDirOption {
+	mustValidateGlobs(patterns)
+	return func(opts *archiveDirOptions) {
+		opts.exclude = append(opts.exclude, patterns...)
+	}
+}
+
+// DirFollowSymlinks adds the target of symlinks instead of the symlinks themselves.
+// By default, symlinks are stored in the archive as symlinks.
+func DirFollowSymlinks() ArchiveDirOption {
+	return func(opts *archiveDirOptions) {
+		opts.symlinks = symlinkFollow
+	}
+}
+
+// DirSkipSymlinks leaves symlinks out of the archive.
+// By default, symlinks are stored in the archive as symlinks.
+func DirSkipSymlinks() ArchiveDirOption {
+	return func(opts *archiveDirOptions) {
+		opts.symlinks = symlinkSkip
+	}
+}
+
+// DirMode overrides the permission bits of files matching the pattern, which is matched in the same way as [DirInclude].
+// If multiple overrides match a file, then the last one wins.
+func DirMode(pattern string, mode fs.FileMode) ArchiveDirOption {
+	mustValidateGlobs([]string{pattern})
+	return func(opts *archiveDirOptions) {
+		opts.modes = append(opts.modes, archiveModeOverride{pattern: pattern, mode: mode.Perm()})
+	}
+}
+
+// archiveDir is a directory that will be walked when an archive is written.
+type archiveDir struct {
+	source PathString
+	prefix string
+	opts   archiveDirOptions
+}
+
+func newArchiveDir(source, archivePrefix PathString, options []ArchiveDirOption) archiveDir {
+	if len(source) == 0 {
+		panic("empty source path")
+	}
+	dir := archiveDir{
+		source: source,
+		prefix: strings.Trim(path.Clean("/"+archivePrefix.ToSlash()), "/"),
+	}
+	for _, opt := range options {
+		opt(&dir.opts)
+	}
+	return dir
+}
+
+// archiveEntry is a single entry to write to an archive.
+type archiveEntry struct {
+	source PathString
+	// name is the slash separated archive path, which ends with "/" for directories.
+	name     string
+	info     fs.FileInfo
+	mode     fs.FileMode
+	linkname string
+}
+
+func (e archiveEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+func (e archiveEntry) isSymlink() bool {
+	return len(e.linkname) > 0
+}
+
+// perm returns the permission bits of the entry, applying any override.
+func (e archiveEntry) perm() fs.FileMode {
+	if e.mode != 0 {
+		return e.mode
+	}
+	return e.info.Mode().Perm()
+}
+
+// archiveFileSet is a FileSet that will be resolved when an archive is written.
+type archiveFileSet struct {
+	set    *FileSet
+	prefix string
+}
+
+func newArchiveFileSet(set *FileSet, archivePrefix PathString) archiveFileSet {
+	if set == nil {
+		panic("nil file set")
+	}
+	return archiveFileSet{
+		set:    set,
+		prefix: strings.Trim(path.Clean("/"+archivePrefix.ToSlash()), "/"),
+	}
+}
+
+// collectArchiveEntries resolves added files, directories, and file sets into entries sorted by archive path.
+// Files added individually take precedence over files found in added directories or file sets with the same archive path.
+func collectArchiveEntries(files map[PathString]string, dirs []archiveDir, sets []archiveFileSet) ([]archiveEntry, error) {
+	byName := map[string]archiveEntry{}
+	for _, dir := range dirs {
+		if err := dir.walk(func(e archiveEntry) {
+			byName[e.name] = e
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range sets {
+		rels, err := s.set.RelFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			source := s.set.Root().Join(rel)
+			fi, err := os.Stat(source.String())
+			if err != nil {
+				return nil, err
+			}
+			name := strings.TrimPrefix(path.Join(s.prefix, rel), "/")
+			byName[name] = archiveEntry{source: source, name: name, info: fi}
+		}
+	}
+	sources := make([]PathString, 0, len(files))
+	for source := range files {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i] < sources[j]
+	})
+	for _, source := range sources {
+		fi, err := os.Stat(source.String())
+		if err != nil {
+			return nil, fmt.Errorf("unable to locate source file: %s", source)
+		}
+		byName[files[source]] = archiveEntry{source: source, name: files[source], info: fi}
+	}
+	names := sortedKeys(byName)
+	entries := make([]archiveEntry, len(names))
+	for i, name := range names {
+		entries[i] = byName[name]
+	}
+	return entries, nil
+}
+
+func (d archiveDir) join(rel string) string {
+	return strings.TrimPrefix(path.Join(d.prefix, rel), "/")
+}
+
+// walk calls add for each entry in the directory.
+// Directory entries are included for the prefix, and for every walked directory if no include patterns are set.
+// Otherwise, only directories containing included entries are added.
+func (d archiveDir) walk(add func(e archiveEntry)) error {
+	rootInfo, err := os.Stat(d.source.String())
+	if err != nil {
+		return fmt.Errorf("unable to locate source directory: %w", err)
+	}
+	if !rootInfo.IsDir() {
+		return fmt.Errorf("source '%s' is not a directory", d.source)
+	}
+	var (
+		dirs     = map[string]archiveEntry{}
+		needed   = map[string]bool{}
+		visited  = map[string]bool{}
+		walkDir  func(dir PathString, rel string) error
+		included = func(rel string) {
+			for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+				needed[parent] = true
+			}
+		}
+	)
+	walkDir = func(dir PathString, rel string) error {
+		if d.opts.symlinks == symlinkFollow {
+			real, err := filepath.EvalSymlinks(dir.String())
+			if err != nil {
+				return err
+			}
+			if visited[real] {
+				return nil
+			}
+			visited[real] = true
+			defer delete(visited, real)
+		}
+		children, err := os.ReadDir(dir.String())
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			source := dir.Join(child.Name())
+			childRel := path.Join(rel, child.Name())
+			if matchAnyGlob(d.opts.exclude, childRel) {
+				continue
+			}
+			info, err := os.Lstat(source.String())
+			if err != nil {
+				return err
+			}
+			if info.Mode()&fs.ModeSymlink != 0 {
+				switch d.opts.symlinks {
+				case symlinkSkip:
+					continue
+				case symlinkFollow:
+					info, err = os.Stat(source.String())
+					if err != nil {
+						return fmt.Errorf("unable to follow symlink '%s': %w", source, err)
+					}
+				case symlinkPreserve:
+					if len(d.opts.include) > 0 && !matchAnyGlob(d.opts.include, childRel) {
+						continue
+					}
+					linkname, err := os.Readlink(source.String())
+					if err != nil {
+						return err
+					}
+					add(archiveEntry{source: source, name: d.join(childRel), info: info, linkname: filepath.ToSlash(linkname)})
+					included(childRel)
+					continue
+				}
+			}
+			if info.IsDir() {
+				dirs[childRel] = archiveEntry{source: source, name: d.join(childRel) + "/", info: info}
+				if err := walkDir(source, childRel); err != nil {
+					return err
+				}
+				continue
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			if len(d.opts.include) > 0 && !matchAnyGlob(d.opts.include, childRel) {
+				continue
+			}
+			entry := archiveEntry{source: source, name: d.join(childRel), info: info}
+			for _, override := range d.opts.modes {
+				if matchAnyGlob([]string{override.pattern}, childRel) {
+					entry.mode = override.mode
+				}
+			}
+			add(entry)
+			included(childRel)
+		}
+		return nil
+	}
+	if err := walkDir(d.source, ""); err != nil {
+		return fmt.Errorf("failed to walk '%s': %w", d.source, err)
+	}
+	for rel, entry := range dirs {
+		if len(d.opts.include) == 0 || needed[rel] {
+			add(entry)
+		}
+	}
+	if len(d.prefix) > 0 {
+		for prefix := d.prefix; prefix != "."; prefix = path.Dir(prefix) {
+			add(archiveEntry{source: d.source, name: prefix + "/", info: rootInfo})
+		}
+	}
+	return nil
+}
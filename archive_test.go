@@ -0,0 +1,210 @@
+package modmake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStaticDir(t *testing.T) PathString {
+	dir := Path(t.TempDir(), "static")
+	files := map[string]string{
+		"index.html":      "<html></html>",
+		"css/site.css":    "body {}",
+		"js/app.js":       "console.log('app')",
+		"js/app.js.map":   "{}",
+		"node_modules/x":  "ignored",
+		"scripts/run.sh":  "#!/bin/sh",
+		"images/logo.svg": "<svg/>",
+	}
+	for name, content := range files {
+		file := dir.Join(name)
+		require.NoError(t, file.Dir().MkdirAll(0755))
+		require.NoError(t, file.WriteFile([]byte(content), 0644))
+	}
+	require.NoError(t, dir.Join("empty").MkdirAll(0755))
+	return dir
+}
+
+type testArchiveEntry struct {
+	mode     fs.FileMode
+	modTime  time.Time
+	linkname string
+	content  string
+}
+
+func readTestTar(t *testing.T, location PathString) ([]string, map[string]testArchiveEntry) {
+	f, err := location.Open()
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var (
+		names   []string
+		entries = map[string]testArchiveEntry{}
+	)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		names = append(names, header.Name)
+		entries[header.Name] = testArchiveEntry{
+			mode:     header.FileInfo().Mode(),
+			modTime:  header.ModTime,
+			linkname: header.Linkname,
+			content:  string(data),
+		}
+	}
+	return names, entries
+}
+
+func readTestZip(t *testing.T, location PathString) ([]string, map[string]testArchiveEntry) {
+	zr, err := zip.OpenReader(location.String())
+	require.NoError(t, err)
+	defer func() {
+		_ = zr.Close()
+	}()
+	var (
+		names   []string
+		entries = map[string]testArchiveEntry{}
+	)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		_ = r.Close()
+		names = append(names, f.Name)
+		entry := testArchiveEntry{
+			mode:    f.Mode(),
+			modTime: f.Modified,
+			content: string(data),
+		}
+		if f.Mode()&fs.ModeSymlink != 0 {
+			entry.linkname = string(data)
+		}
+		entries[f.Name] = entry
+	}
+	return names, entries
+}
+
+func TestArchive_AddDir(t *testing.T) {
+	static := setupStaticDir(t)
+	tmp := static.Dir()
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []string{
+		"web/",
+		"web/static/",
+		"web/static/css/",
+		"web/static/css/site.css",
+		"web/static/empty/",
+		"web/static/images/",
+		"web/static/images/logo.svg",
+		"web/static/index.html",
+		"web/static/js/",
+		"web/static/js/app.js",
+		"web/static/scripts/",
+		"web/static/scripts/run.sh",
+	}
+	options := []ArchiveDirOption{
+		DirExclude("node_modules", "*.map"),
+		DirMode("scripts/*.sh", 0755),
+	}
+	ctx := context.Background()
+
+	require.NoError(t, Tar(tmp.Join("static.tar.gz")).AddDir(static, "web/static", options...).ModTime(modTime).Create().Run(ctx))
+	names, entries := readTestTar(t, tmp.Join("static.tar.gz"))
+	assert.Equal(t, expected, names)
+	assert.True(t, entries["web/static/css/"].mode.IsDir())
+	assert.Equal(t, fs.FileMode(0755), entries["web/static/scripts/run.sh"].mode.Perm())
+	assert.Equal(t, "#!/bin/sh", entries["web/static/scripts/run.sh"].content)
+	for name, entry := range entries {
+		assert.True(t, modTime.Equal(entry.modTime), "Entry '%s' should have a fixed modification time", name)
+	}
+
+	require.NoError(t, Zip(tmp.Join("static.zip")).AddDir(static, "web/static", options...).ModTime(modTime).Create().Run(ctx))
+	names, entries = readTestZip(t, tmp.Join("static.zip"))
+	assert.Equal(t, expected, names)
+	assert.True(t, entries["web/static/css/"].mode.IsDir())
+	assert.Equal(t, fs.FileMode(0755), entries["web/static/scripts/run.sh"].mode.Perm())
+	for name, entry := range entries {
+		assert.True(t, modTime.Equal(entry.modTime), "Entry '%s' should have a fixed modification time", name)
+	}
+}
+
+func TestArchive_AddDir_Include(t *testing.T) {
+	static := setupStaticDir(t)
+	tmp := static.Dir()
+	require.NoError(t, Tar(tmp.Join("static.tar.gz")).
+		AddDir(static, "", DirInclude("*.js", "*.css")).
+		AddFileWithPath(static.Join("index.html"), "index.html").
+		Create().Run(context.Background()))
+	names, _ := readTestTar(t, tmp.Join("static.tar.gz"))
+	assert.Equal(t, []string{
+		"css/",
+		"css/site.css",
+		"index.html",
+		"js/",
+		"js/app.js",
+	}, names, "Only directories with included files should be added")
+
+	require.NoError(t, Tar(tmp.Join("js.tar.gz")).
+		AddDir(static, "", DirInclude("js/**"), DirExclude("**/*.map")).
+		Create().Run(context.Background()))
+	names, _ = readTestTar(t, tmp.Join("js.tar.gz"))
+	assert.Equal(t, []string{
+		"js/",
+		"js/app.js",
+	}, names, "Patterns should use MatchGlob syntax")
+}
+
+func TestArchive_AddDir_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks require elevated permissions on Windows")
+	}
+	static := setupStaticDir(t)
+	tmp := static.Dir()
+	require.NoError(t, os.Symlink("index.html", static.Join("default.html").String()))
+	ctx := context.Background()
+	include := DirInclude("*.html")
+
+	require.NoError(t, Tar(tmp.Join("preserve.tar.gz")).AddDir(static, "", include).Create().Run(ctx))
+	_, entries := readTestTar(t, tmp.Join("preserve.tar.gz"))
+	assert.Equal(t, "index.html", entries["default.html"].linkname)
+	require.NoError(t, Zip(tmp.Join("preserve.zip")).AddDir(static, "", include).Create().Run(ctx))
+	_, entries = readTestZip(t, tmp.Join("preserve.zip"))
+	assert.Equal(t, "index.html", entries["default.html"].linkname)
+
+	require.NoError(t, Tar(tmp.Join("follow.tar.gz")).AddDir(static, "", include, DirFollowSymlinks()).Create().Run(ctx))
+	_, entries = readTestTar(t, tmp.Join("follow.tar.gz"))
+	assert.Empty(t, entries["default.html"].linkname)
+	assert.Equal(t, "<html></html>", entries["default.html"].content)
+
+	require.NoError(t, Tar(tmp.Join("skip.tar.gz")).AddDir(static, "", include, DirSkipSymlinks()).Create().Run(ctx))
+	names, _ := readTestTar(t, tmp.Join("skip.tar.gz"))
+	assert.Equal(t, []string{"index.html"}, names)
+
+	// Symlink loops shouldn't be followed forever.
+	require.NoError(t, os.Symlink("..", static.Join("css", "loop").String()))
+	require.NoError(t, Tar(tmp.Join("loop.tar.gz")).AddDir(static, "", DirInclude("*.css"), DirFollowSymlinks()).Create().Run(ctx))
+	names, _ = readTestTar(t, tmp.Join("loop.tar.gz"))
+	assert.Equal(t, []string{"css/", "css/site.css"}, names)
+}
@@ -0,0 +1,336 @@
+package modmake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrArchiveVerification is returned when an archive doesn't meet an [ArchiveExpectation].
+var ErrArchiveVerification = errors.New("archive verification failed")
+
+// ArchiveEntry describes an entry in a tar or zip archive.
+type ArchiveEntry struct {
+	// Path is the slash separated path of the entry, which ends with "/" for directories.
+	Path string
+	// Size is the uncompressed size of the entry's content.
+	Size int64
+	// Mode includes the entry's type and permission bits.
+	Mode fs.FileMode
+	// ModTime is the entry's modification time.
+	ModTime time.Time
+	// LinkTarget is the target of a symlink or hard link entry.
+	LinkTarget string
+	// SHA256 is the hex encoded checksum of a regular file's content.
+	SHA256 string
+}
+
+// ArchiveLister is implemented by archives that can list their entries, like [TarArchive] and [ZipArchive].
+type ArchiveLister interface {
+	List(ctx context.Context) ([]ArchiveEntry, error)
+}
+
+// List reads the archive and returns its entries in archive order, without extracting it.
+func (t *TarArchive) List(ctx context.Context) ([]ArchiveEntry, error) {
+	src, err := t.path.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open tar archive: %w", err)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+	dr, _, err := decompressReader(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create decompressing reader for '%s': %w", t.path, err)
+	}
+	defer func() {
+		_ = dr.Close()
+	}()
+	tr := tar.NewReader(dr)
+	var entries []ArchiveEntry
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive '%s': %w", t.path, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		entry := ArchiveEntry{
+			Path:       header.Name,
+			Size:       header.Size,
+			Mode:       header.FileInfo().Mode(),
+			ModTime:    header.ModTime,
+			LinkTarget: header.Linkname,
+		}
+		if header.Typeflag == tar.TypeDir && !strings.HasSuffix(entry.Path, "/") {
+			entry.Path += "/"
+		}
+		if header.Typeflag == tar.TypeReg {
+			entry.SHA256, _, err = hashSHA256(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read '%s' in '%s': %w", header.Name, t.path, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// List reads the archive and returns its entries in archive order, without extracting it.
+func (z *ZipArchive) List(ctx context.Context) ([]ArchiveEntry, error) {
+	zr, err := zip.OpenReader(z.path.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s' for reading: %w", z.path, err)
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+	entries := make([]ArchiveEntry, 0, len(zr.File))
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry := ArchiveEntry{
+			Path:    f.Name,
+			Size:    int64(f.UncompressedSize64),
+			Mode:    f.Mode(),
+			ModTime: f.Modified,
+		}
+		if !entry.Mode.IsDir() {
+			err := func() error {
+				r, err := f.Open()
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = r.Close()
+				}()
+				if entry.Mode&fs.ModeSymlink != 0 {
+					target, err := io.ReadAll(io.LimitReader(r, 4096))
+					entry.LinkTarget = string(target)
+					return err
+				}
+				entry.SHA256, _, err = hashSHA256(r)
+				return err
+			}()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read '%s' in '%s': %w", f.Name, z.path, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ArchiveExpectation describes the required content of an archive, and is checked with [TarArchive.Verify] or [ZipArchive.Verify].
+// Use ExpectArchive to create an ArchiveExpectation.
+type ArchiveExpectation struct {
+	contains    []string
+	modes       map[string]fs.FileMode
+	executables []string
+	forbidden   []string
+}
+
+// ExpectArchive creates a new, empty ArchiveExpectation.
+func ExpectArchive() *ArchiveExpectation {
+	return &ArchiveExpectation{
+		modes: map[string]fs.FileMode{},
+	}
+}
+
+// Contains requires that the archive has entries with the given paths.
+func (e *ArchiveExpectation) Contains(paths ...string) *ArchiveExpectation {
+	e.contains = append(e.contains, paths...)
+	return e
+}
+
+// Mode requires that the archive has an entry at the path, with exactly the given permission bits.
+func (e *ArchiveExpectation) Mode(path string, mode fs.FileMode) *ArchiveExpectation {
+	if len(path) == 0 {
+		panic("empty path")
+	}
+	e.modes[path] = mode.Perm()
+	return e
+}
+
+// Executable requires that each pattern matches at least one entry's path, and that all matching entries are executable.
+// See [MatchGlob] for the pattern syntax.
+func (e *ArchiveExpectation) Executable(patterns ...string) *ArchiveExpectation {
+	mustValidateGlobs(patterns)
+	e.executables = append(e.executables, patterns...)
+	return e
+}
+
+// Forbid requires that no entries match any of the patterns, see [MatchGlob] for the pattern syntax.
+// For example, "*.go" forbids Go source files anywhere in the archive.
+func (e *ArchiveExpectation) Forbid(patterns ...string) *ArchiveExpectation {
+	mustValidateGlobs(patterns)
+	e.forbidden = append(e.forbidden, patterns...)
+	return e
+}
+
+// check returns every way the entries don't meet the expectation.
+func (e *ArchiveExpectation) check(entries []ArchiveEntry) []error {
+	var (
+		errs   []error
+		byPath = map[string]ArchiveEntry{}
+	)
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	for _, p := range e.contains {
+		if _, ok := byPath[p]; !ok {
+			errs = append(errs, fmt.Errorf("missing required entry '%s'", p))
+		}
+	}
+	for _, p := range sortedKeys(e.modes) {
+		entry, ok := byPath[p]
+		if !ok {
+			errs = append(errs, fmt.Errorf("missing entry '%s' with mode %s", p, e.modes[p]))
+			continue
+		}
+		if entry.Mode.Perm() != e.modes[p] {
+			errs = append(errs, fmt.Errorf("entry '%s' has mode %s, expected %s", p, entry.Mode.Perm(), e.modes[p]))
+		}
+	}
+	for _, pattern := range e.executables {
+		matched := false
+		for _, entry := range entries {
+			if entry.Mode.IsDir() || !matchAnyGlob([]string{pattern}, strings.TrimSuffix(entry.Path, "/")) {
+				continue
+			}
+			matched = true
+			if entry.Mode.Perm()&0111 == 0 {
+				errs = append(errs, fmt.Errorf("entry '%s' is not executable, mode is %s", entry.Path, entry.Mode.Perm()))
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Errorf("no entries match executable pattern '%s'", pattern))
+		}
+	}
+	for _, entry := range entries {
+		if matchAnyGlob(e.forbidden, strings.TrimSuffix(entry.Path, "/")) {
+			errs = append(errs, fmt.Errorf("forbidden entry '%s'", entry.Path))
+		}
+	}
+	return errs
+}
+
+func verifyArchive(ctx context.Context, name string, archive ArchiveLister, expect *ArchiveExpectation) error {
+	ctx, log := WithGroup(ctx, "verify archive")
+	entries, err := archive.List(ctx)
+	if err != nil {
+		return log.WrapErr(err)
+	}
+	errs := expect.check(entries)
+	if len(errs) == 0 {
+		log.Debug("Verified %d entries in '%s'", len(entries), name)
+		return nil
+	}
+	for _, err := range errs {
+		log.Error("%v", err)
+	}
+	return log.WrapErr(fmt.Errorf("%w: '%s' has %d problem(s): %w", ErrArchiveVerification, name, len(errs), errors.Join(errs...)))
+}
+
+// Verify creates a Task that checks the archive's entries against the expectation, without extracting it.
+// All problems are reported, and the Task fails with [ErrArchiveVerification] if there are any.
+func (t *TarArchive) Verify(expect *ArchiveExpectation) Task {
+	if expect == nil {
+		panic("nil expectation")
+	}
+	return func(ctx context.Context) error {
+		return verifyArchive(ctx, t.path.String(), t, expect)
+	}
+}
+
+// Verify creates a Task that checks the archive's entries against the expectation, without extracting it.
+// All problems are reported, and the Task fails with [ErrArchiveVerification] if there are any.
+func (z *ZipArchive) Verify(expect *ArchiveExpectation) Task {
+	if expect == nil {
+		panic("nil expectation")
+	}
+	return func(ctx context.Context) error {
+		return verifyArchive(ctx, z.path.String(), z, expect)
+	}
+}
+
+// ArchiveDiff describes the differences in content between two archives.
+// Modification times are not compared.
+type ArchiveDiff struct {
+	// Added lists paths only in the second archive.
+	Added []string
+	// Removed lists paths only in the first archive.
+	Removed []string
+	// Changed lists paths in both archives with a different type, mode, link target, or content.
+	Changed []string
+}
+
+// Empty returns true if the archives have the same content.
+func (d ArchiveDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String formats the diff with one path per line, prefixed with "+" if added, "-" if removed, or "~" if changed.
+func (d ArchiveDiff) String() string {
+	var buf strings.Builder
+	for _, group := range []struct {
+		prefix string
+		paths  []string
+	}{{"+", d.Added}, {"-", d.Removed}, {"~", d.Changed}} {
+		for _, p := range group.paths {
+			buf.WriteString(group.prefix + " " + p + "\n")
+		}
+	}
+	return buf.String()
+}
+
+// DiffArchives compares the contents of two archives, which may be any mix of tar and zip.
+func DiffArchives(ctx context.Context, a, b ArchiveLister) (ArchiveDiff, error) {
+	var diff ArchiveDiff
+	aEntries, err := a.List(ctx)
+	if err != nil {
+		return diff, err
+	}
+	bEntries, err := b.List(ctx)
+	if err != nil {
+		return diff, err
+	}
+	aByPath := map[string]ArchiveEntry{}
+	for _, entry := range aEntries {
+		aByPath[entry.Path] = entry
+	}
+	bByPath := map[string]ArchiveEntry{}
+	for _, entry := range bEntries {
+		bByPath[entry.Path] = entry
+		aEntry, ok := aByPath[entry.Path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry.Path)
+		case aEntry.Mode != entry.Mode || aEntry.LinkTarget != entry.LinkTarget || aEntry.SHA256 != entry.SHA256:
+			diff.Changed = append(diff.Changed, entry.Path)
+		}
+	}
+	for _, entry := range aEntries {
+		if _, ok := bByPath[entry.Path]; !ok {
+			diff.Removed = append(diff.Removed, entry.Path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
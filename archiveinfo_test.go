@@ -0,0 +1,110 @@
+package modmake
+
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReleaseDir(t *testing.T) PathString {
+	dir := Path(t.TempDir(), "release")
+	require.NoError(t, dir.MkdirAll(0755))
+	require.NoError(t, dir.Join("app").WriteFile([]byte("binary"), 0755))
+	require.NoError(t, dir.Join("README.md").WriteFile([]byte("readme"), 0644))
+	require.NoError(t, dir.Join("docs").MkdirAll(0755))
+	require.NoError(t, dir.Join("docs", "guide.md").WriteFile([]byte("guide"), 0644))
+	return dir
+}
+
+func TestArchive_List(t *testing.T) {
+	ctx := context.Background()
+	release := setupReleaseDir(t)
+	tmp := release.Dir()
+	require.NoError(t, Tar(tmp.Join("release.tar.gz")).AddDir(release, "").Create().Run(ctx))
+	require.NoError(t, Zip(tmp.Join("release.zip")).AddDir(release, "").Create().Run(ctx))
+
+	for _, lister := range []ArchiveLister{Tar(tmp.Join("release.tar.gz")), Zip(tmp.Join("release.zip"))} {
+		entries, err := lister.List(ctx)
+		require.NoError(t, err)
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		assert.Equal(t, []string{"README.md", "app", "docs/", "docs/guide.md"}, paths)
+		assert.True(t, entries[2].Mode.IsDir())
+		assert.Equal(t, int64(6), entries[1].Size)
+		assert.Equal(t, "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd", entries[1].SHA256)
+		if runtime.GOOS != "windows" {
+			assert.Equal(t, fs.FileMode(0755), entries[1].Mode.Perm())
+		}
+	}
+}
+
+func TestArchive_Verify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File modes aren't fully supported on Windows")
+	}
+	ctx := context.Background()
+	release := setupReleaseDir(t)
+	tmp := release.Dir()
+	tarball := Tar(tmp.Join("release.tar.gz"))
+	require.NoError(t, tarball.AddDir(release, "").Create().Run(ctx))
+	zipFile := Zip(tmp.Join("release.zip"))
+	require.NoError(t, zipFile.AddDir(release, "").Create().Run(ctx))
+
+	good := ExpectArchive().
+		Contains("README.md", "docs/").
+		Mode("app", 0755).
+		Executable("app").
+		Forbid("*.go", ".DS_Store")
+	assert.NoError(t, tarball.Verify(good).Run(ctx))
+	assert.NoError(t, zipFile.Verify(good).Run(ctx))
+
+	bad := ExpectArchive().
+		Contains("LICENSE").
+		Mode("README.md", 0600).
+		Executable("docs/*", "bin/*").
+		Forbid("*.md")
+	err := tarball.Verify(bad).Run(ctx)
+	assert.ErrorIs(t, err, ErrArchiveVerification)
+	for _, problem := range []string{
+		"missing required entry 'LICENSE'",
+		"entry 'README.md' has mode -rw-r--r--, expected -rw-------",
+		"entry 'docs/guide.md' is not executable",
+		"no entries match executable pattern 'bin/*'",
+		"forbidden entry 'docs/guide.md'",
+		"forbidden entry 'README.md'",
+	} {
+		assert.ErrorContains(t, err, problem)
+	}
+}
+
+func TestDiffArchives(t *testing.T) {
+	ctx := context.Background()
+	release := setupReleaseDir(t)
+	tmp := release.Dir()
+	before := Tar(tmp.Join("before.tar.gz"))
+	require.NoError(t, before.AddDir(release, "").Create().Run(ctx))
+
+	require.NoError(t, release.Join("README.md").WriteFile([]byte("updated readme"), 0644))
+	require.NoError(t, release.Join("docs", "guide.md").Remove())
+	require.NoError(t, release.Join("main.go").WriteFile([]byte("package main"), 0644))
+	after := Zip(tmp.Join("after.zip"))
+	require.NoError(t, after.AddDir(release, "").Create().Run(ctx))
+
+	diff, err := DiffArchives(ctx, before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, diff.Added)
+	assert.Equal(t, []string{"docs/guide.md"}, diff.Removed)
+	assert.Equal(t, []string{"README.md"}, diff.Changed)
+	assert.Equal(t, "+ main.go\n- docs/guide.md\n~ README.md\n", diff.String())
+	assert.False(t, diff.Empty())
+
+	diff, err = DiffArchives(ctx, before, before)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+}
@@ -0,0 +1,380 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// OtherPackage is the package name used for symbols that can't be attributed to a Go package, like runtime type metadata.
+const OtherPackage = "<other>"
+
+// BinarySize describes how the bytes of an executable are used.
+type BinarySize struct {
+	// Total is the size of the file in bytes.
+	Total int64 `json:"total"`
+	// Sections maps section names to their size in the file.
+	Sections map[string]int64 `json:"sections"`
+	// Packages maps Go package names to the size of their symbols.
+	// This will be empty if the executable was built without a symbol table, as with [GoBuild.StripDebugSymbols].
+	Packages map[string]int64 `json:"packages"`
+}
+
+// AnalyzeBinary reports the size by section and by package of an ELF, Mach-O (including universal binaries), or PE executable.
+func AnalyzeBinary(binary PathString) (*BinarySize, error) {
+	data, err := binary.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	size := &BinarySize{
+		Total:    int64(len(data)),
+		Sections: map[string]int64{},
+		Packages: map[string]int64{},
+	}
+	r := bytes.NewReader(data)
+	switch {
+	case bytes.HasPrefix(data, []byte(elf.ELFMAG)):
+		err = size.analyzeELF(r)
+	case bytes.HasPrefix(data, []byte("MZ")):
+		err = size.analyzePE(r)
+	case bytes.HasPrefix(data, []byte{0xca, 0xfe, 0xba, 0xbe}):
+		var fat *macho.FatFile
+		fat, err = macho.NewFatFile(r)
+		if err != nil {
+			break
+		}
+		for _, arch := range fat.Arches {
+			if err = size.analyzeMachO(arch.File); err != nil {
+				break
+			}
+		}
+	default:
+		var f *macho.File
+		f, err = macho.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an ELF, Mach-O, or PE executable", binary)
+		}
+		err = size.analyzeMachO(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze '%s': %w", binary, err)
+	}
+	return size, nil
+}
+
+// symbolPackage returns the Go package that a symbol belongs to.
+func symbolPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot <= 0 {
+		return OtherPackage
+	}
+	pkg := name[:slash+1+dot]
+	if strings.ContainsAny(pkg, ":[]() *") {
+		return OtherPackage
+	}
+	return pkg
+}
+
+// addressedSymbol is a symbol without an explicit size, which is inferred from the next symbol's address.
+type addressedSymbol struct {
+	name    string
+	section int
+	addr    uint64
+}
+
+// addSymbolsByAddress attributes the space between symbols in the same section to the preceding symbol's package.
+// The sectionEnd function returns the end address of the file-resident portion of a section.
+func (s *BinarySize) addSymbolsByAddress(syms []addressedSymbol, sectionEnd func(section int) uint64) {
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].section != syms[j].section {
+			return syms[i].section < syms[j].section
+		}
+		return syms[i].addr < syms[j].addr
+	})
+	for i, sym := range syms {
+		end := sectionEnd(sym.section)
+		if i+1 < len(syms) && syms[i+1].section == sym.section && syms[i+1].addr < end {
+			end = syms[i+1].addr
+		}
+		if end > sym.addr {
+			s.Packages[symbolPackage(sym.name)] += int64(end - sym.addr)
+		}
+	}
+}
+
+func (s *BinarySize) analyzeELF(r io.ReaderAt) error {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return err
+	}
+	for _, sect := range f.Sections {
+		if sect.Type == elf.SHT_NOBITS || sect.Type == elf.SHT_NULL {
+			continue
+		}
+		s.Sections[sect.Name] += int64(sect.FileSize)
+	}
+	syms, err := f.Symbols()
+	if err != nil {
+		if errors.Is(err, elf.ErrNoSymbols) {
+			return nil
+		}
+		return err
+	}
+	for _, sym := range syms {
+		typ := elf.ST_TYPE(sym.Info)
+		if typ != elf.STT_FUNC && typ != elf.STT_OBJECT {
+			continue
+		}
+		if int(sym.Section) >= len(f.Sections) || f.Sections[sym.Section].Type == elf.SHT_NOBITS {
+			continue
+		}
+		s.Packages[symbolPackage(sym.Name)] += int64(sym.Size)
+	}
+	return nil
+}
+
+func (s *BinarySize) analyzeMachO(f *macho.File) error {
+	const (
+		sectionTypeMask  = 0xff
+		zeroFill         = 0x1
+		gbZeroFill       = 0xc
+		threadLocalZeros = 0x12
+	)
+	isZeroFill := func(sect *macho.Section) bool {
+		switch sect.Flags & sectionTypeMask {
+		case zeroFill, gbZeroFill, threadLocalZeros:
+			return true
+		}
+		return false
+	}
+	for _, sect := range f.Sections {
+		if isZeroFill(sect) {
+			continue
+		}
+		s.Sections[sect.Seg+","+sect.Name] += int64(sect.Size)
+	}
+	if f.Symtab == nil {
+		return nil
+	}
+	const nSect = 0xe
+	var syms []addressedSymbol
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type&0xe0 != 0 || sym.Type&0x0e != nSect || sym.Sect == 0 || int(sym.Sect) > len(f.Sections) {
+			// Skip debugging, undefined, and absolute symbols.
+			continue
+		}
+		if isZeroFill(f.Sections[sym.Sect-1]) {
+			continue
+		}
+		syms = append(syms, addressedSymbol{name: strings.TrimPrefix(sym.Name, "_"), section: int(sym.Sect), addr: sym.Value})
+	}
+	s.addSymbolsByAddress(syms, func(section int) uint64 {
+		sect := f.Sections[section-1]
+		return sect.Addr + sect.Size
+	})
+	return nil
+}
+
+func (s *BinarySize) analyzePE(r io.ReaderAt) error {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return err
+	}
+	for _, sect := range f.Sections {
+		s.Sections[sect.Name] += int64(sect.Size)
+	}
+	var syms []addressedSymbol
+	for _, sym := range f.Symbols {
+		if sym.SectionNumber <= 0 || int(sym.SectionNumber) > len(f.Sections) {
+			continue
+		}
+		syms = append(syms, addressedSymbol{name: sym.Name, section: int(sym.SectionNumber), addr: uint64(sym.Value)})
+	}
+	s.addSymbolsByAddress(syms, func(section int) uint64 {
+		sect := f.Sections[section-1]
+		// Only count initialized data that's present in the file.
+		end := sect.VirtualSize
+		if sect.Size < end {
+			end = sect.Size
+		}
+		return uint64(end)
+	})
+	return nil
+}
+
+// SizeCheck analyzes the size of a built executable, compares it to a stored baseline, and enforces size budgets.
+// Use CheckSize to create a SizeCheck.
+type SizeCheck struct {
+	binary    PathString
+	baseline  PathString
+	maxBytes  int64
+	maxGrowth float64
+	top       int
+}
+
+// CheckSize creates a new SizeCheck for the given executable.
+// By default, the largest 10 packages and sections are reported, and no budgets are enforced.
+func CheckSize(binary PathString) *SizeCheck {
+	if len(binary) == 0 {
+		panic("empty binary path")
+	}
+	return &SizeCheck{
+		binary: binary,
+		top:    10,
+	}
+}
+
+// Baseline sets the location of a baseline written by [SizeCheck.WriteBaseline].
+// Changes from the baseline will be reported, and used to enforce [SizeCheck.MaxGrowth].
+// A missing baseline file is not an error, so a baseline may be established later.
+func (c *SizeCheck) Baseline(baseline PathString) *SizeCheck {
+	c.baseline = baseline
+	return c
+}
+
+// MaxBytes fails the check if the executable is larger than the given number of bytes.
+// A value of 0 disables this budget.
+func (c *SizeCheck) MaxBytes(maxBytes int64) *SizeCheck {
+	if maxBytes < 0 {
+		panic("negative byte budget")
+	}
+	c.maxBytes = maxBytes
+	return c
+}
+
+// MaxGrowth fails the check if the executable has grown by more than the given percentage since the baseline.
+// A value of 0 disables this budget.
+func (c *SizeCheck) MaxGrowth(percent float64) *SizeCheck {
+	if percent < 0 {
+		panic("negative growth budget")
+	}
+	c.maxGrowth = percent
+	return c
+}
+
+// Top sets the number of packages and sections reported.
+func (c *SizeCheck) Top(top int) *SizeCheck {
+	if top < 0 {
+		panic("negative top count")
+	}
+	c.top = top
+	return c
+}
+
+func (c *SizeCheck) readBaseline() (*BinarySize, error) {
+	if len(c.baseline) == 0 || !c.baseline.IsFile() {
+		return nil, nil
+	}
+	data, err := c.baseline.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	var baseline BinarySize
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse size baseline '%s': %w", c.baseline, err)
+	}
+	return &baseline, nil
+}
+
+// WriteBaseline creates a Task that records the current size of the executable as the baseline.
+func (c *SizeCheck) WriteBaseline() Task {
+	return func(ctx context.Context) error {
+		_, log := WithGroup(ctx, "size baseline")
+		if len(c.baseline) == 0 {
+			return log.WrapErr(errors.New("no baseline location specified"))
+		}
+		size, err := AnalyzeBinary(c.binary)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		data, err := json.MarshalIndent(size, "", "  ")
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if err := c.baseline.Dir().MkdirAll(0755); err != nil {
+			return log.WrapErr(err)
+		}
+		if err := c.baseline.WriteFile(append(data, '\n'), 0644); err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Wrote size baseline for '%s' to '%s'", c.binary, c.baseline)
+		return nil
+	}
+}
+
+// Run analyzes the executable, logs a size report, and returns an error if any budget is exceeded.
+func (c *SizeCheck) Run(ctx context.Context) error {
+	_, log := WithGroup(ctx, "size check")
+	size, err := AnalyzeBinary(c.binary)
+	if err != nil {
+		return log.WrapErr(err)
+	}
+	baseline, err := c.readBaseline()
+	if err != nil {
+		return log.WrapErr(err)
+	}
+	hasBaseline := baseline != nil
+	change := func(before, after int64) string {
+		if !hasBaseline {
+			return ""
+		}
+		if before == 0 {
+			return " (new)"
+		}
+		return fmt.Sprintf(" (%+d bytes, %+.2f%%)", after-before, float64(after-before)/float64(before)*100)
+	}
+	if baseline == nil {
+		baseline = &BinarySize{}
+	}
+	log.Info("%s: %d bytes%s", c.binary, size.Total, change(baseline.Total, size.Total))
+	for _, name := range topSizes(size.Sections, c.top) {
+		log.Info("  section %-24s %10d bytes%s", name, size.Sections[name], change(baseline.Sections[name], size.Sections[name]))
+	}
+	for _, name := range topSizes(size.Packages, c.top) {
+		log.Info("  package %-48s %10d bytes%s", name, size.Packages[name], change(baseline.Packages[name], size.Packages[name]))
+	}
+	if len(baseline.Packages) > 0 {
+		for _, name := range sortedKeys(size.Packages) {
+			if _, ok := baseline.Packages[name]; !ok {
+				log.Warn("  new package %s adds %d bytes", name, size.Packages[name])
+			}
+		}
+	}
+
+	if c.maxBytes > 0 && size.Total > c.maxBytes {
+		return log.WrapErr(fmt.Errorf("'%s' is %d bytes, which exceeds the budget of %d bytes", c.binary, size.Total, c.maxBytes))
+	}
+	if c.maxGrowth > 0 && baseline.Total > 0 {
+		growth := float64(size.Total-baseline.Total) / float64(baseline.Total) * 100
+		if growth > c.maxGrowth {
+			return log.WrapErr(fmt.Errorf("'%s' grew by %.2f%% since the baseline, which exceeds the budget of %.2f%%", c.binary, growth, c.maxGrowth))
+		}
+	}
+	return nil
+}
+
+// Task returns a Task that runs this SizeCheck.
+func (c *SizeCheck) Task() Task {
+	return c.Run
+}
+
+// topSizes returns the names of the largest entries in sizes, up to the given count.
+func topSizes(sizes map[string]int64, top int) []string {
+	names := sortedKeys(sizes)
+	sort.SliceStable(names, func(i, j int) bool {
+		return sizes[names[i]] > sizes[names[j]]
+	})
+	if len(names) > top {
+		names = names[:top]
+	}
+	return names
+}
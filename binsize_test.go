@@ -0,0 +1,125 @@
+package modmake
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolPackage(t *testing.T) {
+	tests := map[string]string{
+		"runtime.mallocgc":                            "runtime",
+		"main.main":                                   "main",
+		"github.com/saylorsolutions/modmake.Go":       "github.com/saylorsolutions/modmake",
+		"github.com/a/b.(*T).Method":                  "github.com/a/b",
+		"vendor/golang.org/x/net/dns/dnsmessage.init": "vendor/golang.org/x/net/dns/dnsmessage",
+		"go:buildinfo":                                OtherPackage,
+		"type:*os.File":                               OtherPackage,
+		"go:itab.*os.File,io.Writer":                  OtherPackage,
+		"noPackage":                                   OtherPackage,
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, symbolPackage(name), name)
+	}
+}
+
+func buildForSize(t *testing.T, os, arch string, strip bool) PathString {
+	output := Path(t.TempDir(), "testingbuild")
+	gb := Go().Build(Go().ToModulePath("testingbuild")).
+		OS(os).
+		Arch(arch).
+		CgoEnabled(false).
+		OutputFilename(output)
+	if strip {
+		gb.StripDebugSymbols()
+	}
+	require.NoError(t, gb.Run(context.Background()))
+	return output
+}
+
+func TestAnalyzeBinary(t *testing.T) {
+	tests := map[string]struct {
+		os, arch, textSection string
+	}{
+		"ELF":    {os: "linux", arch: "amd64", textSection: ".text"},
+		"Mach-O": {os: "darwin", arch: "arm64", textSection: "__TEXT,__text"},
+		"PE":     {os: "windows", arch: "amd64", textSection: ".text"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			binary := buildForSize(t, tc.os, tc.arch, false)
+			size, err := AnalyzeBinary(binary)
+			require.NoError(t, err)
+			fi, err := binary.Stat()
+			require.NoError(t, err)
+			assert.Equal(t, fi.Size(), size.Total)
+			assert.NotZero(t, size.Sections[tc.textSection])
+			for _, pkg := range []string{"runtime", "fmt", "main"} {
+				assert.NotZero(t, size.Packages[pkg], "Package %s should have symbols", pkg)
+			}
+			var packageTotal int64
+			for _, pkgSize := range size.Packages {
+				packageTotal += pkgSize
+			}
+			assert.Less(t, packageTotal, size.Total, "Package sizes should not exceed the file size")
+		})
+	}
+
+	stripped, err := AnalyzeBinary(buildForSize(t, "linux", "amd64", true))
+	require.NoError(t, err)
+	assert.Empty(t, stripped.Packages, "Stripped binaries have no symbols to attribute")
+	assert.NotZero(t, stripped.Sections[".text"])
+
+	_, err = AnalyzeBinary(Path("binsize.go"))
+	assert.Error(t, err, "Non-executables should be rejected")
+}
+
+func TestSizeCheck(t *testing.T) {
+	ctx := context.Background()
+	binary := buildForSize(t, "linux", "amd64", false)
+	baseline := Path(t.TempDir(), "baseline", "testingbuild.json")
+
+	check := CheckSize(binary).Baseline(baseline).MaxGrowth(5)
+	require.NoError(t, check.Run(ctx), "A missing baseline should not fail the check")
+	require.NoError(t, check.WriteBaseline().Run(ctx))
+	require.True(t, baseline.IsFile())
+	require.NoError(t, check.Run(ctx), "An unchanged binary should pass")
+
+	assert.Error(t, CheckSize(binary).MaxBytes(1024).Run(ctx), "Byte budget should be enforced")
+
+	data, err := baseline.ReadFile()
+	require.NoError(t, err)
+	var recorded BinarySize
+	require.NoError(t, json.Unmarshal(data, &recorded))
+	recorded.Total = recorded.Total * 9 / 10
+	data, err = json.Marshal(recorded)
+	require.NoError(t, err)
+	require.NoError(t, baseline.WriteFile(data, 0644))
+	assert.Error(t, check.Run(ctx), "Growth budget should be enforced")
+	assert.NoError(t, CheckSize(binary).Baseline(baseline).MaxGrowth(20).Run(ctx))
+}
+
+func TestAppBuild_SizeBudget(t *testing.T) {
+	a := NewAppBuild("testapp", "cmd/modmake", "1.0.0").
+		Binary("other", "testingbuild").
+		SizeBaseline("sizes").
+		SizeBudget(10_000_000, 5)
+	v := a.Variant("linux", "amd64").SizeBudget(1000, 0)
+	other := a.Variant("darwin", "arm64")
+	assert.True(t, a.sizeChecksEnabled(v))
+
+	check := a.sizeCheck(v, a.binaries()[1])
+	assert.Equal(t, Path("sizes", "other_linux_amd64.json"), check.baseline)
+	assert.Equal(t, int64(1000), check.maxBytes, "Variant budget should override the app budget")
+	assert.Zero(t, check.maxGrowth)
+	check = a.sizeCheck(other, a.binaries()[0])
+	assert.Equal(t, int64(10_000_000), check.maxBytes)
+	assert.InDelta(t, 5.0, check.maxGrowth, 0.001)
+
+	b := a.AsBuild()
+	_, ok := b.StepOk("update-size-baseline-testapp")
+	assert.True(t, ok, "Baseline step should be generated")
+}
@@ -25,9 +25,12 @@ type Build struct {
 	buildStep    *Step
 	packageStep  *Step
 	logger       Logger
+	toolManager  *ToolManager
 
 	workdir   string
 	stepNames map[string]*Step
+	profiles  map[string]*Profile
+	hermetic  bool
 }
 
 var (
@@ -147,6 +150,14 @@ func (b *Build) Workdir(workdir string) *Build {
 	return b
 }
 
+// Hermetic makes every [Command] run by this Build start from a minimal environment, as described in [Command.Hermetic].
+// This may also be enabled for a single run with the --hermetic flag.
+// Variables set by the selected profile, or listed in [EnvHermeticAllow], are still passed through.
+func (b *Build) Hermetic() *Build {
+	b.hermetic = true
+	return b
+}
+
 // Tools allows easily referencing the tools Step.
 func (b *Build) Tools() *Step {
 	return b.toolsStep
@@ -274,3 +274,16 @@ func ExampleCallRemote() {
 
 	// Output:
 }
+
+func TestInvokedSteps(t *testing.T) {
+	var steps []string
+	b := NewBuild()
+	b.Test().Skip()
+	b.AddNewStep("capture", "", Task(func(ctx context.Context) error {
+		steps = InvokedSteps(ctx)
+		return nil
+	}))
+	assert.NoError(t, b.ExecuteErr("capture"))
+	assert.Equal(t, []string{"capture"}, steps)
+	assert.Nil(t, InvokedSteps(context.Background()))
+}
@@ -26,6 +26,22 @@ func sigCtx() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
+type invokedStepsKeyType string
+
+const (
+	invokedStepsKey = invokedStepsKeyType("invoked steps")
+)
+
+// InvokedSteps returns the step names that were requested when the build was executed with [Build.Execute] or [Build.ExecuteErr].
+// If the context was not created by a build execution, then nil is returned.
+func InvokedSteps(ctx context.Context) []string {
+	steps, ok := ctx.Value(invokedStepsKey).([]string)
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), steps...)
+}
+
 // Execute executes a Build as configured, as if it were a CLI application.
 // It takes string arguments to allow overriding the default of capturing os.Args.
 // Run this with the -h flag to see usage information.
@@ -59,51 +75,7 @@ func (b *Build) ExecuteErr(args ...string) (err error) {
 	if err := b.cyclesCheck(); err != nil {
 		return err
 	}
-	flags := flag.NewFlagSet("build", flag.ContinueOnError)
-
-	var (
-		flagHelp     bool
-		flagSkip     []string
-		flagNoSkip   []string
-		flagSkipDeps bool
-		flagTimeout  time.Duration
-		flagDryRun   bool
-		flagDebugLog bool
-		flagVerbose  bool
-		flagNoColor  bool
-	)
-
-	flags.BoolVarP(&flagHelp, "help", "h", false, "Prints this usage information.")
-	flags.StringArrayVar(&flagSkip, "skip", nil, "Skips one or more named steps.")
-	flags.StringArrayVar(&flagNoSkip, "no-skip", nil, "Specifies that the one or more steps should not be skipped, if they need to run. Note that specifically referencing a step will always run it, even if it's skipped by default.")
-	flags.BoolVar(&flagSkipDeps, "only", false, "Skips running the named step's dependencies, only runs the step itself.")
-	flags.DurationVar(&flagTimeout, "timeout", 0, "Sets a timeout duration for this build run.")
-	flags.BoolVar(&flagDryRun, "dry-run", false, "Runs the build's steps in dry run mode. No actual operations will be executed, but logs will still be printed.")
-	flags.BoolVar(&flagDebugLog, "debug", false, "Specifies that debug step logs should be emitted.")
-	flags.BoolVarP(&flagVerbose, "verbose", "v", false, "Used with 'steps' or 'graph' to output all steps, including those that do nothing.")
-	flags.BoolVar(&flagNoColor, "no-color", false, "Used to disable colorized output.")
-
-	flags.Usage = func() {
-		fmt.Printf(`Executes this modmake build
-
-Usage:
-	go run ./BUILD_FILE [FLAGS] STEP...
-	go run ./BUILD_DIR [FLAGS] STEP...
-
-BUILD_FILE is a Go source file that contains a main function that configures and executes a Modmake build.
-BUILD_DIR is a directory in a Go module that contains a BUILD_FILE.
-STEP is a named step in a Modmake build that may have dependencies, before/after hooks, and an operation. Multiple steps may be specified, and they will be executed in order.
-
-There are specialized commands that can be used to introspect the build, represented as STEPs.
-  - graph: Passing this command as the first argument will emit a step dependency graph with descriptions on standard out. This can also be generated with Build.Graph().
-  - steps: Prints the list of all steps in this build.
-
-See https://saylorsolutions.github.io/modmake for detailed usage information.
-
-%s
-
-`, flags.FlagUsages())
-	}
+	flags, opts := b.executeFlags()
 	if len(args) == 0 {
 		args = os.Args[1:]
 	}
@@ -111,29 +83,74 @@ See https://saylorsolutions.github.io/modmake for detailed usage information.
 		flags.Usage()
 		return err
 	}
-	if flagNoColor {
+
+	var (
+		profile        *Profile
+		profileFromEnv bool
+	)
+	if len(opts.profile) == 0 {
+		opts.profile = os.Getenv(EnvProfile)
+		profileFromEnv = true
+	}
+	if len(opts.profile) > 0 {
+		loaded, loadErr := b.loadProfile(Go().ModuleRoot(), opts.profile)
+		switch {
+		case loadErr != nil && profileFromEnv:
+			// The environment variable is inherited by called builds, which may not define the same profiles.
+			b.logger.Warn("Ignoring profile from %s: %v\n", EnvProfile, loadErr)
+		case loadErr != nil:
+			return loadErr
+		default:
+			profile = loaded
+		}
+		if profile != nil && len(profile.flags) > 0 {
+			// Parse again so explicitly passed flags take precedence over the profile's flags.
+			flags, opts = b.executeFlags()
+			if err := flags.Parse(append(append([]string{}, profile.flags...), args...)); err != nil {
+				flags.Usage()
+				return fmt.Errorf("failed to parse flags with profile '%s': %w", profile.name, err)
+			}
+		}
+	}
+	if opts.noColor {
 		color.NoColor = true
 	}
 
-	if flags.NArg() == 0 || flagHelp {
+	if flags.NArg() == 0 || opts.help {
 		flags.Usage()
 		return
 	}
 
-	if flagDebugLog || flagDryRun {
+	if opts.debugLog || opts.dryRun {
 		_stepDebugLog = true
 	}
 
 	ctx, cancel := sigCtx()
 	defer cancel()
+	ctx = context.WithValue(ctx, invokedStepsKey, flags.Args())
+
+	if b.hermetic || opts.hermetic {
+		// Variables set by the profile or passed to the CLI with -e were chosen for this build, so they're allowed through.
+		allow := hermeticAllowFromEnv()
+		if profile != nil {
+			allow = append(allow, sortedKeys(profile.env)...)
+		}
+		ctx = WithHermeticEnv(ctx, allow...)
+	}
 
-	if flagTimeout > 0 {
+	if opts.timeout > 0 {
 		var _cancel context.CancelFunc
-		ctx, _cancel = context.WithTimeout(ctx, flagTimeout)
+		ctx, _cancel = context.WithTimeout(ctx, opts.timeout)
 		defer _cancel()
 	}
 
-	for _, skip := range flagSkip {
+	if profile != nil {
+		b.logger.Info("Using profile '%s'\n", profile.name)
+		if err := profile.apply(b); err != nil {
+			return err
+		}
+	}
+	for _, skip := range opts.skip {
 		step, ok := b.StepOk(skip)
 		if !ok {
 			b.logger.Warn("User asked that step '%s' be skipped, but it doesn't exist in this model\n", skip)
@@ -141,7 +158,7 @@ See https://saylorsolutions.github.io/modmake for detailed usage information.
 		}
 		step.Skip()
 	}
-	for _, noskip := range flagNoSkip {
+	for _, noskip := range opts.noSkip {
 		step, ok := b.StepOk(noskip)
 		if !ok {
 			b.logger.Warn("User asked that step '%s' not be skipped, but it doesn't exist in this model\n", noskip)
@@ -151,20 +168,20 @@ See https://saylorsolutions.github.io/modmake for detailed usage information.
 	}
 
 	start := time.Now()
-	if flagDryRun {
+	if opts.dryRun {
 		b.logger.Info("Running build in %s mode, steps will not run.\n", okColor("DRY RUN"))
 	}
 	for i, stepName := range flags.Args() {
 		switch {
 		case i == 0 && stepName == "graph":
-			b.Graph(flagVerbose)
+			b.Graph(opts.verbose)
 			return
 		case i == 0 && stepName == "steps":
 			var buf strings.Builder
 			steps := b.Steps()
 			for i := 0; i < len(steps); i++ {
 				step := b.Step(steps[i])
-				if flagVerbose || step.hasOperation() {
+				if opts.verbose || step.hasOperation() {
 					buf.WriteString(fmt.Sprintf("%s - %s\n", debugColor(steps[i]), step.description))
 				}
 			}
@@ -175,14 +192,14 @@ See https://saylorsolutions.github.io/modmake for detailed usage information.
 			if !ok {
 				return fmt.Errorf("build step '%s' does not exist", errColor(stepName))
 			}
-			if flagSkipDeps {
+			if opts.skipDeps {
 				step.SkipDependencies()
 			}
 
 			// Make sure that this step is not skipped, since it was called out by name.
 			step.UnSkip()
 			run := step.Run
-			if flagDryRun {
+			if opts.dryRun {
 				run = step.DryRun
 			}
 			if err := run(ctx); err != nil {
@@ -198,3 +215,68 @@ See https://saylorsolutions.github.io/modmake for detailed usage information.
 	b.logger.Info(okColor(fmt.Sprintf("Ran successfully in %s\n", time.Since(start).Round(time.Millisecond).String())))
 	return nil
 }
+
+type executeFlags struct {
+	help     bool
+	skip     []string
+	noSkip   []string
+	skipDeps bool
+	timeout  time.Duration
+	dryRun   bool
+	debugLog bool
+	verbose  bool
+	noColor  bool
+	profile  string
+	hermetic bool
+}
+
+func (b *Build) executeFlags() (*flag.FlagSet, *executeFlags) {
+	var opts executeFlags
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.BoolVarP(&opts.help, "help", "h", false, "Prints this usage information.")
+	flags.StringArrayVar(&opts.skip, "skip", nil, "Skips one or more named steps.")
+	flags.StringArrayVar(&opts.noSkip, "no-skip", nil, "Specifies that the one or more steps should not be skipped, if they need to run. Note that specifically referencing a step will always run it, even if it's skipped by default.")
+	flags.BoolVar(&opts.skipDeps, "only", false, "Skips running the named step's dependencies, only runs the step itself.")
+	flags.DurationVar(&opts.timeout, "timeout", 0, "Sets a timeout duration for this build run.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Runs the build's steps in dry run mode. No actual operations will be executed, but logs will still be printed.")
+	flags.BoolVar(&opts.debugLog, "debug", false, "Specifies that debug step logs should be emitted.")
+	flags.BoolVarP(&opts.verbose, "verbose", "v", false, "Used with 'steps' or 'graph' to output all steps, including those that do nothing.")
+	flags.BoolVar(&opts.noColor, "no-color", false, "Used to disable colorized output.")
+	flags.BoolVar(&opts.hermetic, "hermetic", false, "Runs commands with a minimal environment, so settings like GOFLAGS and CGO_ENABLED in the calling environment don't change the build.")
+	flags.StringVar(&opts.profile, "profile", "", "Selects a named profile that sets default environment variables, flags, and skipped steps. A profile may be defined in code or in a 'modmake.PROFILE.env' file. Defaults to the value of the "+EnvProfile+" environment variable.")
+
+	flags.Usage = func() {
+		fmt.Printf(`Executes this modmake build
+
+Usage:
+	go run ./BUILD_FILE [FLAGS] STEP...
+	go run ./BUILD_DIR [FLAGS] STEP...
+
+BUILD_FILE is a Go source file that contains a main function that configures and executes a Modmake build.
+BUILD_DIR is a directory in a Go module that contains a BUILD_FILE.
+STEP is a named step in a Modmake build that may have dependencies, before/after hooks, and an operation. Multiple steps may be specified, and they will be executed in order.
+
+There are specialized commands that can be used to introspect the build, represented as STEPs.
+  - graph: Passing this command as the first argument will emit a step dependency graph with descriptions on standard out. This can also be generated with Build.Graph().
+  - steps: Prints the list of all steps in this build.
+%s
+See https://saylorsolutions.github.io/modmake for detailed usage information.
+
+%s
+
+`, b.profileUsage(), flags.FlagUsages())
+	}
+	return flags, &opts
+}
+
+func (b *Build) profileUsage() string {
+	if len(b.profiles) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("\nProfiles defined in this build, selected with --profile:\n")
+	for _, name := range b.Profiles() {
+		buf.WriteString(fmt.Sprintf("  - %s: %s\n", name, b.profiles[name].description))
+	}
+	return buf.String()
+}
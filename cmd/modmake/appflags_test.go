@@ -19,3 +19,33 @@ func TestParseSeparate(t *testing.T) {
 	assert.Equal(t, "--skip-dependencies", flags.Arg(0))
 	assert.Equal(t, "client:build", flags.Arg(1))
 }
+
+func TestParseProfile(t *testing.T) {
+	flags := setupFlags()
+	err := flags.Parse([]string{"-p", "ci", "--", "--debug", "build"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ci", flags.profile)
+	assert.Equal(t, []string{"--debug", "build"}, flags.Args())
+}
+
+func TestWatchFileSet(t *testing.T) {
+	flags := setupFlags()
+	require.NoError(t, flags.Parse([]string{"--watch", "src:*.go, static/**"}))
+	files, err := watchFileSet(flags)
+	require.NoError(t, err)
+	for rel, expected := range map[string]bool{
+		"main.go":         true,
+		"pkg/util/x.go":   true,
+		"static/site.css": true,
+		"pkg/site.css":    false,
+	} {
+		matched, err := files.Match(rel)
+		require.NoError(t, err)
+		assert.Equal(t, expected, matched, rel)
+	}
+
+	require.NoError(t, flags.Parse([]string{"--watch", "src:[.go"}))
+	_, err = watchFileSet(flags)
+	assert.Error(t, err)
+}
@@ -12,6 +12,7 @@ type appFlags struct {
 	*flag.FlagSet
 	help          bool
 	envVars       []string
+	profile       string
 	rootOverride  string
 	buildOverride string
 	printVersion  bool
@@ -26,10 +27,11 @@ func setupFlags() *appFlags {
 	}
 	flags.BoolVarP(&flags.help, "help", "h", false, "Prints this usage information")
 	flags.StringArrayVarP(&flags.envVars, "set-env", "e", nil, "Sets one or more environment variables before calling the build")
+	flags.StringVarP(&flags.profile, "profile", "p", "", "Selects a named build profile, which sets default environment variables, build flags, and skipped steps")
 	flags.StringVarP(&flags.rootOverride, "workdir", "w", "", "Overrides the default logic of setting the working directory to the root of the module. Assumed to be a path relative to the module root")
 	flags.StringVarP(&flags.buildOverride, "build", "b", "", "Overrides the build location resolution logic and specifies where the build file is located")
 	flags.BoolVar(&flags.printVersion, "version", false, "Prints the git branch and hash from which the CLI was built")
-	flags.StringVar(&flags.watchDir, "watch", "", "Watches a directory for changes and re-runs the given step when a file changes. A comma-separated filename glob pattern list can be added to the watch path to only restart the task when matching files are changed. Filename globs, if used, should be separated from the path by ':'.")
+	flags.StringVar(&flags.watchDir, "watch", "", "Watches a directory for changes and re-runs the given step when a file changes. A comma-separated glob pattern list can be added to the watch path to only restart the task when matching files are changed. Globs, if used, should be separated from the path by ':'. Patterns without a '/' match file names at any depth, and other patterns are matched against the path relative to the watched directory, with '**' matching any number of directories.")
 	flags.BoolVar(&flags.watchSubdirs, "subdirs", false, "Used with 'watch' to also watch sub-directories for file changes. Sub-directories created after watching has started will not be watched for file changes.")
 	flags.DurationVar(&flags.watchInterval, "debounce", 200*time.Millisecond, "Sets the debounce interval for watched tasks. This only applies if the 'watch' flag is used. Must be greater than zero, and may need to be set higher if files matching a pattern are generated while the step(s) run.")
 
@@ -44,6 +46,7 @@ Here are some reasons you may want to use this:
 * You want the build's working directory to default to the root of your module.
 * You want to easily and consistently override the working directory for the build.
 * You want to easily set one or more environment variables for the build, maybe so they act as parameters.
+* You want to select a build profile that sets the same environment variables and flags every time.
 * You want to run a Modmake step repeatedly when files change.
 
 USAGE: modmake MODMAKE_FLAGS [-- BUILD_FLAGS] BUILD_STEPS
@@ -83,13 +83,27 @@ func run(ctx context.Context, flags *appFlags) error {
 }
 
 func runBuild(ctx context.Context, target string, flags *appFlags) error {
-	run := Go().Run(target).Arg(flags.Args()...)
+	run := Go().Run(target)
+	if len(flags.profile) > 0 {
+		run.Arg("--profile=" + flags.profile)
+	}
+	run.Arg(flags.Args()...)
+	var allow []string
 	for _, env := range flags.envVars {
 		kv := strings.Split(env, "=")
 		if len(kv) != 2 {
 			return fmt.Errorf("invalid environment variable format, '%s' must be 'KEY=VALUE'", env)
 		}
-		run.Env(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		key := strings.TrimSpace(kv[0])
+		run.Env(key, strings.TrimSpace(kv[1]))
+		allow = append(allow, key)
+	}
+	if len(allow) > 0 {
+		// Lets a hermetic build keep the variables that were explicitly passed in.
+		if current := os.Getenv(EnvHermeticAllow); len(current) > 0 {
+			allow = append([]string{current}, allow...)
+		}
+		run.Env(EnvHermeticAllow, strings.Join(allow, ","))
 	}
 	if len(flags.watchDir) > 0 {
 		return runWatching(ctx, run.Task(), flags)
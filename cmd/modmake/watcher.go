@@ -50,6 +50,10 @@ func runWatching(_base context.Context, task Task, flags *appFlags) (rerr error)
 		}
 	}
 
+	files, err := watchFileSet(flags)
+	if err != nil {
+		return err
+	}
 	gate := newProcessGate(base, flags.watchInterval, task)
 	defer func() {
 		if err := gate.Stop(); err != nil {
@@ -58,26 +62,13 @@ func runWatching(_base context.Context, task Task, flags *appFlags) (rerr error)
 	}()
 	go func() {
 		defer wg.Done()
-		var (
-			patterns = flags.watchPatterns()
-			match    = func(path string) bool {
-				return true
-			}
-		)
-		if len(patterns) > 0 {
-			match = func(name string) bool {
-				for _, pattern := range patterns {
-					pattern = strings.TrimSpace(pattern)
-					matched, err := filepath.Match(pattern, filepath.Base(name))
-					if err != nil {
-						continue
-					}
-					if matched {
-						return true
-					}
-				}
+		match := func(name string) bool {
+			rel, err := filepath.Rel(watchDir, name)
+			if err != nil {
 				return false
 			}
+			matched, err := files.Match(rel)
+			return err == nil && matched
 		}
 		if err := gate.Start(); err != nil {
 			rerr = err
@@ -117,3 +108,20 @@ func runWatching(_base context.Context, task Task, flags *appFlags) (rerr error)
 	}
 	return nil
 }
+
+// watchFileSet creates a FileSet that selects the files that will trigger a watched task.
+// Patterns are matched against the path relative to the watched directory, see [MatchGlob] for the syntax.
+func watchFileSet(flags *appFlags) (*FileSet, error) {
+	files := NewFileSet(flags.watchDirectory())
+	for _, pattern := range flags.watchPatterns() {
+		pattern = strings.TrimSpace(pattern)
+		if len(pattern) == 0 {
+			continue
+		}
+		if _, err := MatchGlob(pattern, ""); err != nil {
+			return nil, err
+		}
+		files.Include(pattern)
+	}
+	return files, nil
+}
@@ -0,0 +1,303 @@
+package modmake
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TarCompression selects the compression used for a tar archive.
+//
+// Gzip and bzip2 are implemented in Go, but zstd and xz are not.
+// Zstd and xz compression and decompression run the "zstd" and "xz" executables, which must be available on the PATH of the host running the build.
+// They're run like any other [Command], so they get the hermetic environment of a hermetic step.
+// Tasks that need a missing executable fail with [ErrCompressorNotFound] before any files are written.
+type TarCompression string
+
+const (
+	// TarNoCompression creates a plain tar archive.
+	// This must be set explicitly with [TarArchive.Compression], since a location ending in ".tar" still uses gzip.
+	TarNoCompression TarCompression = "none"
+	// TarGzip uses gzip compression.
+	// This is used when the compression isn't set and can't be inferred from the archive's file extension.
+	TarGzip TarCompression = "gzip"
+	// TarZstd uses zstd compression, which is typically both smaller and faster than gzip.
+	// This requires the "zstd" executable on the PATH.
+	TarZstd TarCompression = "zstd"
+	// TarXz uses xz compression, which is typically the smallest but slowest option.
+	// This requires the "xz" executable on the PATH.
+	TarXz TarCompression = "xz"
+	// TarBzip2 uses bzip2 compression, which is only supported for extraction.
+	TarBzip2 TarCompression = "bzip2"
+)
+
+// Ext returns the conventional file extension of a tar archive with this compression, like ".tar.gz".
+func (c TarCompression) Ext() string {
+	switch c {
+	case TarNoCompression:
+		return ".tar"
+	case TarZstd:
+		return ".tar.zst"
+	case TarXz:
+		return ".tar.xz"
+	case TarBzip2:
+		return ".tar.bz2"
+	default:
+		return ".tar.gz"
+	}
+}
+
+func (c TarCompression) validate() {
+	switch c {
+	case TarNoCompression, TarGzip, TarZstd, TarXz, TarBzip2:
+	default:
+		panic(fmt.Sprintf("unsupported tar compression '%s'", c))
+	}
+}
+
+// tarCompressionFromPath infers zstd or xz compression from a file extension, defaulting to gzip.
+func tarCompressionFromPath(location PathString) TarCompression {
+	lower := strings.ToLower(location.String())
+	switch {
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return TarZstd
+	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"):
+		return TarXz
+	default:
+		return TarGzip
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compressWriter wraps w to compress data written to it.
+// A level less than 0 selects the default level for the compression.
+// The returned writer must be closed to flush all data.
+func compressWriter(ctx context.Context, w io.Writer, compression TarCompression, level int) (io.WriteCloser, error) {
+	switch compression {
+	case TarNoCompression:
+		return nopWriteCloser{w}, nil
+	case TarGzip:
+		if level < 0 {
+			level = gzip.DefaultCompression
+		}
+		gz, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip compression level %d: %w", level, err)
+		}
+		return gz, nil
+	case TarZstd:
+		args := []string{"-q", "-c", "-T0"}
+		if level >= 0 {
+			if level < 1 || level > 19 {
+				return nil, fmt.Errorf("invalid zstd compression level %d, must be 1-19", level)
+			}
+			args = append(args, "-"+strconv.Itoa(level))
+		}
+		return startCompressor(ctx, w, "zstd", args...)
+	case TarXz:
+		args := []string{"-q", "-c", "-T0"}
+		if level >= 0 {
+			if level > 9 {
+				return nil, fmt.Errorf("invalid xz compression level %d, must be 0-9", level)
+			}
+			args = append(args, "-"+strconv.Itoa(level))
+		}
+		return startCompressor(ctx, w, "xz", args...)
+	case TarBzip2:
+		return nil, errors.New("bzip2 compression is only supported for extraction")
+	default:
+		return nil, fmt.Errorf("unsupported tar compression '%s'", compression)
+	}
+}
+
+// compressionMagicLen is the number of leading bytes needed by detectCompression.
+const compressionMagicLen = 6
+
+// detectCompression identifies the compression of data from its leading magic bytes.
+// TarNoCompression is returned if no known compression header is found.
+func detectCompression(magic []byte) TarCompression {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return TarGzip
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return TarZstd
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return TarXz
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return TarBzip2
+	default:
+		return TarNoCompression
+	}
+}
+
+// decompressReader detects the compression of r from its magic bytes, and returns a reader of the decompressed data.
+// Data without a recognized compression header is returned as-is.
+func decompressReader(ctx context.Context, r io.Reader) (io.ReadCloser, TarCompression, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(compressionMagicLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	switch compression := detectCompression(magic); compression {
+	case TarGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", err
+		}
+		return gz, compression, nil
+	case TarZstd:
+		rc, err := startDecompressor(ctx, br, "zstd", "-q", "-d", "-c")
+		return rc, compression, err
+	case TarXz:
+		rc, err := startDecompressor(ctx, br, "xz", "-q", "-d", "-c")
+		return rc, compression, err
+	case TarBzip2:
+		return io.NopCloser(bzip2.NewReader(br)), compression, nil
+	default:
+		return io.NopCloser(br), compression, nil
+	}
+}
+
+// ErrCompressorNotFound is returned when zstd or xz compression is used, but the "zstd" or "xz" executable isn't available on the PATH.
+var ErrCompressorNotFound = errors.New("compression executable not found")
+
+func lookCompressor(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s compression requires the '%s' executable on the PATH: %v", ErrCompressorNotFound, name, name, err)
+	}
+	return path, nil
+}
+
+// requireCompressor checks that any executable needed for the compression is available, so a task can fail before writing anything.
+func requireCompressor(compression TarCompression) error {
+	switch compression {
+	case TarZstd, TarXz:
+		_, err := lookCompressor(string(compression))
+		return err
+	default:
+		return nil
+	}
+}
+
+// compressorCommand creates the process for an external compressor with [Exec], so it's run with the same environment as other Commands, like in a hermetic step.
+// Stderr is written to the buffer for use in error messages.
+func compressorCommand(ctx context.Context, stdout io.Writer, stderr *bytes.Buffer, name string, args ...string) (*exec.Cmd, func(), error) {
+	path, err := lookCompressor(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := Exec(append([]string{path}, args...)...).LogGroup(name).Stdout(stdout).Stderr(stderr)
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	ctx, log := WithGroup(ctx, c.logGroup)
+	cmd, flush := c.command(ctx, log)
+	return cmd, flush, nil
+}
+
+// processWriter pipes written data through an external compressor.
+type processWriter struct {
+	cmd    *exec.Cmd
+	flush  func()
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+func startCompressor(ctx context.Context, w io.Writer, name string, args ...string) (io.WriteCloser, error) {
+	p := &processWriter{}
+	var err error
+	p.cmd, p.flush, err = compressorCommand(ctx, w, &p.stderr, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	p.stdin, err = p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	return p, nil
+}
+
+func (p *processWriter) Write(data []byte) (int, error) {
+	return p.stdin.Write(data)
+}
+
+func (p *processWriter) Close() error {
+	_ = p.stdin.Close()
+	err := p.cmd.Wait()
+	p.flush()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", p.cmd.Path, err, strings.TrimSpace(p.stderr.String()))
+	}
+	return nil
+}
+
+// processReader reads data piped through an external decompressor.
+type processReader struct {
+	cmd    *exec.Cmd
+	flush  func()
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	done   bool
+}
+
+func startDecompressor(ctx context.Context, r io.Reader, name string, args ...string) (io.ReadCloser, error) {
+	p := &processReader{}
+	var err error
+	p.cmd, p.flush, err = compressorCommand(ctx, nil, &p.stderr, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	p.cmd.Stdin = r
+	p.stdout, err = p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	return p, nil
+}
+
+func (p *processReader) Read(data []byte) (int, error) {
+	n, err := p.stdout.Read(data)
+	if errors.Is(err, io.EOF) && !p.done {
+		p.done = true
+		err := p.cmd.Wait()
+		p.flush()
+		if err != nil {
+			return n, fmt.Errorf("%s failed: %w: %s", p.cmd.Path, err, strings.TrimSpace(p.stderr.String()))
+		}
+	}
+	return n, err
+}
+
+func (p *processReader) Close() error {
+	if p.done {
+		return nil
+	}
+	p.done = true
+	// The output wasn't fully read, so the process may be blocked writing.
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+	p.flush()
+	return nil
+}
@@ -0,0 +1,139 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requireExecutable(t *testing.T, name string) {
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("'%s' is not available on the PATH", name)
+	}
+}
+
+func TestTarArchive_Compression(t *testing.T) {
+	tests := map[string]struct {
+		compression TarCompression
+		level       int
+		executable  string
+		magic       []byte
+	}{
+		"None":       {compression: TarNoCompression, level: -1},
+		"Gzip":       {compression: TarGzip, level: -1, magic: []byte{0x1f, 0x8b}},
+		"Gzip level": {compression: TarGzip, level: 9, magic: []byte{0x1f, 0x8b}},
+		"Zstd":       {compression: TarZstd, level: 19, executable: "zstd", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+		"Xz":         {compression: TarXz, level: -1, executable: "xz", magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if len(tc.executable) > 0 {
+				requireExecutable(t, tc.executable)
+			}
+			ctx := context.Background()
+			tmp := Path(t.TempDir())
+			content := bytes.Repeat([]byte("compress me "), 1000)
+			require.NoError(t, tmp.Join("input.txt").WriteFile(content, 0644))
+			archive := tmp.Join("archive" + tc.compression.Ext())
+			tarball := Tar(archive).AddFileWithPath(tmp.Join("input.txt"), "input.txt")
+			if tc.compression == TarNoCompression {
+				// A plain tar archive must be requested explicitly.
+				tarball.Compression(TarNoCompression)
+			}
+			if tc.level >= 0 {
+				tarball.CompressionLevel(tc.level)
+			}
+			require.NoError(t, tarball.Create().Run(ctx), "Compression should be inferred from the extension")
+
+			data, err := archive.ReadFile()
+			require.NoError(t, err)
+			if len(tc.magic) > 0 {
+				assert.True(t, bytes.HasPrefix(data, tc.magic), "Archive should start with the compression's magic bytes")
+			} else {
+				assert.Equal(t, "ustar", string(data[257:262]))
+			}
+
+			// Extension doesn't matter when extracting.
+			renamed := tmp.Join("archive.bin")
+			require.NoError(t, archive.CopyTo(renamed))
+			require.NoError(t, Tar(renamed).Extract(tmp.Join("output")).Run(ctx))
+			extracted, err := tmp.Join("output", "input.txt").ReadFile()
+			require.NoError(t, err)
+			assert.Equal(t, content, extracted)
+		})
+	}
+}
+
+func TestTarArchive_ExplicitCompression(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	require.NoError(t, tmp.Join("input.txt").WriteFile([]byte("data"), 0644))
+	archive := tmp.Join("archive.tar.gz")
+	require.NoError(t, Tar(archive).Compression(TarNoCompression).AddFileWithPath(tmp.Join("input.txt"), "input.txt").Create().Run(ctx))
+	data, err := archive.ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "ustar", string(data[257:262]), "Explicit compression should override the extension")
+
+	plain := tmp.Join("archive.tar")
+	require.NoError(t, Tar(plain).AddFileWithPath(tmp.Join("input.txt"), "input.txt").Create().Run(ctx))
+	data, err = plain.ReadFile()
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte{0x1f, 0x8b}), "A '.tar' location should use gzip unless set explicitly")
+
+	err = Tar(tmp.Join("archive.tar.bz2")).Compression(TarBzip2).AddFileWithPath(tmp.Join("input.txt"), "input.txt").Create().Run(ctx)
+	assert.ErrorContains(t, err, "only supported for extraction")
+	err = Tar(tmp.Join("archive.tar.gz")).CompressionLevel(10).AddFileWithPath(tmp.Join("input.txt"), "input.txt").Create().Run(ctx)
+	assert.ErrorContains(t, err, "invalid gzip compression level")
+	assert.Panics(t, func() {
+		Tar(archive).Compression("lz4")
+	})
+}
+
+func TestTarArchive_ExtractBzip2(t *testing.T) {
+	requireExecutable(t, "bzip2")
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	require.NoError(t, tmp.Join("input.txt").WriteFile([]byte("bzip2 data"), 0644))
+	archive := tmp.Join("archive.tar")
+	require.NoError(t, Tar(archive).Compression(TarNoCompression).AddFileWithPath(tmp.Join("input.txt"), "input.txt").Create().Run(ctx))
+	require.NoError(t, Exec("bzip2", archive.String()).Silent().Run(ctx))
+
+	require.NoError(t, Tar(tmp.Join("archive.tar.bz2")).Extract(tmp.Join("output")).Run(ctx))
+	data, err := tmp.Join("output", "input.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "bzip2 data", string(data))
+}
+
+func TestPackageTar_Compression(t *testing.T) {
+	requireExecutable(t, "zstd")
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	binary := tmp.Join("build", "app")
+	require.NoError(t, binary.Dir().MkdirAll(0755))
+	require.NoError(t, binary.WriteFile([]byte("binary"), 0755))
+	require.NoError(t, tmp.Join("dist").MkdirAll(0755))
+	require.NoError(t, PackageTar(TarZstd)(binary, tmp.Join("dist"), "app", "linux_amd64", "1.0.0").Run(ctx))
+	assert.True(t, tmp.Join("dist", "app_linux_amd64_1.0.0.tar.zst").IsFile())
+	assert.Panics(t, func() {
+		PackageTar(TarBzip2)
+	})
+}
+
+func TestPackageTar_MissingCompressor(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	binary := tmp.Join("build", "app")
+	require.NoError(t, binary.Dir().MkdirAll(0755))
+	require.NoError(t, binary.WriteFile([]byte("binary"), 0755))
+	require.NoError(t, tmp.Join("dist").MkdirAll(0755))
+	t.Setenv("PATH", tmp.Join("empty").String())
+
+	err := PackageTar(TarXz)(binary, tmp.Join("dist"), "app", "linux_amd64", "1.0.0").Run(ctx)
+	assert.ErrorIs(t, err, ErrCompressorNotFound)
+	assert.ErrorContains(t, err, "'xz' executable")
+	assert.False(t, tmp.Join("dist", "app_linux_amd64_1.0.0.tar.xz").Exists(), "Should fail before the archive is created")
+}
@@ -0,0 +1,408 @@
+package modmake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CopyDirOption customizes the behavior of [CopyDir] and [SyncDir].
+type CopyDirOption func(opts *copyDirOptions)
+
+type copyDirOptions struct {
+	include     []string
+	exclude     []string
+	compareHash bool
+	delete      bool
+	summary     *CopySummary
+}
+
+// CopyInclude limits copying to files matching at least one of the patterns.
+// Directories are only created in the target if they contain a copied file.
+// See [MatchGlob] for the pattern syntax.
+func CopyInclude(patterns ...string) CopyDirOption {
+	mustValidateGlobs(patterns)
+	return func(opts *copyDirOptions) {
+		opts.include = append(opts.include, patterns...)
+	}
+}
+
+// CopyExclude skips files and directories matching any of the patterns, including the contents of excluded directories.
+// See [MatchGlob] for the pattern syntax.
+func CopyExclude(patterns ...string) CopyDirOption {
+	mustValidateGlobs(patterns)
+	return func(opts *copyDirOptions) {
+		opts.exclude = append(opts.exclude, patterns...)
+	}
+}
+
+// CopyCompareHash compares the content of files with the same size to determine whether they've changed, instead of their modification times.
+// This is slower, but works when modification times aren't reliable, like after a fresh VCS checkout.
+func CopyCompareHash() CopyDirOption {
+	return func(opts *copyDirOptions) {
+		opts.compareHash = true
+	}
+}
+
+// CopyDeleteExtraneous removes files and directories in the target that weren't copied from the source.
+// This is the default for [SyncDir].
+func CopyDeleteExtraneous() CopyDirOption {
+	return func(opts *copyDirOptions) {
+		opts.delete = true
+	}
+}
+
+// CopySummaryTo records the summary of the copy in the given CopySummary when the Task completes successfully.
+func CopySummaryTo(summary *CopySummary) CopyDirOption {
+	if summary == nil {
+		panic("nil summary")
+	}
+	return func(opts *copyDirOptions) {
+		opts.summary = summary
+	}
+}
+
+// CopySummary describes the changes made by [CopyDir] or [SyncDir].
+type CopySummary struct {
+	// Copied is the number of files and symlinks copied to the target.
+	Copied int
+	// Unchanged is the number of files and symlinks skipped because they were already up-to-date in the target.
+	Unchanged int
+	// Deleted is the number of extraneous files and directories removed from the target.
+	Deleted int
+	// Bytes is the total size of copied files.
+	Bytes int64
+}
+
+// String formats the summary for logging.
+func (s CopySummary) String() string {
+	return fmt.Sprintf("copied %d file(s) (%d bytes), %d unchanged, %d deleted", s.Copied, s.Bytes, s.Unchanged, s.Deleted)
+}
+
+// CopyDir creates a Task that recursively copies the source directory to the target directory, creating it if needed.
+// File and directory modes and modification times are preserved, and symlinks are recreated rather than followed.
+// Files that are unchanged in the target, judged by size and modification time by default, are skipped.
+// Extraneous files in the target are left alone unless [CopyDeleteExtraneous] is passed.
+// The source and target must not be the same directory or within each other, and target directories are made writable while they're populated.
+func CopyDir(source, target PathString, options ...CopyDirOption) Task {
+	var opts copyDirOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "copy dir")
+		summary, err := copyDir(ctx, source, target, opts)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Copied '%s' to '%s': %s", source, target, summary)
+		if opts.summary != nil {
+			*opts.summary = summary
+		}
+		return nil
+	}
+}
+
+// SyncDir creates a Task that makes the target directory mirror the source directory.
+// It's the same as [CopyDir] with [CopyDeleteExtraneous].
+func SyncDir(source, target PathString, options ...CopyDirOption) Task {
+	return CopyDir(source, target, append([]CopyDirOption{CopyDeleteExtraneous()}, options...)...)
+}
+
+type copyDirEntry struct {
+	rel  string
+	info fs.FileInfo
+}
+
+func copyDir(ctx context.Context, source, target PathString, opts copyDirOptions) (CopySummary, error) {
+	var summary CopySummary
+	if !source.IsDir() {
+		return summary, fmt.Errorf("source '%s' is not a directory", source)
+	}
+	if nested, err := copyDirsNested(source, target); err != nil {
+		return summary, err
+	} else if nested {
+		return summary, fmt.Errorf("source '%s' and target '%s' must not be within each other", source, target)
+	}
+	var (
+		dirs  []copyDirEntry
+		files []copyDirEntry
+	)
+	err := source.Walk(func(_ PathString, rel string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, copyDirEntry{rel, info})
+			return nil
+		}
+		if len(opts.include) > 0 && !matchAnyGlob(opts.include, rel) {
+			return nil
+		}
+		if !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		files = append(files, copyDirEntry{rel, info})
+		return nil
+	}, WalkSkip(opts.exclude...))
+	if err != nil {
+		return summary, fmt.Errorf("failed to read source directory '%s': %w", source, err)
+	}
+
+	// Only directories that contain a copied file are kept when filtering with include patterns.
+	keep := map[string]bool{".": true}
+	for _, f := range files {
+		for dir := path.Dir(f.rel); dir != "."; dir = path.Dir(dir) {
+			keep[dir] = true
+		}
+	}
+	if len(opts.include) == 0 {
+		for _, d := range dirs {
+			keep[d.rel] = true
+		}
+	}
+
+	if err := target.MkdirAll(0755); err != nil {
+		return summary, fmt.Errorf("failed to create target directory '%s': %w", target, err)
+	}
+	for _, d := range dirs {
+		if !keep[d.rel] {
+			continue
+		}
+		dir := target.Join(d.rel)
+		// Write permission is needed to populate the directory, the source mode is applied after copying.
+		if fi, err := os.Lstat(dir.String()); err == nil && !fi.IsDir() {
+			if err := dir.RemoveAll(); err != nil {
+				return summary, err
+			}
+		} else if err == nil && fi.Mode().Perm()&0700 != 0700 {
+			if err := os.Chmod(dir.String(), fi.Mode().Perm()|0700); err != nil {
+				return summary, err
+			}
+		}
+		if err := dir.MkdirAll(0755); err != nil {
+			return summary, fmt.Errorf("failed to create directory '%s': %w", dir, err)
+		}
+	}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		copied, err := copyDirFile(source.Join(f.rel), target.Join(f.rel), f.info, opts.compareHash)
+		if err != nil {
+			return summary, err
+		}
+		if !copied {
+			summary.Unchanged++
+			continue
+		}
+		summary.Copied++
+		if f.info.Mode().IsRegular() {
+			summary.Bytes += f.info.Size()
+		}
+	}
+
+	if opts.delete {
+		deleted, err := deleteExtraneous(target, keep, files)
+		summary.Deleted = deleted
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	// Apply directory modes and times deepest first, since populating a directory changes its modification time.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if !keep[d.rel] {
+			continue
+		}
+		dir := target.Join(d.rel).String()
+		if err := os.Chmod(dir, d.info.Mode().Perm()); err != nil {
+			return summary, err
+		}
+		if err := os.Chtimes(dir, d.info.ModTime(), d.info.ModTime()); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// copyDirsNested returns true if either directory is the same as, or within, the other.
+// Symlinks are resolved for paths that exist, so nesting can't be hidden behind a link.
+func copyDirsNested(source, target PathString) (bool, error) {
+	resolve := func(p PathString) (string, error) {
+		abs, err := p.Abs()
+		if err != nil {
+			return "", err
+		}
+		// Resolve the deepest existing parent, since the target may not exist yet.
+		existing, rest := abs.String(), ""
+		for {
+			real, err := filepath.EvalSymlinks(existing)
+			if err == nil {
+				return filepath.Join(real, rest), nil
+			}
+			parent := filepath.Dir(existing)
+			if parent == existing {
+				return abs.String(), nil
+			}
+			existing, rest = parent, filepath.Join(filepath.Base(existing), rest)
+		}
+	}
+	src, err := resolve(source)
+	if err != nil {
+		return false, err
+	}
+	dst, err := resolve(target)
+	if err != nil {
+		return false, err
+	}
+	within := func(dir, p string) bool {
+		return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+	}
+	return within(src, dst) || within(dst, src), nil
+}
+
+// copyDirFile copies a file or symlink if it differs from the target, returning whether it was copied.
+func copyDirFile(source, target PathString, info fs.FileInfo, compareHash bool) (bool, error) {
+	existing, err := os.Lstat(target.String())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(source.String())
+		if err != nil {
+			return false, err
+		}
+		if existing != nil && existing.Mode()&fs.ModeSymlink != 0 {
+			if current, err := os.Readlink(target.String()); err == nil && current == link {
+				return false, nil
+			}
+		}
+		if existing != nil {
+			if err := target.RemoveAll(); err != nil {
+				return false, err
+			}
+		}
+		if err := os.Symlink(link, target.String()); err != nil {
+			return false, fmt.Errorf("failed to create symlink '%s': %w", target, err)
+		}
+		return true, nil
+	}
+
+	if existing != nil {
+		unchanged, err := sameFile(source, target, info, existing, compareHash)
+		if err != nil {
+			return false, err
+		}
+		if unchanged {
+			return false, nil
+		}
+		if existing.IsDir() || existing.Mode()&fs.ModeSymlink != 0 {
+			if err := target.RemoveAll(); err != nil {
+				return false, err
+			}
+		}
+	}
+	in, err := source.Open()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	if err := installFile(in, target, info.Mode().Perm(), info.ModTime()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func sameFile(source, target PathString, info, existing fs.FileInfo, compareHash bool) (bool, error) {
+	if !existing.Mode().IsRegular() || existing.Size() != info.Size() || existing.Mode().Perm() != info.Mode().Perm() {
+		return false, nil
+	}
+	if !compareHash {
+		return existing.ModTime().Equal(info.ModTime()), nil
+	}
+	sourceSum, err := source.SHA256()
+	if err != nil {
+		return false, err
+	}
+	targetSum, err := target.SHA256()
+	if err != nil {
+		return false, err
+	}
+	if sourceSum != targetSum {
+		return false, nil
+	}
+	// Keep the modification time in sync so later comparisons by time are accurate.
+	return true, os.Chtimes(target.String(), info.ModTime(), info.ModTime())
+}
+
+// deleteExtraneous removes anything in target that isn't a kept directory or copied file, returning the number of removed entries.
+func deleteExtraneous(target PathString, keep map[string]bool, files []copyDirEntry) (int, error) {
+	copied := map[string]bool{}
+	for _, f := range files {
+		copied[f.rel] = true
+	}
+	var extraneous []string
+	err := target.Walk(func(_ PathString, rel string, d fs.DirEntry) error {
+		if d.IsDir() && keep[rel] || !d.IsDir() && copied[rel] {
+			return nil
+		}
+		extraneous = append(extraneous, rel)
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read target directory '%s': %w", target, err)
+	}
+	sort.Strings(extraneous)
+	for i, rel := range extraneous {
+		if err := makeDirsWritable(target.Join(rel)); err != nil {
+			return i, fmt.Errorf("failed to remove extraneous '%s': %w", rel, err)
+		}
+		if err := target.Join(rel).RemoveAll(); err != nil {
+			return i, fmt.Errorf("failed to remove extraneous '%s': %w", rel, err)
+		}
+	}
+	return len(extraneous), nil
+}
+
+// makeDirsWritable adds owner permissions to dir and the directories within it, so their contents can be removed.
+// Nothing is done if dir isn't a directory.
+func makeDirsWritable(dir PathString) error {
+	fi, err := os.Lstat(dir.String())
+	if err != nil || !fi.IsDir() {
+		return nil
+	}
+	// Directories are visited before they're read, so unreadable directories are fixed before their contents are walked.
+	return filepath.WalkDir(dir.String(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0700 == 0700 {
+			return nil
+		}
+		return os.Chmod(p, info.Mode().Perm()|0700)
+	})
+}
@@ -0,0 +1,178 @@
+package modmake
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathString_CopyToPreservesMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File modes aren't fully supported on Windows")
+	}
+	tmp := Path(t.TempDir())
+	require.NoError(t, tmp.Join("app").WriteFile([]byte("binary"), 0755))
+	require.NoError(t, tmp.Join("app").CopyTo(tmp.Join("copy")))
+	fi, err := tmp.Join("copy").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+}
+
+func TestCopyDir(t *testing.T) {
+	ctx := context.Background()
+	src := setupGlobDir(t)
+	require.NoError(t, os.Chmod(src.Join("build", "output.bin").String(), 0755))
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(src.Join("static").String(), modTime, modTime))
+	dst := Path(t.TempDir(), "dst")
+
+	var summary CopySummary
+	require.NoError(t, CopyDir(src, dst, CopyExclude(".hidden", "**/*_test.go"), CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, CopySummary{Copied: 6, Bytes: 83}, summary)
+	assert.Equal(t, []string{
+		"README.md",
+		"build/output.bin",
+		"cmd/app/main.go",
+		"main.go",
+		"static/css/site.css",
+		"static/index.html",
+	}, walkedPaths(t, dst))
+	if runtime.GOOS != "windows" {
+		fi, err := dst.Join("build", "output.bin").Stat()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	}
+	fi, err := dst.Join("static").Stat()
+	require.NoError(t, err)
+	assert.True(t, fi.ModTime().Equal(modTime), "Directory modification time should be preserved")
+
+	// Unchanged files are skipped, and extraneous files are kept.
+	require.NoError(t, dst.Join("extra.txt").WriteFile([]byte("extra"), 0644))
+	require.NoError(t, src.Join("main.go").WriteFile([]byte("changed"), 0644))
+	require.NoError(t, CopyDir(src, dst, CopyExclude(".hidden", "**/*_test.go"), CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, CopySummary{Copied: 1, Unchanged: 5, Bytes: 7}, summary)
+	data, err := dst.Join("main.go").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(data))
+	assert.True(t, dst.Join("extra.txt").IsFile())
+
+	err = CopyDir(src.Join("main.go"), dst).Run(ctx)
+	assert.ErrorContains(t, err, "is not a directory")
+}
+
+func TestCopyDir_Nested(t *testing.T) {
+	ctx := context.Background()
+	out := setupGlobDir(t)
+	src := out.Join("cmd")
+	tests := map[string]struct {
+		source, target PathString
+	}{
+		"Target within source": {out, out.Join("nested")},
+		"Source within target": {src, out},
+		"Same directory":       {out, out.Join("cmd", "..")},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := SyncDir(tc.source, tc.target).Run(ctx)
+			assert.ErrorContains(t, err, "must not be within each other")
+			assert.True(t, src.Join("app", "main.go").IsFile(), "Source files should be left alone")
+			assert.False(t, out.Join("nested").Exists())
+		})
+	}
+	if runtime.GOOS != "windows" {
+		link := Path(t.TempDir(), "link")
+		require.NoError(t, os.Symlink(out.String(), link.String()))
+		err := SyncDir(src, link).Run(ctx)
+		assert.ErrorContains(t, err, "must not be within each other", "Nesting through a symlink should be detected")
+		assert.True(t, src.Join("app", "main.go").IsFile())
+	}
+}
+
+func TestCopyDir_ReadOnlyDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Directory modes aren't fully supported on Windows")
+	}
+	ctx := context.Background()
+	src := setupGlobDir(t)
+	dst := Path(t.TempDir(), "dst")
+	t.Cleanup(func() {
+		_ = makeDirsWritable(src)
+		_ = makeDirsWritable(dst.Dir())
+	})
+	require.NoError(t, os.Chmod(src.Join("static", "css").String(), 0555))
+	require.NoError(t, os.Chmod(src.Join("static").String(), 0555))
+	require.NoError(t, SyncDir(src, dst).Run(ctx))
+	fi, err := dst.Join("static").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0555), fi.Mode().Perm())
+
+	// A second run must be able to update and remove files in read-only directories.
+	require.NoError(t, makeDirsWritable(src.Join("static")))
+	require.NoError(t, src.Join("static", "index.html").WriteFile([]byte("changed"), 0644))
+	require.NoError(t, src.Join("static", "css").RemoveAll())
+	require.NoError(t, os.Chmod(src.Join("static").String(), 0555))
+	var summary CopySummary
+	require.NoError(t, SyncDir(src, dst, CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, 1, summary.Copied)
+	assert.Equal(t, 1, summary.Deleted)
+	data, err := dst.Join("static", "index.html").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(data))
+	assert.False(t, dst.Join("static", "css").Exists())
+	fi, err = dst.Join("static").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0555), fi.Mode().Perm(), "Directory mode should be restored")
+}
+
+func TestSyncDir(t *testing.T) {
+	ctx := context.Background()
+	src := setupGlobDir(t)
+	dst := Path(t.TempDir(), "dst")
+	require.NoError(t, dst.Join("stale", "nested").MkdirAll(0755))
+	require.NoError(t, dst.Join("stale", "nested", "old.txt").WriteFile([]byte("old"), 0644))
+	require.NoError(t, dst.Join("cmd").MkdirAll(0755))
+	require.NoError(t, dst.Join("cmd", "old.go").WriteFile([]byte("old"), 0644))
+
+	var summary CopySummary
+	require.NoError(t, SyncDir(src, dst, CopyInclude("**/*.go"), CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, 3, summary.Copied)
+	assert.Equal(t, 2, summary.Deleted)
+	assert.Equal(t, []string{
+		"cmd/app/main.go",
+		"cmd/app/main_test.go",
+		"main.go",
+	}, walkedPaths(t, dst))
+	assert.False(t, dst.Join("static").Exists(), "Directories without included files shouldn't be created")
+
+	// Content is compared when hashing, even if the modification time differs.
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(src.Join("main.go").String(), future, future))
+	require.NoError(t, SyncDir(src, dst, CopyInclude("**/*.go"), CopyCompareHash(), CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, CopySummary{Unchanged: 3}, summary)
+	fi, err := dst.Join("main.go").Stat()
+	require.NoError(t, err)
+	assert.True(t, fi.ModTime().Equal(future), "Modification time should be synced")
+}
+
+func TestCopyDir_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks require elevated permissions on Windows")
+	}
+	ctx := context.Background()
+	src := setupGlobDir(t)
+	require.NoError(t, os.Symlink("main.go", src.Join("link.go").String()))
+	dst := Path(t.TempDir(), "dst")
+	require.NoError(t, CopyDir(src, dst).Run(ctx))
+	link, err := os.Readlink(dst.Join("link.go").String())
+	require.NoError(t, err)
+	assert.Equal(t, "main.go", link)
+
+	var summary CopySummary
+	require.NoError(t, CopyDir(src, dst, CopySummaryTo(&summary)).Run(ctx))
+	assert.Equal(t, 0, summary.Copied)
+}
@@ -3,9 +3,15 @@ package modmake
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // EnvMap is a specialized map for holding environment variables that are used to interpolate strings.
@@ -39,8 +45,11 @@ func Environment() EnvMap {
 	return m
 }
 
+// ErrMissingVariable is returned when a required variable in an interpolated string is missing or empty.
+var ErrMissingVariable = errors.New("missing required variable")
+
 // F will format a string, replacing variables with their value as found in the environment data.
-// Additional values may be added as the second parameter, which will override values in the original environment.
+// Additional EnvMap layers may be passed after the string, and are merged over the environment in order, so later layers take precedence.
 //
 // Variable placeholders may be specified like ${ENV_VAR_NAME}. The example below will replace ${BUILD_NUM} with a value from the environment, or an empty string.
 // If a variable either doesn't exist in the environment, or has an empty value, then an empty string will replace the variable placeholder.
@@ -55,8 +64,30 @@ func Environment() EnvMap {
 //
 //	str := F("My string that references build ${BUILD_NUM:0}")
 //
+// Default values may reference other variables, so the example below uses BUILD_NUM if it's defined, then CI_BUILD_NUM, then "0".
+//
+//	str := F("My string that references build ${BUILD_NUM:${CI_BUILD_NUM:0}}")
+//
+// A variable can be required with a question mark ("?") separator after the key, optionally followed by a message.
+// Required variables that are missing or empty cause F to panic with [ErrMissingVariable], use [FErr] to handle the error instead.
+// The "?" is only a separator if the key before it is made of letters, digits, "_", ".", and "-", and there isn't a variable with the "?" in its name, otherwise it's part of the key.
+//
+//	str := F("${DEPLOY_TARGET?the deployment target must be set}/app")
+//
+// Modifiers can transform a value, and are separated from the key and each other with a pipe ("|").
+// They're applied in order after any default value, and arguments to modifiers may reference other variables.
+// A pipe is only treated as a separator if it's followed by known modifiers, otherwise it's part of the key, default value, or message, so "${CMD:a|b}" uses the default value "a|b".
+//   - upper and lower change the case of the value.
+//   - trim removes leading and trailing whitespace.
+//   - base and dir return the last element or all but the last element of a path, like [filepath.Base] and [filepath.Dir].
+//   - join:ELEM joins ELEM to the value as a path, like [filepath.Join].
+//
+// The example below might produce "build/APP".
+//
+//	str := F("${BUILD_DIR:build|join:${APP_NAME}|upper}")
+//
 // Note that whitespace characters in default values will always be trimmed.
-// The string "${" can still be expressed in F strings, but it must be formatted to use a default value, which means that interpolation cannot be recursive.
+// The string "${" can still be expressed in F strings, but it must be formatted to use a default value.
 // The string output from the function call below will be "My string has a variable reference ${BUILD_NUM}".
 //
 //	str := F("My string has a variable reference ${:$}{BUILD_NUM}")
@@ -64,8 +95,23 @@ func F(fmt string, data ...EnvMap) string {
 	return string(FReader(strings.NewReader(fmt), data...))
 }
 
+// FErr does the same thing as F, but returns an error instead of panicking if a variable reference is invalid or a required variable is missing.
+func FErr(fmt string, data ...EnvMap) (string, error) {
+	out, err := FReaderErr(strings.NewReader(fmt), data...)
+	return string(out), err
+}
+
 // FReader will do the same thing as F, but operates on an io.Reader expressing a stream of UTF-8 encoded bytes instead.
 func FReader(in io.Reader, data ...EnvMap) []byte {
+	out, err := FReaderErr(in, data...)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// FReaderErr does the same thing as FReader, but returns an error instead of panicking.
+func FReaderErr(in io.Reader, data ...EnvMap) ([]byte, error) {
 	var rr io.RuneReader
 	if _rr, ok := in.(io.RuneReader); ok {
 		rr = _rr
@@ -73,13 +119,13 @@ func FReader(in io.Reader, data ...EnvMap) []byte {
 		rr = bufio.NewReader(in)
 	}
 	m := Environment()
-	if len(data) > 0 {
-		m.merge(data[0])
+	for _, layer := range data {
+		m.merge(layer)
 	}
 	return parseString(rr, m)
 }
 
-func parseString(in io.RuneReader, data EnvMap) []byte {
+func parseString(in io.RuneReader, data EnvMap) ([]byte, error) {
 	const (
 		DOLLAR rune = '$'
 		BRACE  rune = '{'
@@ -90,17 +136,25 @@ func parseString(in io.RuneReader, data EnvMap) []byte {
 	for {
 		r, _, err := in.ReadRune()
 		if err != nil {
-			return outBuf.Bytes()
+			return outBuf.Bytes(), nil
 		}
 		switch r {
 		case DOLLAR:
 			maybeBrace, _, err := in.ReadRune()
 			if err != nil {
 				outBuf.WriteRune(r)
-				return outBuf.Bytes()
+				return outBuf.Bytes(), nil
 			}
 			if maybeBrace == BRACE {
-				outBuf.Write(replaceIdentifier(in, data))
+				expr, ok := readExpression(in)
+				if !ok {
+					return outBuf.Bytes(), nil
+				}
+				val, err := evalExpression(expr, data)
+				if err != nil {
+					return nil, err
+				}
+				outBuf.WriteString(val)
 			} else {
 				outBuf.WriteRune(r)
 				outBuf.WriteRune(maybeBrace)
@@ -111,30 +165,270 @@ func parseString(in io.RuneReader, data EnvMap) []byte {
 	}
 }
 
-func replaceIdentifier(in io.RuneReader, data EnvMap) []byte {
-	const (
-		END_BRACE = '}'
-	)
+// readExpression reads up to the "}" that closes a variable reference, including any nested references.
+// False is returned if the reference isn't closed.
+func readExpression(in io.RuneReader) (string, bool) {
 	var (
-		varBuf       strings.Builder
-		defaultValue string
+		exprBuf strings.Builder
+		depth   int
+		pending rune
 	)
 	for {
-		r, _, err := in.ReadRune()
-		if err != nil {
-			return nil
+		r := pending
+		pending = 0
+		if r == 0 {
+			var err error
+			if r, _, err = in.ReadRune(); err != nil {
+				return "", false
+			}
+		}
+		switch r {
+		case '$':
+			next, _, err := in.ReadRune()
+			if err != nil {
+				return "", false
+			}
+			exprBuf.WriteRune(r)
+			if next == '{' {
+				depth++
+				exprBuf.WriteRune(next)
+				continue
+			}
+			pending = next
+		case '}':
+			if depth == 0 {
+				return exprBuf.String(), true
+			}
+			depth--
+			exprBuf.WriteRune(r)
+		default:
+			exprBuf.WriteRune(r)
+		}
+	}
+}
+
+// indexOutsideRefs returns the index of the first of the chars in s that isn't within a nested variable reference, or -1.
+func indexOutsideRefs(s string, chars string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(chars, s[i]) >= 0:
+			return i
+		}
+	}
+	return -1
+}
+
+var envModifiers = map[string]bool{
+	"upper": true,
+	"lower": true,
+	"trim":  true,
+	"base":  true,
+	"dir":   true,
+	"join":  true,
+}
+
+// splitModifiers splits the modifiers from the end of an expression.
+// A pipe only starts the modifiers if every pipe separated part after it starts with a known modifier name, so pipes may still be used in keys and default values.
+func splitModifiers(expr string) (string, []string) {
+	for start := 0; ; {
+		i := indexOutsideRefs(expr[start:], "|")
+		if i < 0 {
+			return expr, nil
+		}
+		i += start
+		var (
+			modifiers []string
+			rest      = expr[i+1:]
+		)
+		for {
+			j := indexOutsideRefs(rest, "|")
+			if j < 0 {
+				modifiers = append(modifiers, rest)
+				break
+			}
+			modifiers = append(modifiers, rest[:j])
+			rest = rest[j+1:]
+		}
+		if knownModifiers(modifiers) {
+			return expr[:i], modifiers
+		}
+		start = i + 1
+	}
+}
+
+// knownModifiers returns true if each modifier starts with a known modifier name.
+func knownModifiers(modifiers []string) bool {
+	for _, modifier := range modifiers {
+		name := modifier
+		if i := indexOutsideRefs(name, ":"); i >= 0 {
+			name = name[:i]
+		}
+		if !envModifiers[strings.TrimSpace(name)] {
+			return false
+		}
+	}
+	return true
+}
+
+// validVariableName returns true if the space padded name may be used as the key of a required variable.
+func validVariableName(name string) bool {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.-", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func evalExpression(expr string, data EnvMap) (string, error) {
+	expr, modifiers := splitModifiers(expr)
+
+	key, op, arg := expr, byte(0), ""
+	i := indexOutsideRefs(expr, ":?")
+	if i >= 0 && expr[i] == '?' {
+		keyEnd := indexOutsideRefs(expr, ":")
+		rawKey := expr
+		if keyEnd >= 0 {
+			rawKey = expr[:keyEnd]
+		}
+		if _, exists := data[strings.ToUpper(strings.TrimSpace(rawKey))]; exists || !validVariableName(expr[:i]) {
+			i = keyEnd
+		}
+	}
+	if i >= 0 {
+		key, op, arg = expr[:i], expr[i], strings.TrimSpace(expr[i+1:])
+	}
+	key = strings.ToUpper(strings.TrimSpace(key))
+	val, ok := data[key]
+	switch op {
+	case ':':
+		if !ok {
+			var err error
+			if val, err = interpolate(arg, data); err != nil {
+				return "", err
+			}
+		}
+	case '?':
+		if len(val) == 0 {
+			if len(arg) > 0 {
+				return "", fmt.Errorf("%w '%s': %s", ErrMissingVariable, key, arg)
+			}
+			return "", fmt.Errorf("%w '%s'", ErrMissingVariable, key)
+		}
+	}
+
+	for _, modifier := range modifiers {
+		name, modArg := strings.TrimSpace(modifier), ""
+		if i := indexOutsideRefs(name, ":"); i >= 0 {
+			name, modArg = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+		}
+		switch name {
+		case "upper":
+			val = strings.ToUpper(val)
+		case "lower":
+			val = strings.ToLower(val)
+		case "trim":
+			val = strings.TrimSpace(val)
+		case "base":
+			val = filepath.Base(val)
+		case "dir":
+			val = filepath.Dir(val)
+		case "join":
+			elem, err := interpolate(modArg, data)
+			if err != nil {
+				return "", err
+			}
+			val = filepath.Join(val, elem)
 		}
-		if r == END_BRACE {
-			baseKey := strings.TrimSpace(varBuf.String())
-			if before, after, found := strings.Cut(baseKey, ":"); found {
-				baseKey, defaultValue = strings.TrimSpace(before), strings.TrimSpace(after)
+	}
+	return val, nil
+}
+
+func interpolate(s string, data EnvMap) (string, error) {
+	out, err := parseString(strings.NewReader(s), data)
+	return string(out), err
+}
+
+// ReadEnvFile reads variables from a .env file, with one KEY=VALUE pair per line.
+// Blank lines and lines starting with "#" are ignored, and a leading "export " is allowed for compatibility with shell scripts.
+// Values may be wrapped in double quotes to use escape sequences like "\n", or single quotes to be used literally.
+// Unquoted values are trimmed, and anything after a " #" is treated as a comment.
+func ReadEnvFile(file PathString) (EnvMap, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	m := EnvMap{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, val, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 {
+			return nil, fmt.Errorf("invalid line %d in '%s', expected KEY=VALUE", lineNum, file)
+		}
+		val = strings.TrimSpace(val)
+		switch {
+		case len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"':
+			unquoted, err := strconv.Unquote(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value on line %d in '%s': %w", lineNum, file, err)
 			}
-			baseKey = strings.ToUpper(baseKey)
-			if val, ok := data[baseKey]; ok {
-				return []byte(val)
+			val = unquoted
+		case len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'':
+			val = val[1 : len(val)-1]
+		default:
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+		}
+		m[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", file, err)
+	}
+	return m, nil
+}
+
+// ReadEnvFiles reads and merges each of the .env files that exist, in order, so values in later files take precedence.
+// This is useful for layering local overrides over shared defaults, like ReadEnvFiles(".env", ".env.local").
+// Missing files are skipped, but any other error reading a file is returned.
+//
+// The returned EnvMap can be passed to [F] as a layer over the environment.
+// To let the environment take precedence over the files instead, pass [Environment] as a later layer.
+//
+//	dotenv, err := ReadEnvFiles(".env", ".env.local")
+//	str := F("${DEPLOY_TARGET?}", dotenv, Environment())
+func ReadEnvFiles(files ...PathString) (EnvMap, error) {
+	m := EnvMap{}
+	for _, file := range files {
+		layer, err := ReadEnvFile(file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
 			}
-			return []byte(defaultValue)
+			return nil, err
 		}
-		varBuf.WriteRune(r)
+		m.merge(layer)
 	}
+	return m, nil
 }
@@ -3,6 +3,7 @@ package modmake
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -76,6 +77,56 @@ func TestF(t *testing.T) {
 			out:  "A string with a name of John",
 			data: nil,
 		},
+		"Default values can reference other variables": {
+			in:  "Build ${BUILD_NUM:${CI_BUILD_NUM:0}}",
+			out: "Build 42",
+			data: EnvMap{
+				"CI_BUILD_NUM": "42",
+			},
+		},
+		"Nested default values fall through": {
+			in:   "Build ${BUILD_NUM:${CI_BUILD_NUM:0}}",
+			out:  "Build 0",
+			data: nil,
+		},
+		"Required variables with a value": {
+			in:  "${DEPLOY_TARGET?}/app",
+			out: "/srv/app",
+			data: EnvMap{
+				"DEPLOY_TARGET": "/srv",
+			},
+		},
+		"Modifiers": {
+			in:  "${ name | upper } ${NAME|lower} [${PADDED|trim}]",
+			out: "BOB bob [padded]",
+			data: EnvMap{
+				"NAME":   "Bob",
+				"PADDED": "  padded\t",
+			},
+		},
+		"Path modifiers": {
+			in:  "${BINARY|base} ${BINARY|dir} ${BUILD_DIR:build|join:${APP}|join:bin}",
+			out: "app " + filepath.Join("build", "app") + " " + filepath.Join("build", "app", "bin"),
+			data: EnvMap{
+				"BINARY": filepath.Join("build", "app", "app"),
+				"APP":    "app",
+			},
+		},
+		"Modifiers apply to defaults": {
+			in:   "${GOOS:Linux|lower}",
+			out:  "linux",
+			data: EnvMap{"GOOS": "LINUX"},
+		},
+		"Pipes in default values": {
+			in:   "${MODMAKE_PIPE_CMD:a|b} ${MODMAKE_PIPE_CMD:a|b|upper} ${MODMAKE_SHELL_CMD:${MODMAKE_PIPE_CMD:ls | sort}}",
+			out:  "a|b A|B ls | sort",
+			data: nil,
+		},
+		"Escaped reference": {
+			in:   "A reference ${:$}{NAME}",
+			out:  "A reference ${NAME}",
+			data: nil,
+		},
 	}
 
 	for name, tc := range tests {
@@ -89,6 +140,77 @@ func TestF(t *testing.T) {
 	}
 }
 
+func TestFErr(t *testing.T) {
+	_, err := FErr("${SOME_KEY_THAT_SHOULD_NOT_BE_A_THING?}/app")
+	assert.ErrorIs(t, err, ErrMissingVariable)
+	assert.ErrorContains(t, err, "SOME_KEY_THAT_SHOULD_NOT_BE_A_THING")
+
+	_, err = FErr("${DEPLOY_TARGET ? the deployment target must be set}/app", EnvMap{"DEPLOY_TARGET": ""})
+	assert.ErrorIs(t, err, ErrMissingVariable, "Empty values don't satisfy required variables")
+	assert.ErrorContains(t, err, "the deployment target must be set")
+
+	_, err = FErr("${BUILD:${SOME_KEY_THAT_SHOULD_NOT_BE_A_THING?}}")
+	assert.ErrorIs(t, err, ErrMissingVariable, "Required variables in defaults should be checked")
+
+	_, err = FErr("${TARGET?must be set|see docs}", EnvMap{"TARGET": ""})
+	assert.ErrorContains(t, err, "must be set|see docs", "Pipes in messages aren't modifiers")
+
+	assert.Panics(t, func() {
+		F("${SOME_KEY_THAT_SHOULD_NOT_BE_A_THING?}")
+	})
+}
+
+func TestF_UnknownForms(t *testing.T) {
+	assert.NotPanics(t, func() {
+		assert.Equal(t, "[]", F("[${NAME|reverse}]", EnvMap{"NAME": "Bob"}), "Unknown modifiers are part of the key")
+		assert.Equal(t, "[]", F("[${WHAT IS THIS?}]"), "A '?' after an invalid name is part of the key")
+		assert.Equal(t, "[x]", F("[${WHAT IS THIS?:x}]"))
+	})
+	assert.Equal(t, "found", F("${NAME|REVERSE}", EnvMap{"NAME|REVERSE": "found"}))
+	assert.Equal(t, "found", F("${a url?}", EnvMap{"A URL?": "found"}))
+	assert.Equal(t, "found", F("${A?B}", EnvMap{"A?B": "found"}), "Existing keys with a '?' should still be found")
+}
+
+func TestF_Layers(t *testing.T) {
+	t.Setenv("MODMAKE_LAYER_TEST", "environment")
+	assert.Equal(t, "environment", F("${MODMAKE_LAYER_TEST}"))
+	assert.Equal(t, "second", F("${MODMAKE_LAYER_TEST}", EnvMap{"MODMAKE_LAYER_TEST": "first"}, EnvMap{"modmake_layer_test": "second"}))
+	assert.Equal(t, "environment", F("${MODMAKE_LAYER_TEST}", EnvMap{"MODMAKE_LAYER_TEST": "first"}, Environment()))
+}
+
+func TestReadEnvFiles(t *testing.T) {
+	tmp := Path(t.TempDir())
+	require.NoError(t, tmp.Join(".env").WriteFile([]byte(`# Shared defaults
+APP_NAME=app
+export DEPLOY_TARGET = /srv/app # Production
+GREETING="Hello\nWorld"
+LITERAL='${NOT_INTERPOLATED}'
+
+EMPTY=
+`), 0644))
+	require.NoError(t, tmp.Join(".env.local").WriteFile([]byte("DEPLOY_TARGET=/tmp/app\n"), 0644))
+
+	env, err := ReadEnvFile(tmp.Join(".env"))
+	require.NoError(t, err)
+	assert.Equal(t, EnvMap{
+		"APP_NAME":      "app",
+		"DEPLOY_TARGET": "/srv/app",
+		"GREETING":      "Hello\nWorld",
+		"LITERAL":       "${NOT_INTERPOLATED}",
+		"EMPTY":         "",
+	}, env)
+
+	env, err = ReadEnvFiles(tmp.Join(".env"), tmp.Join(".env.local"), tmp.Join(".env.missing"))
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/app", env["DEPLOY_TARGET"], "Later files should take precedence")
+	assert.Equal(t, "app", env["APP_NAME"])
+	assert.Equal(t, "/tmp/app/app", F("${DEPLOY_TARGET?}/${APP_NAME}", env))
+
+	require.NoError(t, tmp.Join(".env.bad").WriteFile([]byte("NOT A PAIR\n"), 0644))
+	_, err = ReadEnvFiles(tmp.Join(".env.bad"))
+	assert.ErrorContains(t, err, "invalid line 1")
+}
+
 func TestF_DynamicVariables(t *testing.T) {
 	const (
 		nonExistentKey = "SOME_KEY_THAT_SHOULD_NOT_BE_A_THING"
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 type Command struct {
@@ -18,16 +19,18 @@ type Command struct {
 	args         []string
 	trailingArgs []string
 	env          []string
+	hermetic     bool
+	allowEnv     []string
 	stdout       io.Writer
 	stderr       io.Writer
 	stdin        io.Reader
+	redact       bool
 	logGroup     string
 }
 
 // Exec creates a new Command representing running an external application.
 func Exec(cmdAndInitArgs ...string) *Command {
 	i := &Command{
-		env:    os.Environ(),
 		stdout: os.Stdout,
 		stderr: os.Stderr,
 		stdin:  nil,
@@ -101,6 +104,40 @@ func (i *Command) TrailingArg(args ...string) *Command {
 	return i
 }
 
+// Hermetic makes the Command start from a minimal environment, rather than inheriting the whole environment of the build.
+// Only the variables in [HermeticEnvAllowlist] and those allowed with AllowEnv are passed through, and variables set with Env are added explicitly.
+// This makes builds behave the same regardless of developer-specific settings like GOFLAGS or CGO_ENABLED.
+// The effective environment is logged in debug mode.
+//
+// See [WithHermeticEnv] to make all Commands run with a context hermetic.
+func (i *Command) Hermetic() *Command {
+	i.hermetic = true
+	return i
+}
+
+// AllowEnv allows the named environment variables to pass through to the Command when it's hermetic.
+func (i *Command) AllowEnv(keys ...string) *Command {
+	i.allowEnv = append(i.allowEnv, keys...)
+	return i
+}
+
+// environ returns the effective environment of the Command, and whether it's hermetic.
+// The environment is read when the Command runs, so changes made after the Command is created are reflected.
+func (i *Command) environ(ctx context.Context) ([]string, bool) {
+	var (
+		hermetic = i.hermetic
+		allow    = i.allowEnv
+	)
+	if h, ok := ctx.Value(hermeticEnvKey).(hermeticEnv); ok {
+		hermetic = true
+		allow = append(append([]string{}, h.allow...), allow...)
+	}
+	if !hermetic {
+		return append(os.Environ(), i.env...), false
+	}
+	return append(hermeticEnviron(allow), i.env...), true
+}
+
 // Env sets an environment variable for the running Command.
 func (i *Command) Env(key, value string) *Command {
 	if i.err != nil {
@@ -114,6 +151,13 @@ func (i *Command) Env(key, value string) *Command {
 	return i
 }
 
+// SecretEnv sets an environment variable to the value of a [Secret].
+// The value is redacted from logs and the Command's output to os.Stdout and os.Stderr, see [Command.RedactOutput] to redact captured output.
+func (i *Command) SecretEnv(key string, secret Secret) *Command {
+	RegisterSecret(secret.Value())
+	return i.Env(key, secret.Value())
+}
+
 // WorkDir sets the working directory in which to execute the Command.
 func (i *Command) WorkDir(workdir PathString) *Command {
 	if i.err != nil {
@@ -133,13 +177,8 @@ func (i *Command) Silent() *Command {
 	if i.err != nil {
 		return i
 	}
-	null, err := os.Open(os.DevNull)
-	if err != nil {
-		i.err = err
-		return i
-	}
-	i.stderr = null
-	i.stdout = null
+	i.stderr = io.Discard
+	i.stdout = io.Discard
 	return i
 }
 
@@ -175,6 +214,17 @@ func (i *Command) Output(w io.Writer) *Command {
 	return i.Stdout(w).Stderr(w)
 }
 
+// RedactOutput will redact registered secrets from output captured with [Command.Stdout], [Command.Stderr], or [Command.Output].
+// Output written to os.Stdout and os.Stderr is always redacted.
+// Redacted output is buffered by line, so this shouldn't be used to capture binary data.
+func (i *Command) RedactOutput() *Command {
+	if i.err != nil {
+		return i
+	}
+	i.redact = true
+	return i
+}
+
 func (i *Command) Run(ctx context.Context) error {
 	var log Logger
 	if len(i.logGroup) == 0 {
@@ -188,14 +238,8 @@ func (i *Command) Run(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	cmd := exec.CommandContext(ctx, i.cmd, append(append(i.initialArgs, i.args...), i.trailingArgs...)...) //nolint:gosec // This is intended to allow arbitrary inputs.
-	cmd.Env = i.env
-	cmd.Stdout = i.stdout
-	cmd.Stderr = i.stderr
-	cmd.Stdin = i.stdin
-	cmd.Dir = i.workdir
-	customizeCmd(cmd)
-	cmd.Cancel = cancelIncludeChildren(cmd)
+	cmd, flush := i.command(ctx, log)
+	defer flush()
 	if err := cmd.Run(); err != nil {
 		log.Error(err.Error())
 		return log.WrapErr(err)
@@ -203,6 +247,35 @@ func (i *Command) Run(ctx context.Context) error {
 	return nil
 }
 
+// command creates the process for this Command, with the environment, working directory, and output configured.
+// The returned function flushes redacted output, and must be called after the process completes.
+func (i *Command) command(ctx context.Context, log Logger) (*exec.Cmd, func()) {
+	args := append(append(i.initialArgs, i.args...), i.trailingArgs...)
+	cmd := exec.CommandContext(ctx, i.cmd, args...) //nolint:gosec // This is intended to allow arbitrary inputs.
+	log.Debug("Running '%s'", strings.Join(append([]string{i.cmd}, args...), " "))
+	env, hermetic := i.environ(ctx)
+	if hermetic {
+		log.Debug("Running '%s' with hermetic environment: %s", i.cmd, strings.Join(env, " "))
+	}
+	cmd.Env = env
+	stdout, flushStdout := redactOutput(i.stdout, i.redact)
+	// A shared writer is wrapped once, so writes aren't interleaved within a line.
+	stderr, flushStderr := stdout, func() {}
+	if !sameWriter(i.stdout, i.stderr) {
+		stderr, flushStderr = redactOutput(i.stderr, i.redact)
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = i.stdin
+	cmd.Dir = i.workdir
+	customizeCmd(cmd)
+	cmd.Cancel = cancelIncludeChildren(cmd)
+	return cmd, func() {
+		flushStderr()
+		flushStdout()
+	}
+}
+
 func (i *Command) Task() Task {
 	return i.Run
 }
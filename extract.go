@@ -0,0 +1,406 @@
+package modmake
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultExtractMaxSize is the default limit of total bytes written when extracting an archive.
+	DefaultExtractMaxSize int64 = 4 << 30
+	// DefaultExtractMaxEntries is the default limit of entries extracted from an archive.
+	DefaultExtractMaxEntries = 100_000
+)
+
+var (
+	// ErrUnsafeArchiveEntry is returned when an archive entry would be written outside the extraction directory.
+	ErrUnsafeArchiveEntry = errors.New("unsafe archive entry")
+	// ErrArchiveLimit is returned when extracting an archive exceeds the configured size or entry limits.
+	ErrArchiveLimit = errors.New("archive extraction limit exceeded")
+)
+
+// ExtractOption customizes the behavior of [TarArchive.Extract] and [ZipArchive.Extract].
+type ExtractOption func(opts *extractOptions)
+
+type extractOptions struct {
+	maxSize     int64
+	maxEntries  int
+	allowUnsafe bool
+}
+
+// ExtractMaxSize limits the total number of bytes written while extracting, which defaults to [DefaultExtractMaxSize].
+// A limit of 0 disables the check.
+func ExtractMaxSize(maxBytes int64) ExtractOption {
+	if maxBytes < 0 {
+		panic("negative max size")
+	}
+	return func(opts *extractOptions) {
+		opts.maxSize = maxBytes
+	}
+}
+
+// ExtractMaxEntries limits the number of entries extracted, which defaults to [DefaultExtractMaxEntries].
+// A limit of 0 disables the check.
+func ExtractMaxEntries(maxEntries int) ExtractOption {
+	if maxEntries < 0 {
+		panic("negative max entries")
+	}
+	return func(opts *extractOptions) {
+		opts.maxEntries = maxEntries
+	}
+}
+
+// ExtractAllowUnsafe disables checks that prevent entries, symlinks, and hard links from referencing locations outside the extraction directory.
+// Size and entry limits still apply.
+// Only use this with archives from a trusted source.
+func ExtractAllowUnsafe() ExtractOption {
+	return func(opts *extractOptions) {
+		opts.allowUnsafe = true
+	}
+}
+
+type extractedDir struct {
+	path    PathString
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// extractor writes archive entries to a directory, enforcing safety checks and limits.
+type extractor struct {
+	opts    extractOptions
+	root    PathString
+	written int64
+	entries int
+	dirs    []extractedDir
+	links   []PathString
+}
+
+func newExtractor(extractDir PathString, options []ExtractOption) (*extractor, error) {
+	e := &extractor{
+		opts: extractOptions{
+			maxSize:    DefaultExtractMaxSize,
+			maxEntries: DefaultExtractMaxEntries,
+		},
+	}
+	for _, opt := range options {
+		opt(&e.opts)
+	}
+	if err := extractDir.MkdirAll(0700); err != nil {
+		return nil, fmt.Errorf("unable to create extraction directory: %w", err)
+	}
+	root, err := filepath.Abs(extractDir.String())
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	e.root = Path(root)
+	return e, nil
+}
+
+// entry counts an extracted entry, and enforces the entry limit.
+func (e *extractor) entry(name string) error {
+	e.entries++
+	if e.opts.maxEntries > 0 && e.entries > e.opts.maxEntries {
+		return fmt.Errorf("%w: more than %d entries at '%s'", ErrArchiveLimit, e.opts.maxEntries, name)
+	}
+	return nil
+}
+
+// limit wraps r so data read from it counts toward the size limit.
+func (e *extractor) limit(r io.Reader, name string) io.Reader {
+	return &extractLimitReader{e: e, r: r, name: name}
+}
+
+type extractLimitReader struct {
+	e    *extractor
+	r    io.Reader
+	name string
+}
+
+func (l *extractLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.e.written += int64(n)
+	if l.e.opts.maxSize > 0 && l.e.written > l.e.opts.maxSize {
+		return n, fmt.Errorf("%w: more than %d bytes at '%s'", ErrArchiveLimit, l.e.opts.maxSize, l.name)
+	}
+	return n, err
+}
+
+// within reports whether p is the root directory or one of its descendants.
+func (e *extractor) within(p string) bool {
+	rel, err := filepath.Rel(e.root.String(), p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// target resolves an archive entry name to a location in the extraction directory.
+// Entries that would escape the extraction directory, either by name or through a previously extracted symlink, are rejected.
+func (e *extractor) target(name string) (PathString, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if e.opts.allowUnsafe {
+		return e.root.Join(filepath.FromSlash(slashed)), nil
+	}
+	if path.IsAbs(slashed) || filepath.IsAbs(name) || len(filepath.VolumeName(name)) > 0 {
+		return "", fmt.Errorf("%w: '%s' is an absolute path", ErrUnsafeArchiveEntry, name)
+	}
+	clean := path.Clean(slashed)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: '%s' is outside the extraction directory", ErrUnsafeArchiveEntry, name)
+	}
+	if clean == "." {
+		return e.root, nil
+	}
+	target := e.root.Join(filepath.FromSlash(clean))
+	// Find the deepest existing parent, and make sure it doesn't resolve outside the root through a symlink.
+	parent := target.Dir()
+	for parent != e.root && parent != parent.Dir() {
+		if _, err := os.Lstat(parent.String()); err == nil {
+			break
+		}
+		parent = parent.Dir()
+	}
+	resolved, err := filepath.EvalSymlinks(parent.String())
+	if err != nil {
+		return "", err
+	}
+	if !e.within(resolved) {
+		return "", fmt.Errorf("%w: '%s' would be written through a symlink outside the extraction directory", ErrUnsafeArchiveEntry, name)
+	}
+	return target, nil
+}
+
+// prepare creates the target's parent directories, and removes any existing non-directory at the target so a symlink isn't followed.
+func (e *extractor) prepare(target PathString) error {
+	if err := target.Dir().MkdirAll(0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for '%s': %w", target, err)
+	}
+	if fi, err := os.Lstat(target.String()); err == nil {
+		if fi.IsDir() {
+			return fmt.Errorf("unable to replace directory '%s' with a file", target)
+		}
+		if err := target.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) file(name string, mode fs.FileMode, modTime time.Time, r io.Reader) error {
+	if err := e.entry(name); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := e.prepare(target); err != nil {
+		return err
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := target.OpenFile(os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm|0200)
+	if err != nil {
+		return fmt.Errorf("failed to create file '%s': %w", target, err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if _, err := io.Copy(out, e.limit(r, name)); err != nil {
+		if errors.Is(err, ErrArchiveLimit) {
+			return err
+		}
+		return fmt.Errorf("failed to extract '%s': %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Set explicitly, so the mode isn't affected by the umask.
+	if err := os.Chmod(target.String(), perm); err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(target.String(), modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) dir(name string, mode fs.FileMode, modTime time.Time) error {
+	if err := e.entry(name); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if target == e.root {
+		return nil
+	}
+	if fi, err := os.Lstat(target.String()); err == nil && !fi.IsDir() {
+		if err := target.Remove(); err != nil {
+			return err
+		}
+	}
+	if err := target.MkdirAll(0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", target, err)
+	}
+	// Modes and times are applied after all entries are written, so they aren't changed by writing children.
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0755
+	}
+	e.dirs = append(e.dirs, extractedDir{path: target, mode: perm, modTime: modTime})
+	return nil
+}
+
+func (e *extractor) symlink(name, linkname string) error {
+	if err := e.entry(name); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if !e.opts.allowUnsafe && (filepath.IsAbs(linkname) || path.IsAbs(linkname)) {
+		return fmt.Errorf("%w: symlink '%s' has an absolute target '%s'", ErrUnsafeArchiveEntry, name, linkname)
+	}
+	if err := e.prepare(target); err != nil {
+		return err
+	}
+	if !e.opts.allowUnsafe {
+		// The link is relative to its parent as resolved through any symlinks extracted earlier, like a parent that links to ".".
+		parent, err := filepath.EvalSymlinks(target.Dir().String())
+		if err != nil {
+			return err
+		}
+		if !e.within(parent) || !e.within(filepath.Join(parent, filepath.FromSlash(linkname))) {
+			return fmt.Errorf("%w: symlink '%s' points outside the extraction directory to '%s'", ErrUnsafeArchiveEntry, name, linkname)
+		}
+	}
+	if err := os.Symlink(filepath.FromSlash(linkname), target.String()); err != nil {
+		return fmt.Errorf("failed to create symlink '%s': %w", target, err)
+	}
+	e.links = append(e.links, target)
+	return nil
+}
+
+func (e *extractor) hardlink(name, linkname string) error {
+	if err := e.entry(name); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	source, err := e.target(linkname)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Lstat(source.String())
+	if err != nil {
+		return fmt.Errorf("hard link '%s' references '%s', which hasn't been extracted: %w", name, linkname, err)
+	}
+	if !e.opts.allowUnsafe && !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: hard link '%s' references '%s', which isn't a regular file", ErrUnsafeArchiveEntry, name, linkname)
+	}
+	if err := e.prepare(target); err != nil {
+		return err
+	}
+	if err := os.Link(source.String(), target.String()); err != nil {
+		return fmt.Errorf("failed to create hard link '%s': %w", target, err)
+	}
+	return nil
+}
+
+// finish checks that no extracted symlink resolves outside the extraction directory, then applies directory modes and modification times, deepest directories first.
+// Symlinks are checked again once all entries exist, since a link target may traverse symlinks extracted after it.
+var errSymlinkLoop = errors.New("too many levels of symbolic links")
+
+// resolveLink resolves a symlink like [filepath.EvalSymlinks], except that it doesn't fail for a dangling link.
+// Components that don't exist are resolved lexically, so a dangling link is resolved to where it would point if the missing components were created as directories.
+func resolveLink(link PathString) (string, error) {
+	const maxLinks = 255
+	cur, err := filepath.EvalSymlinks(link.Dir().String())
+	if err != nil {
+		return "", err
+	}
+	pending := []string{link.Base().String()}
+	followed := 0
+	for len(pending) > 0 {
+		component := pending[0]
+		pending = pending[1:]
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+		next := filepath.Join(cur, component)
+		fi, err := os.Lstat(next)
+		if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+		followed++
+		if followed > maxLinks {
+			return "", errSymlinkLoop
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if vol := filepath.VolumeName(target); filepath.IsAbs(target) {
+			cur = vol + string(filepath.Separator)
+			target = target[len(vol):]
+		}
+		pending = append(strings.Split(filepath.ToSlash(target), "/"), pending...)
+	}
+	return cur, nil
+}
+
+func (e *extractor) finish() error {
+	if !e.opts.allowUnsafe {
+		for _, link := range e.links {
+			resolved, err := resolveLink(link)
+			if errors.Is(err, errSymlinkLoop) {
+				// A link that loops can't be followed, so it doesn't reference anything.
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !e.within(resolved) {
+				_ = link.Remove()
+				return fmt.Errorf("%w: symlink '%s' resolves outside the extraction directory to '%s'", ErrUnsafeArchiveEntry, link, resolved)
+			}
+		}
+	}
+	sort.SliceStable(e.dirs, func(i, j int) bool {
+		return len(e.dirs[i].path) > len(e.dirs[j].path)
+	})
+	for _, d := range e.dirs {
+		if err := os.Chmod(d.path.String(), d.mode); err != nil {
+			return err
+		}
+		if !d.modTime.IsZero() {
+			if err := os.Chtimes(d.path.String(), d.modTime, d.modTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package modmake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	mode     int64
+	linkname string
+	content  string
+	modTime  time.Time
+}
+
+func writeTestTar(t *testing.T, location PathString, entries ...testEntry) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		if e.mode == 0 {
+			e.mode = 0644
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     e.mode,
+			Linkname: e.linkname,
+			Size:     int64(len(e.content)),
+			ModTime:  e.modTime,
+		}))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, location.WriteFile(buf.Bytes(), 0644))
+}
+
+func writeTestZip(t *testing.T, location PathString, entries ...testEntry) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.modTime}
+		mode := fs.FileMode(e.mode)
+		if mode == 0 {
+			mode = 0644
+		}
+		if e.typeflag == tar.TypeSymlink {
+			mode |= fs.ModeSymlink
+			e.content = e.linkname
+		}
+		header.SetMode(mode)
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, location.WriteFile(buf.Bytes(), 0644))
+}
+
+func TestExtract_Unsafe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks require elevated permissions on Windows")
+	}
+	ctx := context.Background()
+	tests := map[string][]testEntry{
+		"Traversal": {
+			{name: "../escaped.txt", typeflag: tar.TypeReg, content: "escaped"},
+		},
+		"Absolute path": {
+			{name: "/escaped.txt", typeflag: tar.TypeReg, content: "escaped"},
+		},
+		"Absolute symlink": {
+			{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc"},
+		},
+		"Relative symlink": {
+			{name: "dir/link", typeflag: tar.TypeSymlink, linkname: "../../"},
+		},
+		"Symlink through symlink": {
+			{name: "d", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "d/l", typeflag: tar.TypeSymlink, linkname: ".."},
+		},
+		"Symlink through later symlink": {
+			{name: "l", typeflag: tar.TypeSymlink, linkname: "d/.."},
+			{name: "d", typeflag: tar.TypeSymlink, linkname: "."},
+		},
+		"Dangling symlink through later symlink": {
+			{name: "l", typeflag: tar.TypeSymlink, linkname: "d/../x"},
+			{name: "d", typeflag: tar.TypeSymlink, linkname: "."},
+		},
+		"Hard link": {
+			{name: "link", typeflag: tar.TypeLink, linkname: "../escaped.txt"},
+		},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmp := Path(t.TempDir())
+			extractDir := tmp.Join("extract")
+			writeTestTar(t, tmp.Join("test.tar.gz"), entries...)
+			err := Tar(tmp.Join("test.tar.gz")).Extract(extractDir).Run(ctx)
+			assert.ErrorIs(t, err, ErrUnsafeArchiveEntry)
+			assert.False(t, tmp.Join("escaped.txt").Exists())
+			assert.False(t, extractDir.Join("l").Exists(), "Escaping symlinks should not be left behind")
+
+			if entries[0].typeflag == tar.TypeLink {
+				return
+			}
+			writeTestZip(t, tmp.Join("test.zip"), entries...)
+			require.NoError(t, extractDir.RemoveAll())
+			err = Zip(tmp.Join("test.zip")).Extract(extractDir).Run(ctx)
+			assert.ErrorIs(t, err, ErrUnsafeArchiveEntry)
+			assert.False(t, tmp.Join("escaped.txt").Exists())
+			assert.False(t, extractDir.Join("l").Exists(), "Escaping symlinks should not be left behind")
+		})
+	}
+
+	t.Run("Write through symlink", func(t *testing.T) {
+		tmp := Path(t.TempDir())
+		outside := tmp.Join("outside")
+		require.NoError(t, outside.MkdirAll(0755))
+		extractDir := tmp.Join("extract")
+		require.NoError(t, extractDir.MkdirAll(0755))
+		require.NoError(t, os.Symlink(outside.String(), extractDir.Join("link").String()))
+		writeTestTar(t, tmp.Join("test.tar.gz"), testEntry{name: "link/escaped.txt", typeflag: tar.TypeReg, content: "escaped"})
+		err := Tar(tmp.Join("test.tar.gz")).Extract(extractDir).Run(ctx)
+		assert.ErrorIs(t, err, ErrUnsafeArchiveEntry)
+		assert.False(t, outside.Join("escaped.txt").Exists())
+	})
+
+	t.Run("Allow unsafe", func(t *testing.T) {
+		tmp := Path(t.TempDir())
+		extractDir := tmp.Join("extract")
+		writeTestTar(t, tmp.Join("test.tar.gz"), testEntry{name: "../escaped.txt", typeflag: tar.TypeReg, content: "escaped"})
+		require.NoError(t, Tar(tmp.Join("test.tar.gz")).Extract(extractDir, ExtractAllowUnsafe()).Run(ctx))
+		assert.True(t, tmp.Join("escaped.txt").IsFile())
+	})
+}
+
+func TestExtract_Links(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks require elevated permissions on Windows")
+	}
+	tmp := Path(t.TempDir())
+	extractDir := tmp.Join("extract")
+	writeTestTar(t, tmp.Join("test.tar.gz"),
+		testEntry{name: "dir/", typeflag: tar.TypeDir, mode: 0755},
+		testEntry{name: "dir/file.txt", typeflag: tar.TypeReg, content: "content"},
+		testEntry{name: "dir/symlink.txt", typeflag: tar.TypeSymlink, linkname: "file.txt"},
+		testEntry{name: "hardlink.txt", typeflag: tar.TypeLink, linkname: "dir/file.txt"},
+		testEntry{name: "dir/dangling.txt", typeflag: tar.TypeSymlink, linkname: "missing/../missing.txt"},
+	)
+	require.NoError(t, Tar(tmp.Join("test.tar.gz")).Extract(extractDir).Run(context.Background()))
+	_, err := os.Lstat(extractDir.Join("dir", "dangling.txt").String())
+	assert.NoError(t, err, "Dangling symlinks within the extraction directory should be kept")
+	target, err := os.Readlink(extractDir.Join("dir", "symlink.txt").String())
+	require.NoError(t, err)
+	assert.Equal(t, "file.txt", target)
+	data, err := extractDir.Join("hardlink.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestExtract_ModesAndTimes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File modes aren't fully supported on Windows")
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []testEntry{
+		{name: "dir/", typeflag: tar.TypeDir, mode: 0750, modTime: modTime},
+		{name: "dir/run.sh", typeflag: tar.TypeReg, mode: 0755, content: "#!/bin/sh", modTime: modTime},
+		{name: "dir/readonly.txt", typeflag: tar.TypeReg, mode: 0444, content: "read only", modTime: modTime},
+	}
+	check := func(t *testing.T, extractDir PathString) {
+		fi, err := extractDir.Join("dir", "run.sh").Stat()
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0755), fi.Mode().Perm())
+		assert.True(t, modTime.Equal(fi.ModTime()), "Modification time should be restored")
+		fi, err = extractDir.Join("dir", "readonly.txt").Stat()
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0444), fi.Mode().Perm())
+		fi, err = extractDir.Join("dir").Stat()
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0750), fi.Mode().Perm())
+		assert.True(t, modTime.Equal(fi.ModTime()), "Directory modification time should be restored after writing children")
+	}
+
+	tmp := Path(t.TempDir())
+	writeTestTar(t, tmp.Join("test.tar.gz"), entries...)
+	require.NoError(t, Tar(tmp.Join("test.tar.gz")).Extract(tmp.Join("tar")).Run(context.Background()))
+	check(t, tmp.Join("tar"))
+
+	writeTestZip(t, tmp.Join("test.zip"), entries...)
+	require.NoError(t, Zip(tmp.Join("test.zip")).Extract(tmp.Join("zip")).Run(context.Background()))
+	check(t, tmp.Join("zip"))
+}
+
+func TestExtract_Limits(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	entries := []testEntry{
+		{name: "a.txt", typeflag: tar.TypeReg, content: "0123456789"},
+		{name: "b.txt", typeflag: tar.TypeReg, content: "0123456789"},
+		{name: "c.txt", typeflag: tar.TypeReg, content: "0123456789"},
+	}
+	writeTestTar(t, tmp.Join("test.tar.gz"), entries...)
+	writeTestZip(t, tmp.Join("test.zip"), entries...)
+
+	err := Tar(tmp.Join("test.tar.gz")).Extract(tmp.Join("size"), ExtractMaxSize(25)).Run(ctx)
+	assert.ErrorIs(t, err, ErrArchiveLimit)
+	err = Zip(tmp.Join("test.zip")).Extract(tmp.Join("size"), ExtractMaxSize(25)).Run(ctx)
+	assert.ErrorIs(t, err, ErrArchiveLimit)
+	err = Tar(tmp.Join("test.tar.gz")).Extract(tmp.Join("entries"), ExtractMaxEntries(2)).Run(ctx)
+	assert.ErrorIs(t, err, ErrArchiveLimit)
+	err = Zip(tmp.Join("test.zip")).Extract(tmp.Join("entries"), ExtractMaxEntries(2)).Run(ctx)
+	assert.ErrorIs(t, err, ErrArchiveLimit)
+
+	require.NoError(t, Tar(tmp.Join("test.tar.gz")).Extract(tmp.Join("exact"), ExtractMaxSize(30), ExtractMaxEntries(3)).Run(ctx))
+	require.NoError(t, Zip(tmp.Join("test.zip")).Extract(tmp.Join("unlimited"), ExtractMaxSize(0), ExtractMaxEntries(0)).Run(ctx))
+}
@@ -0,0 +1,434 @@
+package modmake
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlob reports whether the slash separated name matches the pattern.
+// Each segment of the pattern uses [path.Match] syntax, and a "**" segment matches zero or more path segments.
+//
+// A pattern without a "/" matches the last segment of the name at any depth, as if it started with "**/", so "*.go" matches both "main.go" and "cmd/app/main.go".
+// A pattern with a "/" is matched against the whole name, so "static/**" matches everything within the top level static directory.
+// A leading "/" anchors a pattern to the root without adding a segment, so "/build" matches "build" but not "cmd/build".
+// This is the rule used by every function and option in this package that accepts glob patterns.
+//
+// An error is returned if the pattern is malformed.
+func MatchGlob(pattern, name string) (bool, error) {
+	if err := validateGlob(pattern); err != nil {
+		return false, err
+	}
+	return matchGlobSegments(globSegments(pattern), strings.Split(name, "/")), nil
+}
+
+// globSegments splits a pattern into segments, applying the depth rule described on [MatchGlob].
+func globSegments(pattern string) []string {
+	if strings.HasPrefix(pattern, "/") {
+		return strings.Split(pattern[1:], "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		return []string{"**", pattern}
+	}
+	return strings.Split(pattern, "/")
+}
+
+func validateGlob(pattern string) error {
+	for _, segment := range globSegments(pattern) {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func mustValidateGlobs(patterns []string) {
+	for _, pattern := range patterns {
+		if err := validateGlob(pattern); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlobSegments(globSegments(pattern), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob returns the files and directories within this PathString that match the slash separated pattern, in lexical order.
+// See [MatchGlob] for the pattern syntax.
+func (p PathString) Glob(pattern string) ([]PathString, error) {
+	if err := validateGlob(pattern); err != nil {
+		return nil, err
+	}
+	// Start walking from the longest literal prefix of the pattern.
+	var (
+		segments = globSegments(pattern)
+		prefix   []string
+	)
+	for _, segment := range segments[:len(segments)-1] {
+		if segment == "**" || strings.ContainsAny(segment, `*?[\`) {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+	start := p.Join(prefix...)
+	if !start.IsDir() {
+		return nil, nil
+	}
+	var matches []PathString
+	err := start.Walk(func(file PathString, rel string, _ fs.DirEntry) error {
+		if matchGlobSegments(segments, append(append([]string{}, prefix...), strings.Split(rel, "/")...)) {
+			matches = append(matches, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// WalkFunc is called by [PathString.Walk] for each file and directory.
+// The rel parameter is the slash separated path relative to the walked directory.
+// Returning [fs.SkipDir] skips the rest of a directory, and [fs.SkipAll] stops the walk without an error.
+type WalkFunc func(path PathString, rel string, d fs.DirEntry) error
+
+// WalkOption customizes the behavior of [PathString.Walk].
+type WalkOption func(opts *walkOptions)
+
+type walkOptions struct {
+	skip       []string
+	skipHidden bool
+	gitignore  bool
+}
+
+// WalkSkip skips files and directories matching any of the patterns, including the contents of skipped directories.
+// See [MatchGlob] for the pattern syntax.
+func WalkSkip(patterns ...string) WalkOption {
+	mustValidateGlobs(patterns)
+	return func(opts *walkOptions) {
+		opts.skip = append(opts.skip, patterns...)
+	}
+}
+
+// WalkSkipHidden skips files and directories with names starting with ".".
+func WalkSkipHidden() WalkOption {
+	return func(opts *walkOptions) {
+		opts.skipHidden = true
+	}
+}
+
+// WalkGitIgnore skips files and directories ignored by .gitignore files in the walked directory and its subdirectories, as well as the .git directory.
+func WalkGitIgnore() WalkOption {
+	return func(opts *walkOptions) {
+		opts.gitignore = true
+	}
+}
+
+// Walk walks the directory tree rooted at this PathString in lexical order, calling fn for each file and directory except the root.
+// Options may be passed to skip files and directories.
+func (p PathString) Walk(fn WalkFunc, options ...WalkOption) error {
+	var opts walkOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	ignore := &gitIgnore{}
+	root := p.String()
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." {
+			skip := (opts.skipHidden && strings.HasPrefix(d.Name(), ".")) ||
+				matchAnyGlob(opts.skip, rel) ||
+				(opts.gitignore && (d.Name() == ".git" || ignore.ignored(rel, d.IsDir())))
+			if skip {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+		if opts.gitignore && d.IsDir() {
+			if err := ignore.load(Path(file), rel); err != nil {
+				return err
+			}
+		}
+		if rel == "." {
+			return nil
+		}
+		return fn(Path(file), rel, d)
+	})
+	if errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+type gitIgnoreRule struct {
+	base    string
+	pattern []string
+	negate  bool
+	dirOnly bool
+}
+
+// gitIgnore matches paths against rules loaded from .gitignore files.
+type gitIgnore struct {
+	rules []gitIgnoreRule
+}
+
+// load reads the .gitignore file in dir, if it exists.
+// The rel parameter is the directory's slash separated path relative to the walk root.
+func (g *gitIgnore) load(dir PathString, rel string) error {
+	f, err := dir.Join(".gitignore").Open()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if rel == "." {
+		rel = ""
+	}
+	return g.parse(f, rel)
+}
+
+func (g *gitIgnore) parse(r io.Reader, base string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitIgnoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if len(line) == 0 {
+			continue
+		}
+		// Patterns without a slash match at any depth, otherwise they're relative to the .gitignore file.
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		line = strings.TrimPrefix(line, "/")
+		if err := validateGlob(line); err != nil {
+			continue
+		}
+		rule.pattern = strings.Split(line, "/")
+		g.rules = append(g.rules, rule)
+	}
+	return scanner.Err()
+}
+
+// ignored returns true if the slash separated path relative to the walk root is ignored.
+// The last matching rule wins, so later negated rules may re-include a path.
+func (g *gitIgnore) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if len(rule.base) > 0 {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		if matchGlobSegments(rule.pattern, strings.Split(sub, "/")) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// FileSet selects files within a root directory with include and exclude patterns.
+// It's evaluated each time its files are requested, so it may be created before the files exist.
+// Use NewFileSet to create a FileSet.
+type FileSet struct {
+	root      PathString
+	include   []string
+	exclude   []string
+	gitignore bool
+}
+
+// NewFileSet creates a FileSet that selects all files within the root directory.
+func NewFileSet(root PathString) *FileSet {
+	if len(root) == 0 {
+		panic("empty root directory")
+	}
+	return &FileSet{root: root}
+}
+
+// Include limits the FileSet to files matching at least one of the patterns.
+// See [MatchGlob] for the pattern syntax.
+func (s *FileSet) Include(patterns ...string) *FileSet {
+	mustValidateGlobs(patterns)
+	s.include = append(s.include, patterns...)
+	return s
+}
+
+// Exclude removes files and directories matching any of the patterns from the FileSet.
+// See [MatchGlob] for the pattern syntax.
+func (s *FileSet) Exclude(patterns ...string) *FileSet {
+	mustValidateGlobs(patterns)
+	s.exclude = append(s.exclude, patterns...)
+	return s
+}
+
+// GitIgnore excludes files ignored by .gitignore files within the root directory.
+func (s *FileSet) GitIgnore() *FileSet {
+	s.gitignore = true
+	return s
+}
+
+// Root returns the root directory of the FileSet.
+func (s *FileSet) Root() PathString {
+	return s.root
+}
+
+// Match reports whether the file at the slash separated path, relative to the root directory, would be selected by the FileSet.
+// The file doesn't need to exist, so this may be used to filter file system events.
+func (s *FileSet) Match(rel string) (bool, error) {
+	rel = path.Clean(filepath.ToSlash(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return false, nil
+	}
+	var (
+		ignore   = &gitIgnore{}
+		segments = strings.Split(rel, "/")
+	)
+	if s.gitignore {
+		if err := ignore.load(s.root, "."); err != nil {
+			return false, err
+		}
+	}
+	for i, segment := range segments {
+		sub := strings.Join(segments[:i+1], "/")
+		isDir := i < len(segments)-1
+		if matchAnyGlob(s.exclude, sub) {
+			return false, nil
+		}
+		if s.gitignore {
+			if segment == ".git" || ignore.ignored(sub, isDir) {
+				return false, nil
+			}
+			if isDir {
+				if err := ignore.load(s.root.Join(sub), sub); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+	return len(s.include) == 0 || matchAnyGlob(s.include, rel), nil
+}
+
+// RelFiles returns the slash separated paths of the selected files, relative to the root directory and in lexical order.
+func (s *FileSet) RelFiles() ([]string, error) {
+	options := []WalkOption{WalkSkip(s.exclude...)}
+	if s.gitignore {
+		options = append(options, WalkGitIgnore())
+	}
+	var files []string
+	err := s.root.Walk(func(_ PathString, rel string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+		if len(s.include) > 0 && !matchAnyGlob(s.include, rel) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select files in '%s': %w", s.root, err)
+	}
+	return files, nil
+}
+
+// Files returns the paths of the selected files, in lexical order.
+func (s *FileSet) Files() ([]PathString, error) {
+	rel, err := s.RelFiles()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]PathString, len(rel))
+	for i, r := range rel {
+		files[i] = s.root.Join(r)
+	}
+	return files, nil
+}
+
+// Hash returns a hex encoded SHA-256 hash of the selected files' relative paths and content.
+// The hash changes if a file is added, removed, renamed, or modified, so it's useful for detecting when work needs to be redone.
+func (s *FileSet) Hash() (string, error) {
+	rel, err := s.RelFiles()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, r := range rel {
+		sum, err := s.root.Join(r).SHA256()
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%s\n", r, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,237 @@
+package modmake
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		"Literal":              {"main.go", "main.go", true},
+		"Star":                 {"*.go", "main.go", true},
+		"Star any depth":       {"*.go", "cmd/main.go", true},
+		"Anchored":             {"/main.go", "main.go", true},
+		"Anchored nested":      {"/main.go", "cmd/main.go", false},
+		"Slash anchors":        {"cmd/*.go", "app/cmd/main.go", false},
+		"Double star":          {"**/*.go", "cmd/app/main.go", true},
+		"Double star zero":     {"**/*.go", "main.go", true},
+		"Double star middle":   {"cmd/**/main.go", "cmd/main.go", true},
+		"Double star suffix":   {"static/**", "static/css/site.css", true},
+		"Double star mismatch": {"static/**", "templates/index.html", false},
+		"Too short":            {"cmd/*/main.go", "cmd/main.go", false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			match, err := MatchGlob(tc.pattern, tc.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.match, match)
+		})
+	}
+	_, err := MatchGlob("[", "a")
+	assert.Error(t, err)
+}
+
+func setupGlobDir(t *testing.T) PathString {
+	tmp := Path(t.TempDir())
+	for _, file := range []string{
+		"main.go",
+		"README.md",
+		".hidden/config",
+		"cmd/app/main.go",
+		"cmd/app/main_test.go",
+		"static/css/site.css",
+		"static/index.html",
+		"build/output.bin",
+	} {
+		p := tmp.Join(file)
+		require.NoError(t, p.Dir().MkdirAll(0755))
+		require.NoError(t, p.WriteFile([]byte(file), 0644))
+	}
+	return tmp
+}
+
+func TestPathString_Glob(t *testing.T) {
+	tmp := setupGlobDir(t)
+	matches, err := tmp.Glob("**/*.go")
+	require.NoError(t, err)
+	assert.Equal(t, []PathString{
+		tmp.Join("cmd/app/main.go"),
+		tmp.Join("cmd/app/main_test.go"),
+		tmp.Join("main.go"),
+	}, matches)
+
+	same, err := tmp.Glob("*.go")
+	require.NoError(t, err)
+	assert.Equal(t, matches, same, "A pattern without a slash should match at any depth")
+
+	matches, err = tmp.Glob("/*.go")
+	require.NoError(t, err)
+	assert.Equal(t, []PathString{tmp.Join("main.go")}, matches)
+
+	matches, err = tmp.Glob("static/**/*.css")
+	require.NoError(t, err)
+	assert.Equal(t, []PathString{tmp.Join("static/css/site.css")}, matches)
+
+	matches, err = tmp.Glob("missing/**")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func walkedPaths(t *testing.T, dir PathString, options ...WalkOption) []string {
+	var paths []string
+	require.NoError(t, dir.Walk(func(_ PathString, rel string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			paths = append(paths, rel)
+		}
+		return nil
+	}, options...))
+	return paths
+}
+
+func TestPathString_Walk(t *testing.T) {
+	tmp := setupGlobDir(t)
+	assert.Equal(t, []string{
+		".hidden/config",
+		"README.md",
+		"build/output.bin",
+		"cmd/app/main.go",
+		"cmd/app/main_test.go",
+		"main.go",
+		"static/css/site.css",
+		"static/index.html",
+	}, walkedPaths(t, tmp))
+
+	assert.Equal(t, []string{
+		"README.md",
+		"cmd/app/main.go",
+		"main.go",
+		"static/index.html",
+	}, walkedPaths(t, tmp, WalkSkipHidden(), WalkSkip("/build", "static/css", "*_test.go")))
+
+	var visited int
+	require.NoError(t, tmp.Walk(func(_ PathString, _ string, _ fs.DirEntry) error {
+		visited++
+		return fs.SkipAll
+	}))
+	assert.Equal(t, 1, visited)
+	assert.Panics(t, func() {
+		WalkSkip("[")
+	})
+}
+
+func TestPathString_WalkGitIgnore(t *testing.T) {
+	tmp := setupGlobDir(t)
+	require.NoError(t, tmp.Join(".git").MkdirAll(0755))
+	require.NoError(t, tmp.Join(".git", "HEAD").WriteFile([]byte("ref"), 0644))
+	require.NoError(t, tmp.Join(".gitignore").WriteFile([]byte(`
+# Build output
+build/
+*.md
+!README.md
+/main.go
+`), 0644))
+	require.NoError(t, tmp.Join("static", ".gitignore").WriteFile([]byte("css/\n"), 0644))
+	require.NoError(t, tmp.Join("cmd", "app", "main.go.md").WriteFile([]byte("notes"), 0644))
+
+	assert.Equal(t, []string{
+		".gitignore",
+		".hidden/config",
+		"README.md",
+		"cmd/app/main.go",
+		"cmd/app/main_test.go",
+		"static/.gitignore",
+		"static/index.html",
+	}, walkedPaths(t, tmp, WalkGitIgnore()))
+}
+
+func TestFileSet(t *testing.T) {
+	tmp := setupGlobDir(t)
+	set := NewFileSet(tmp).Include("**/*.go", "static/**").Exclude("**/*_test.go", "static/css")
+	files, err := set.RelFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd/app/main.go", "main.go", "static/index.html"}, files)
+
+	paths, err := set.Files()
+	require.NoError(t, err)
+	assert.Equal(t, tmp.Join("cmd/app/main.go"), paths[0])
+
+	hash, err := set.Hash()
+	require.NoError(t, err)
+	same, err := set.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, same)
+
+	// Excluded files don't affect the hash.
+	require.NoError(t, tmp.Join("cmd", "app", "main_test.go").WriteFile([]byte("changed"), 0644))
+	same, err = set.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, same)
+
+	require.NoError(t, tmp.Join("main.go").WriteFile([]byte("changed"), 0644))
+	changed, err := set.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed)
+
+	assert.Panics(t, func() {
+		NewFileSet("")
+	})
+}
+
+func TestFileSet_Match(t *testing.T) {
+	tmp := setupGlobDir(t)
+	require.NoError(t, tmp.Join(".gitignore").WriteFile([]byte("build/\n"), 0644))
+	set := NewFileSet(tmp).Include("**/*.go", "static/**").Exclude("**/*_test.go", "static/css").GitIgnore()
+	tests := map[string]bool{
+		"main.go":              true,
+		"cmd/app/main.go":      true,
+		"cmd/app/new.go":       true,
+		"cmd/app/main_test.go": false,
+		"static/index.html":    true,
+		"static/css/site.css":  false,
+		"build/output.go":      false,
+		".git/hooks/hook.go":   false,
+		"README.md":            false,
+		"../main.go":           false,
+		".":                    false,
+	}
+	for rel, expected := range tests {
+		matched, err := set.Match(rel)
+		require.NoError(t, err)
+		assert.Equal(t, expected, matched, rel)
+	}
+}
+
+func TestArchive_AddFileSet(t *testing.T) {
+	ctx := context.Background()
+	src := setupGlobDir(t)
+	tmp := Path(t.TempDir())
+	set := NewFileSet(src).Include("**/*.go").Exclude("**/*_test.go")
+	tarball := Tar(tmp.Join("src.tar.gz"))
+	require.NoError(t, tarball.AddFileSet(set, "src").Create().Run(ctx))
+	zipFile := Zip(tmp.Join("src.zip"))
+	require.NoError(t, zipFile.AddFileSet(set, "").Create().Run(ctx))
+
+	entries, err := tarball.List(ctx)
+	require.NoError(t, err)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"src/cmd/app/main.go", "src/main.go"}, paths)
+
+	entries, err = zipFile.List(ctx)
+	require.NoError(t, err)
+	paths = nil
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"cmd/app/main.go", "main.go"}, paths)
+}
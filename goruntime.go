@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -419,6 +420,7 @@ type GoBuild struct {
 	stripDebug    bool
 	trimPath      bool
 	buildMode     string
+	pgo           string
 	gcFlags       map[string]bool
 	ldFlags       map[string]bool
 	tags          map[string]bool
@@ -629,6 +631,17 @@ func (b *GoBuild) GoCompileFlags(flags ...string) *GoBuild {
 	return b
 }
 
+// PGO sets the profile used for profile-guided optimization with the -pgo flag.
+// The profile may be "auto" to use the default.pgo file in the main package directory, "off" to disable profile-guided optimization, or a path to a CPU profile.
+// See [PGOWorkflow] for collecting a profile.
+func (b *GoBuild) PGO(profile string) *GoBuild {
+	if b.err != nil {
+		return b
+	}
+	b.pgo = strings.TrimSpace(profile)
+	return b
+}
+
 // StripDebugSymbols will remove debugging information from the built artifact, reducing file size.
 // Assumes TrimPath as well.
 func (b *GoBuild) StripDebugSymbols() *GoBuild {
@@ -750,6 +763,16 @@ func (b *GoBuild) Env(key, value string) *GoBuild {
 	return b
 }
 
+// Hermetic runs the go build command with a minimal environment, as described in [Command.Hermetic].
+// Variables set with Env, OS, Arch, and CgoEnabled are still applied.
+func (b *GoBuild) Hermetic(allowEnv ...string) *GoBuild {
+	if b.err != nil {
+		return b
+	}
+	b.cmd.Hermetic().AllowEnv(allowEnv...)
+	return b
+}
+
 func (b *GoBuild) CgoEnabled(enabled bool) *GoBuild {
 	if b.err != nil {
 		return b
@@ -778,41 +801,72 @@ func (b *GoBuild) Silent() *GoBuild {
 	return b
 }
 
-func (b *GoBuild) Run(ctx context.Context) error {
-	ctx, log := WithGroup(ctx, "go build")
-	if b.err != nil {
-		return log.WrapErr(b.err)
+// Args returns the arguments that will be passed to 'go build', including flags and targets, as currently configured.
+// Flags and targets are sorted, so the same configuration always produces the same arguments.
+// This is useful for recording how an artifact was produced.
+func (b *GoBuild) Args() []string {
+	var args []string
+	if len(b.output) > 0 {
+		args = append(args, "-o", b.output.String())
+	}
+	if b.forceRebuild {
+		args = append(args, "-a")
+	}
+	if b.dryRun {
+		args = append(args, "-n")
+	}
+	if b.detectRace {
+		args = append(args, "-race")
+	}
+	if b.memorySan {
+		args = append(args, "-msan")
+	}
+	if b.addrSan {
+		args = append(args, "-asan")
+	}
+	if b.printPackages {
+		args = append(args, "-v")
+	}
+	if b.printCommands {
+		args = append(args, "-x")
 	}
-
-	b.cmd.OptArg(len(b.output) > 0, "-o", b.output.String())
-	b.cmd.OptArg(b.forceRebuild, "-a")
-	b.cmd.OptArg(b.dryRun, "-n")
-	b.cmd.OptArg(b.detectRace, "-race")
-	b.cmd.OptArg(b.memorySan, "-msan")
-	b.cmd.OptArg(b.addrSan, "-asan")
-	b.cmd.OptArg(b.printPackages, "-v")
-	b.cmd.OptArg(b.printCommands, "-x")
 	if len(b.buildMode) > 0 {
-		b.cmd.Arg("-buildmode=" + b.buildMode)
+		args = append(args, "-buildmode="+b.buildMode)
 	}
 	if len(b.gcFlags) > 0 {
-		b.cmd.Arg(fmt.Sprintf("-gcflags=%s", strings.Join(keySlice(b.gcFlags), " ")))
+		args = append(args, fmt.Sprintf("-gcflags=%s", strings.Join(sortedKeys(b.gcFlags), " ")))
 	}
-	b.cmd.OptArg(b.trimPath, "-trimpath")
+	if b.trimPath {
+		args = append(args, "-trimpath")
+	}
+	if len(b.pgo) > 0 {
+		args = append(args, "-pgo="+b.pgo)
+	}
+	ldFlags := b.ldFlags
 	if b.stripDebug {
-		b.LinkerFlags("-s", "-w")
+		ldFlags = map[string]bool{"-s": true, "-w": true}
+		for flag := range b.ldFlags {
+			ldFlags[flag] = true
+		}
 	}
-	if len(b.ldFlags) > 0 {
-		b.cmd.Arg(fmt.Sprintf("-ldflags=%s", strings.Join(keySlice(b.ldFlags), " ")))
+	if len(ldFlags) > 0 {
+		args = append(args, fmt.Sprintf("-ldflags=%s", strings.Join(sortedKeys(ldFlags), " ")))
 	}
 	if len(b.tags) > 0 {
-		b.cmd.Arg("-tags", strings.Join(keySlice(b.tags), ","))
+		args = append(args, "-tags", strings.Join(sortedKeys(b.tags), ","))
+	}
+	return append(args, sortedKeys(b.targets)...)
+}
+
+func (b *GoBuild) Run(ctx context.Context) error {
+	ctx, log := WithGroup(ctx, "go build")
+	if b.err != nil {
+		return log.WrapErr(b.err)
 	}
 	if len(b.private) > 0 {
 		b.cmd.Env("GOPRIVATE", strings.Join(b.private, ","))
 	}
-
-	b.cmd.Arg(keySlice(b.targets)...)
+	b.cmd.Arg(b.Args()...)
 	return b.cmd.Run(ctx)
 }
 
@@ -832,6 +886,12 @@ func keySlice[T any](set map[string]T) []string {
 	return keys
 }
 
+func sortedKeys[T any](set map[string]T) []string {
+	keys := keySlice(set)
+	sort.Strings(keys)
+	return keys
+}
+
 var (
 	modNamePattern = regexp.MustCompile(`^\s*module\s+(\S+)$`)
 )
@@ -109,3 +109,46 @@ func TestGoTools_GetEnv(t *testing.T) {
 	})
 	assert.Equal(t, want, got)
 }
+
+func TestGoBuild_Args(t *testing.T) {
+	gb := Go().Build("./cmd/app").
+		OutputFilename("build/app").
+		StripDebugSymbols().
+		Tags("release")
+	args := gb.Args()
+	assert.Equal(t, []string{"-o", "build/app"}, args[:2])
+	assert.Contains(t, args, "-trimpath")
+	assert.Equal(t, "./cmd/app", args[len(args)-1])
+	var ldFlags string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-ldflags=") {
+			ldFlags = arg
+		}
+	}
+	assert.Contains(t, ldFlags, "-s")
+	assert.Contains(t, ldFlags, "-w")
+	assert.Equal(t, args, gb.Args(), "Args should not change the build configuration")
+}
+
+func TestGoBuild_ArgsOrder(t *testing.T) {
+	want := []string{
+		"-o", "build/app",
+		"-race",
+		"-gcflags=-N -l",
+		"-trimpath",
+		"-ldflags=-X 'main.commit=abc' -X 'main.version=1.0.0' -s -w",
+		"-tags", "netgo,osusergo,release",
+		"./cmd/app", "./cmd/other",
+	}
+	for i := 0; i < 20; i++ {
+		gb := Go().Build("./cmd/other", "./cmd/app").
+			OutputFilename("build/app").
+			RaceDetector().
+			GoCompileFlags("-N", "-l").
+			StripDebugSymbols().
+			SetVariable("main", "version", "1.0.0").
+			SetVariable("main", "commit", "abc").
+			Tags("release", "osusergo", "netgo")
+		require.Equal(t, want, gb.Args(), "Flags and targets should always be in the same order")
+	}
+}
@@ -0,0 +1,92 @@
+package modmake
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// HermeticEnvAllowlist lists the environment variables that are passed through to hermetic Commands.
+// These are needed to locate executables, temporary directories, and caches, but don't otherwise change how tools behave.
+// Variables like GOFLAGS, CGO_ENABLED, and proxy settings are intentionally left out, and may be allowed with [Command.AllowEnv] or [WithHermeticEnv] if needed.
+var HermeticEnvAllowlist = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LOGNAME",
+	"TMPDIR",
+	"TEMP",
+	"TMP",
+	"GOPATH",
+	"GOCACHE",
+	"GOMODCACHE",
+	// Needed for executables to work on Windows.
+	"SYSTEMROOT",
+	"SYSTEMDRIVE",
+	"WINDIR",
+	"COMSPEC",
+	"PATHEXT",
+	"USERPROFILE",
+	"APPDATA",
+	"LOCALAPPDATA",
+	"PROGRAMDATA",
+}
+
+// EnvHermeticAllow is an environment variable with a comma separated list of additional variables that hermetic builds should pass through.
+// The modmake CLI sets this for variables passed with -e, so they aren't dropped when --hermetic is used.
+const EnvHermeticAllow = "MODMAKE_HERMETIC_ALLOW"
+
+type hermeticEnvKeyType string
+
+const hermeticEnvKey = hermeticEnvKeyType("hermetic env")
+
+type hermeticEnv struct {
+	allow []string
+}
+
+// WithHermeticEnv returns a context that makes every [Command] run with it start from a minimal environment, as if [Command.Hermetic] was called.
+// Additional variables may be allowed through from the environment, and calls may be nested to allow more.
+// This is used by [Step.Hermetic] and [Build.Hermetic], but may be used directly to make a group of Tasks hermetic.
+func WithHermeticEnv(ctx context.Context, allow ...string) context.Context {
+	if current, ok := ctx.Value(hermeticEnvKey).(hermeticEnv); ok {
+		allow = append(append([]string{}, current.allow...), allow...)
+	}
+	return context.WithValue(ctx, hermeticEnvKey, hermeticEnv{allow: allow})
+}
+
+// IsHermetic returns true if the context was created with [WithHermeticEnv].
+func IsHermetic(ctx context.Context) bool {
+	_, ok := ctx.Value(hermeticEnvKey).(hermeticEnv)
+	return ok
+}
+
+// hermeticEnviron returns the variables in the environment that are in the HermeticEnvAllowlist or the allow list.
+// Names are compared case-insensitively, since that's how Windows treats them.
+func hermeticEnviron(allow []string) []string {
+	allowed := map[string]bool{}
+	for _, key := range HermeticEnvAllowlist {
+		allowed[strings.ToUpper(key)] = true
+	}
+	for _, key := range allow {
+		allowed[strings.ToUpper(key)] = true
+	}
+	var env []string
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if allowed[strings.ToUpper(key)] {
+			env = append(env, entry)
+		}
+	}
+	return env
+}
+
+// hermeticAllowFromEnv returns the variable names listed in [EnvHermeticAllow].
+func hermeticAllowFromEnv() []string {
+	var allow []string
+	for _, key := range strings.Split(os.Getenv(EnvHermeticAllow), ",") {
+		if key = strings.TrimSpace(key); len(key) > 0 {
+			allow = append(allow, key)
+		}
+	}
+	return allow
+}
@@ -0,0 +1,122 @@
+package modmake
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requirePosixEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The 'env' command isn't available on Windows")
+	}
+	requireExecutable(t, "env")
+}
+
+func commandEnv(t *testing.T, ctx context.Context, cmd *Command) []string {
+	var buf strings.Builder
+	require.NoError(t, cmd.Stdout(&buf).Run(ctx))
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestCommand_Hermetic(t *testing.T) {
+	requirePosixEnv(t)
+	ctx := context.Background()
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	t.Setenv("MODMAKE_HERMETIC_ALLOWED", "yes")
+
+	env := commandEnv(t, ctx, Exec("env").Hermetic().AllowEnv("modmake_hermetic_allowed").Env("EXPLICIT", "value"))
+	assert.NotContains(t, env, "GOFLAGS=-mod=vendor")
+	assert.Contains(t, env, "MODMAKE_HERMETIC_ALLOWED=yes")
+	assert.Contains(t, env, "EXPLICIT=value")
+	assert.Contains(t, env, "PATH="+Environment()["PATH"])
+
+	env = commandEnv(t, ctx, Exec("env"))
+	assert.Contains(t, env, "GOFLAGS=-mod=vendor", "Commands inherit the environment by default")
+
+	cmd := Exec("env")
+	t.Setenv("MODMAKE_HERMETIC_LATE", "late")
+	assert.Contains(t, commandEnv(t, ctx, cmd), "MODMAKE_HERMETIC_LATE=late", "The environment should be read when the Command runs")
+}
+
+func TestWithHermeticEnv(t *testing.T) {
+	requirePosixEnv(t)
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	t.Setenv("GOPROXY", "direct")
+	ctx := WithHermeticEnv(context.Background(), "GOPROXY")
+	assert.True(t, IsHermetic(ctx))
+	assert.False(t, IsHermetic(context.Background()))
+
+	env := commandEnv(t, ctx, Exec("env"))
+	assert.NotContains(t, env, "GOFLAGS=-mod=vendor")
+	assert.Contains(t, env, "GOPROXY=direct")
+
+	env = commandEnv(t, WithHermeticEnv(ctx, "GOFLAGS"), Exec("env"))
+	assert.Contains(t, env, "GOFLAGS=-mod=vendor", "Nested calls should allow more variables")
+	assert.Contains(t, env, "GOPROXY=direct")
+}
+
+func TestBuild_Hermetic(t *testing.T) {
+	requirePosixEnv(t)
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	var stepEnv, hermeticStepEnv string
+	newBuild := func() *Build {
+		b := NewBuild()
+		b.Test().Skip()
+		b.AddNewStep("capture", "", Task(func(ctx context.Context) error {
+			var buf strings.Builder
+			err := Exec("env").Stdout(&buf).Run(ctx)
+			stepEnv = buf.String()
+			return err
+		}))
+		b.AddNewStep("hermetic", "", Task(func(ctx context.Context) error {
+			var buf strings.Builder
+			err := Exec("env").Stdout(&buf).Run(ctx)
+			hermeticStepEnv = buf.String()
+			return err
+		})).Hermetic()
+		return b
+	}
+
+	require.NoError(t, newBuild().ExecuteErr("capture", "hermetic"))
+	assert.Contains(t, stepEnv, "GOFLAGS=-mod=vendor")
+	assert.NotContains(t, hermeticStepEnv, "GOFLAGS=-mod=vendor", "Step should be hermetic")
+
+	require.NoError(t, newBuild().ExecuteErr("--hermetic", "capture"))
+	assert.NotContains(t, stepEnv, "GOFLAGS=-mod=vendor", "Build should be hermetic with the flag")
+
+	stepEnv = ""
+	require.NoError(t, newBuild().Hermetic().ExecuteErr("capture"))
+	assert.NotContains(t, stepEnv, "GOFLAGS=-mod=vendor", "Build should be hermetic")
+	assert.Contains(t, stepEnv, "PATH=")
+}
+
+func TestBuild_HermeticProfile(t *testing.T) {
+	requirePosixEnv(t)
+	t.Cleanup(func() {
+		_ = os.Unsetenv("MODMAKE_PROFILE_VALUE")
+	})
+	t.Setenv("MODMAKE_CLI_VALUE", "cli")
+	t.Setenv(EnvHermeticAllow, "MODMAKE_CLI_VALUE")
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	var stepEnv string
+	b := NewBuild()
+	b.Test().Skip()
+	b.AddNewStep("capture", "", Task(func(ctx context.Context) error {
+		var buf strings.Builder
+		err := Exec("env").Stdout(&buf).Run(ctx)
+		stepEnv = buf.String()
+		return err
+	}))
+	b.Profile("ci", "").Env("MODMAKE_PROFILE_VALUE", "ci")
+
+	require.NoError(t, b.ExecuteErr("--profile=ci", "--hermetic", "capture"))
+	assert.Contains(t, stepEnv, "MODMAKE_PROFILE_VALUE=ci", "Profile variables should be allowed in hermetic builds")
+	assert.Contains(t, stepEnv, "MODMAKE_CLI_VALUE=cli", "Variables passed with -e should be allowed in hermetic builds")
+	assert.NotContains(t, stepEnv, "GOFLAGS=-mod=vendor", "Build should still be hermetic")
+}
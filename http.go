@@ -2,13 +2,142 @@ package modmake
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func Download(url string, location PathString) Task {
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// MaxDownloadBackoff is the longest delay between download attempts, unless the initial backoff passed to [DownloadRetries] is longer.
+const MaxDownloadBackoff = time.Minute
+
+// DownloadOption customizes the behavior of [Download].
+type DownloadOption func(opts *downloadOptions)
+
+type downloadOptions struct {
+	client           *http.Client
+	sha256           string
+	retries          int
+	backoff          time.Duration
+	resume           bool
+	headers          http.Header
+	cacheDir         PathString
+	progressInterval time.Duration
+}
+
+// DownloadSHA256 sets the expected SHA-256 checksum of the downloaded file, in hex format.
+// The download fails, and the destination is left untouched, if the checksum doesn't match.
+func DownloadSHA256(checksum string) DownloadOption {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		panic(fmt.Sprintf("invalid SHA-256 checksum '%s'", checksum))
+	}
+	return func(opts *downloadOptions) {
+		opts.sha256 = checksum
+	}
+}
+
+// DownloadRetries will retry a failed download up to the given number of times.
+// The delay between attempts starts at backoff, and doubles after each attempt up to [MaxDownloadBackoff].
+// Connection errors, 5xx responses, and 429 Too Many Requests responses are retried, other responses are not.
+func DownloadRetries(retries int, backoff time.Duration) DownloadOption {
+	if retries < 0 {
+		panic("negative retries")
+	}
+	if backoff < 0 {
+		panic("negative backoff")
+	}
+	return func(opts *downloadOptions) {
+		opts.retries = retries
+		opts.backoff = backoff
+	}
+}
+
+// DownloadResume will keep partially downloaded data between attempts and runs, and request only the remaining data with an HTTP range request.
+// If the server doesn't support range requests, then the download starts over.
+func DownloadResume() DownloadOption {
+	return func(opts *downloadOptions) {
+		opts.resume = true
+	}
+}
+
+// DownloadHeader adds a header to the download request.
+func DownloadHeader(key, value string) DownloadOption {
+	return func(opts *downloadOptions) {
+		opts.headers.Add(key, value)
+	}
+}
+
+// DownloadHeaderFromEnv adds a header to the download request with a value from the named environment variable.
+// The header isn't added if the variable is unset or empty, so the same build may be used with and without credentials.
+// The value is registered with [RegisterSecret].
+func DownloadHeaderFromEnv(key, envVar string) DownloadOption {
+	return func(opts *downloadOptions) {
+		if value := os.Getenv(envVar); len(value) > 0 {
+			RegisterSecret(value)
+			opts.headers.Add(key, value)
+		}
+	}
+}
+
+// DownloadBearerTokenFromEnv sets an "Authorization: Bearer" header with a token from the named environment variable, if it's set.
+// The token is registered with [RegisterSecret].
+func DownloadBearerTokenFromEnv(envVar string) DownloadOption {
+	return func(opts *downloadOptions) {
+		if token := os.Getenv(envVar); len(token) > 0 {
+			RegisterSecret(token)
+			opts.headers.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// DownloadCache stores downloads in the given directory, keyed by the URL and expected checksum (if any).
+// Later downloads with the same key are copied from the cache rather than fetched again.
+func DownloadCache(cacheDir PathString) DownloadOption {
+	if len(cacheDir) == 0 {
+		panic("empty cache directory")
+	}
+	return func(opts *downloadOptions) {
+		opts.cacheDir = cacheDir
+	}
+}
+
+// DownloadProgress logs download progress at the given interval through the step logger.
+func DownloadProgress(interval time.Duration) DownloadOption {
+	if interval <= 0 {
+		panic("invalid progress interval")
+	}
+	return func(opts *downloadOptions) {
+		opts.progressInterval = interval
+	}
+}
+
+// DownloadClient overrides the HTTP client used for downloading, which defaults to http.DefaultClient.
+func DownloadClient(client *http.Client) DownloadOption {
+	if client == nil {
+		panic("nil HTTP client")
+	}
+	return func(opts *downloadOptions) {
+		opts.client = client
+	}
+}
+
+// Download creates a Task that downloads the given URL to the location.
+// Data is written to a temporary file next to the location, which is renamed once the download is complete, so the location never contains a partial download.
+// Options may be passed to verify checksums, retry, resume, authenticate, cache, and report progress.
+// The URL is logged without its query string or credentials, since they often contain tokens.
+func Download(url string, location PathString, options ...DownloadOption) Task {
 	url = strings.TrimSpace(url)
 	if len(url) == 0 {
 		panic("empty URL")
@@ -18,31 +147,227 @@ func Download(url string, location PathString) Task {
 	}
 	return func(ctx context.Context) error {
 		ctx, log := WithGroup(ctx, "download")
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return log.WrapErr(fmt.Errorf("failed to create request: %v", err))
+		logURL := displayURL(url)
+		opts := &downloadOptions{
+			client:  http.DefaultClient,
+			headers: http.Header{},
 		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return log.WrapErr(fmt.Errorf("failed to send HTTP request: %v", err))
+		for _, opt := range options {
+			opt(opts)
 		}
-		defer func() {
-			if resp.Body != nil {
-				_ = resp.Body.Close()
+
+		var cached PathString
+		if len(opts.cacheDir) > 0 {
+			key := sha256.Sum256([]byte(url + "\n" + opts.sha256))
+			cached = opts.cacheDir.Join(hex.EncodeToString(key[:]))
+			if cached.IsFile() {
+				if err := verifyDownload(cached, opts.sha256); err == nil {
+					log.Debug("Using cached download of %s", logURL)
+					return log.WrapErr(copyAtomic(cached, location))
+				}
+				log.Warn("Ignoring invalid cached download of %s", logURL)
 			}
-		}()
-		if resp.StatusCode != 200 {
-			return log.WrapErr(fmt.Errorf("expected status 200 OK, got %s", resp.Status))
 		}
 
-		out, err := location.Create()
+		partial := Path(location.String() + ".part")
+		err := downloadWithRetries(ctx, log, url, partial, opts)
 		if err != nil {
-			return log.WrapErr(fmt.Errorf("failed to create download file '%s': %w", location, err))
+			redactURLError(err)
+			if !opts.resume {
+				_ = partial.Remove()
+			}
+			return log.WrapErr(err)
+		}
+		if err := verifyDownload(partial, opts.sha256); err != nil {
+			_ = partial.Remove()
+			return log.WrapErr(err)
+		}
+		if len(cached) > 0 {
+			if err := opts.cacheDir.MkdirAll(0755); err != nil {
+				return log.WrapErr(err)
+			}
+			if err := copyAtomic(partial, cached); err != nil {
+				log.Warn("Failed to cache download of %s: %v", logURL, err)
+			}
+		}
+		if err := os.Rename(partial.String(), location.String()); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to move download to '%s': %w", location, err))
+		}
+		return nil
+	}
+}
+
+func downloadWithRetries(ctx context.Context, log Logger, url string, partial PathString, opts *downloadOptions) error {
+	for attempt := 0; ; attempt++ {
+		retry, err := downloadAttempt(ctx, log, url, partial, opts)
+		if err == nil || !retry || attempt >= opts.retries {
+			return err
+		}
+		delay := downloadBackoff(opts.backoff, attempt)
+		redactURLError(err)
+		log.Warn("Download attempt %d of %d failed, retrying in %s: %v", attempt+1, opts.retries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// downloadBackoff returns the delay before the next attempt, doubling the backoff for each previous attempt up to [MaxDownloadBackoff].
+func downloadBackoff(backoff time.Duration, attempt int) time.Duration {
+	delay := backoff
+	for i := 0; i < attempt && delay < MaxDownloadBackoff; i++ {
+		delay = min(delay*2, MaxDownloadBackoff)
+	}
+	return delay
+}
+
+// displayURL returns the URL without credentials, a query string, or a fragment, so it may be logged.
+func displayURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			return rawURL[:i]
+		}
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// redactURLError removes the query string from the URL in an HTTP client error, which would otherwise be included in the message.
+func redactURLError(err error) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = displayURL(urlErr.URL)
+	}
+}
+
+// downloadAttempt makes a single download request, writing to the partial file.
+// It returns whether a failure may be retried.
+func downloadAttempt(ctx context.Context, log Logger, url string, partial PathString, opts *downloadOptions) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range opts.headers {
+		req.Header[key] = values
+	}
+	var offset int64
+	if opts.resume {
+		if fi, err := partial.Stat(); err == nil && fi.Size() > 0 {
+			offset = fi.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+	}
+	resp, err := opts.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		log.Info("Resuming download of %s at %d bytes", displayURL(url), offset)
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file may already be complete, or is stale. Start over to be safe.
+		_ = partial.Remove()
+		return true, fmt.Errorf("server rejected resuming at %d bytes", offset)
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	default:
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("expected status 200 OK, got %s", resp.Status)
+	}
+
+	out, err := partial.OpenFile(flags, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to create download file '%s': %w", partial, err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	var (
+		w     io.Writer = out
+		total           = resp.ContentLength
+	)
+	if total >= 0 {
+		total += offset
+	}
+	if opts.progressInterval > 0 {
+		w = &progressWriter{
+			w:        out,
+			log:      log,
+			url:      displayURL(url),
+			written:  offset,
+			total:    total,
+			interval: opts.progressInterval,
+			last:     time.Now(),
+		}
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return false, nil
+}
+
+func verifyDownload(file PathString, expected string) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	actual, err := file.SHA256()
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: got SHA-256 %s, expected %s", ErrChecksumMismatch, actual, expected)
+	}
+	return nil
+}
+
+// copyAtomic copies source to a temporary file next to target, and renames it into place.
+func copyAtomic(source, target PathString) error {
+	tmp := Path(target.String() + ".tmp-" + strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := source.CopyTo(tmp); err != nil {
+		_ = tmp.Remove()
+		return err
+	}
+	if err := os.Rename(tmp.String(), target.String()); err != nil {
+		_ = tmp.Remove()
+		return err
+	}
+	return nil
+}
+
+type progressWriter struct {
+	w        io.Writer
+	log      Logger
+	url      string
+	written  int64
+	total    int64
+	interval time.Duration
+	last     time.Time
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	p.written += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		if p.total > 0 {
+			p.log.Info("Downloaded %d of %d bytes (%.1f%%) from %s", p.written, p.total, float64(p.written)/float64(p.total)*100, p.url)
+		} else {
+			p.log.Info("Downloaded %d bytes from %s", p.written, p.url)
 		}
-		defer func() {
-			_ = out.Close()
-		}()
-		_, err = io.Copy(out, resp.Body)
-		return log.WrapErr(err)
 	}
+	return n, err
 }
@@ -0,0 +1,514 @@
+package modmake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat identifies the format of a downloaded release archive.
+type ArchiveFormat string
+
+const (
+	// ArchiveAuto detects the archive format from the URL's file extension, or from the downloaded file's content.
+	ArchiveAuto ArchiveFormat = ""
+	// ArchiveRaw is a download that isn't an archive, like a single executable.
+	ArchiveRaw ArchiveFormat = "raw"
+	// ArchiveTar is an uncompressed tar archive.
+	ArchiveTar ArchiveFormat = "tar"
+	// ArchiveTarGz is a gzip compressed tar archive.
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	// ArchiveTarZst is a zstd compressed tar archive, which requires the "zstd" executable.
+	ArchiveTarZst ArchiveFormat = "tar.zst"
+	// ArchiveTarXz is an xz compressed tar archive, which requires the "xz" executable.
+	ArchiveTarXz ArchiveFormat = "tar.xz"
+	// ArchiveTarBz2 is a bzip2 compressed tar archive.
+	ArchiveTarBz2 ArchiveFormat = "tar.bz2"
+	// ArchiveZip is a zip archive.
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// ReleaseInstall downloads a release archive, and installs selected files from it to a target directory.
+// Use InstallRelease to create a ReleaseInstall.
+type ReleaseInstall struct {
+	urlTemplate     string
+	targetDir       PathString
+	goos, goarch    string
+	vars            EnvMap
+	sha256          map[string]string
+	format          ArchiveFormat
+	stripComponents int
+	members         []string
+	executables     []string
+	name            string
+	downloadOpts    []DownloadOption
+	extractOpts     []ExtractOption
+}
+
+// InstallRelease creates a ReleaseInstall that downloads from the urlTemplate and installs files to the targetDir.
+// The urlTemplate is formatted with [F], and may reference the following variables in addition to any set with [ReleaseInstall.Var]:
+//   - GOOS (or OS): The target OS, which defaults to runtime.GOOS.
+//   - GOARCH (or ARCH): The target architecture, which defaults to runtime.GOARCH.
+//   - EXE: ".exe" if the target OS is windows, otherwise an empty string.
+//
+// For example, "https://github.com/me/tool/releases/download/v${VERSION}/tool_${GOOS}_${GOARCH}.tar.gz".
+func InstallRelease(urlTemplate string, targetDir PathString) *ReleaseInstall {
+	if len(strings.TrimSpace(urlTemplate)) == 0 {
+		panic("empty URL template")
+	}
+	if len(targetDir) == 0 {
+		panic("empty target directory")
+	}
+	return &ReleaseInstall{
+		urlTemplate: urlTemplate,
+		targetDir:   targetDir,
+		goos:        runtime.GOOS,
+		goarch:      runtime.GOARCH,
+		vars:        EnvMap{},
+		sha256:      map[string]string{},
+	}
+}
+
+// Var sets a variable that may be referenced in the URL template and member patterns.
+func (r *ReleaseInstall) Var(key, value string) *ReleaseInstall {
+	if len(key) == 0 {
+		panic("empty variable name")
+	}
+	r.vars[key] = value
+	return r
+}
+
+// Platform overrides the target OS and architecture, which default to the host's.
+func (r *ReleaseInstall) Platform(goos, goarch string) *ReleaseInstall {
+	if len(goos) == 0 || len(goarch) == 0 {
+		panic("empty OS or architecture")
+	}
+	r.goos = goos
+	r.goarch = goarch
+	return r
+}
+
+// SHA256 sets the expected checksum of the download for a platform, in "os/arch" format like "linux/amd64".
+// The install will fail if the downloaded file for the target platform doesn't match.
+func (r *ReleaseInstall) SHA256(platform, checksum string) *ReleaseInstall {
+	if len(platform) == 0 {
+		panic("empty platform")
+	}
+	// Validated early, so misconfiguration is found before a build runs.
+	DownloadSHA256(checksum)
+	r.sha256[platform] = checksum
+	return r
+}
+
+// Format overrides archive format detection.
+func (r *ReleaseInstall) Format(format ArchiveFormat) *ReleaseInstall {
+	switch format {
+	case ArchiveAuto, ArchiveRaw, ArchiveTar, ArchiveTarGz, ArchiveTarZst, ArchiveTarXz, ArchiveTarBz2, ArchiveZip:
+		r.format = format
+		return r
+	default:
+		panic(fmt.Sprintf("unsupported archive format '%s'", format))
+	}
+}
+
+// StripComponents removes the given number of leading path elements from each archive member, like tar's --strip-components option.
+// Members with fewer path elements are skipped.
+func (r *ReleaseInstall) StripComponents(n int) *ReleaseInstall {
+	if n < 0 {
+		panic("negative strip components")
+	}
+	r.stripComponents = n
+	return r
+}
+
+// Member selects files to install from the archive with patterns, see [MatchGlob] for the syntax.
+// Patterns are matched against the slash separated archive path after [ReleaseInstall.StripComponents] is applied, and are formatted with the same variables as the URL template.
+// Each pattern must match at least one file.
+// If no members are selected, then all files are installed.
+func (r *ReleaseInstall) Member(patterns ...string) *ReleaseInstall {
+	mustValidateGlobs(patterns)
+	r.members = append(r.members, patterns...)
+	return r
+}
+
+// Executable marks installed files matching the patterns as executable, which are matched in the same way as [ReleaseInstall.Member].
+// Files that are executable in the archive are always installed as executable, and a download that isn't an archive is always executable.
+func (r *ReleaseInstall) Executable(patterns ...string) *ReleaseInstall {
+	mustValidateGlobs(patterns)
+	r.executables = append(r.executables, patterns...)
+	return r
+}
+
+// Name sets the installed file name of a download that isn't an archive, which defaults to the base name of the URL path.
+// The name is formatted with the same variables as the URL template.
+func (r *ReleaseInstall) Name(name string) *ReleaseInstall {
+	if len(name) == 0 {
+		panic("empty name")
+	}
+	r.name = name
+	return r
+}
+
+// DownloadOptions sets options used to download the release, like [DownloadRetries] or [DownloadCache].
+func (r *ReleaseInstall) DownloadOptions(opts ...DownloadOption) *ReleaseInstall {
+	r.downloadOpts = append(r.downloadOpts, opts...)
+	return r
+}
+
+// ExtractOptions sets limits used while installing files from an archive, like [ExtractMaxSize] and [ExtractMaxEntries], which default to [DefaultExtractMaxSize] and [DefaultExtractMaxEntries].
+// Installed files are always kept within the target directory, so [ExtractAllowUnsafe] has no effect.
+func (r *ReleaseInstall) ExtractOptions(opts ...ExtractOption) *ReleaseInstall {
+	r.extractOpts = append(r.extractOpts, opts...)
+	return r
+}
+
+func (r *ReleaseInstall) templateVars() EnvMap {
+	exe := ""
+	if r.goos == "windows" {
+		exe = ".exe"
+	}
+	vars := EnvMap{
+		"GOOS":   r.goos,
+		"OS":     r.goos,
+		"GOARCH": r.goarch,
+		"ARCH":   r.goarch,
+		"EXE":    exe,
+	}
+	for k, v := range r.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// Run downloads the release and installs the selected files.
+func (r *ReleaseInstall) Run(ctx context.Context) error {
+	ctx, log := WithGroup(ctx, "install release")
+	_, err := r.install(ctx, log)
+	return log.WrapErr(err)
+}
+
+// install downloads the release and installs the selected files, returning the hex encoded SHA-256 checksum of the download.
+func (r *ReleaseInstall) install(ctx context.Context, log Logger) (string, error) {
+	vars := r.templateVars()
+	downloadURL, err := FErr(r.urlTemplate, vars)
+	if err != nil {
+		return "", err
+	}
+	name, err := FErr(r.name, vars)
+	if err != nil {
+		return "", fmt.Errorf("invalid file name: %w", err)
+	}
+	members := make([]string, len(r.members))
+	for i, m := range r.members {
+		if members[i], err = FErr(m, vars); err != nil {
+			return "", fmt.Errorf("invalid member pattern: %w", err)
+		}
+	}
+	executables := make([]string, len(r.executables))
+	for i, e := range r.executables {
+		if executables[i], err = FErr(e, vars); err != nil {
+			return "", fmt.Errorf("invalid executable pattern: %w", err)
+		}
+	}
+	opts := append([]DownloadOption{}, r.downloadOpts...)
+	if sum, ok := r.sha256[r.goos+"/"+r.goarch]; ok {
+		opts = append(opts, DownloadSHA256(sum))
+	}
+
+	tmp, err := os.MkdirTemp("", "modmake-release-*")
+	if err != nil {
+		return "", err
+	}
+	tmpDir := Path(tmp)
+	defer func() {
+		_ = tmpDir.RemoveAll()
+	}()
+	download := tmpDir.Join("download")
+	log.Info("Downloading %s", downloadURL)
+	if err := Download(downloadURL, download, opts...).Run(ctx); err != nil {
+		return "", err
+	}
+	sum, err := download.SHA256()
+	if err != nil {
+		return "", err
+	}
+
+	format := r.format
+	if format == ArchiveAuto {
+		format, err = detectArchiveFormat(downloadURL, download)
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := r.targetDir.MkdirAll(0755); err != nil {
+		return "", fmt.Errorf("failed to create target directory: %w", err)
+	}
+	if format == ArchiveRaw {
+		if len(name) == 0 {
+			name = path.Base(urlPath(downloadURL))
+		}
+		if len(name) == 0 || name == "/" || name == "." {
+			return "", fmt.Errorf("unable to determine a file name for '%s', use Name to set one", downloadURL)
+		}
+		target := r.targetDir.Join(name)
+		f, err := download.Open()
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		if err := installFile(f, target, 0755, time.Time{}); err != nil {
+			return "", err
+		}
+		log.Info("Installed %s", target)
+		return sum, nil
+	}
+
+	limits, err := newExtractor(r.targetDir, r.extractOpts)
+	if err != nil {
+		return "", err
+	}
+	matched := make([]bool, len(members))
+	installed := 0
+	err = walkArchive(ctx, download, format, func(name string, mode fs.FileMode, modTime time.Time, open func() (io.ReadCloser, error)) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, ok := stripComponents(name, r.stripComponents)
+		if !ok {
+			return nil
+		}
+		if len(members) > 0 {
+			selected := false
+			for i, pattern := range members {
+				if matchAnyGlob([]string{pattern}, rel) {
+					matched[i] = true
+					selected = true
+				}
+			}
+			if !selected {
+				return nil
+			}
+		}
+		perm := fs.FileMode(0644)
+		if mode&0111 != 0 {
+			perm = 0755
+		}
+		for _, pattern := range executables {
+			if matchAnyGlob([]string{pattern}, rel) {
+				perm = 0755
+			}
+		}
+		if err := limits.entry(name); err != nil {
+			return err
+		}
+		src, err := open()
+		if err != nil {
+			return fmt.Errorf("failed to open archive member '%s': %w", name, err)
+		}
+		defer func() {
+			_ = src.Close()
+		}()
+		target := r.targetDir.Join(rel)
+		if err := installFile(limits.limit(src, name), target, perm, modTime); err != nil {
+			return err
+		}
+		log.Debug("Installed %s", target)
+		installed++
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for i, ok := range matched {
+		if !ok {
+			return "", fmt.Errorf("no files in '%s' match member pattern '%s'", downloadURL, members[i])
+		}
+	}
+	log.Info("Installed %d files to %s", installed, r.targetDir)
+	return sum, nil
+}
+
+// Task returns a Task that runs this ReleaseInstall.
+func (r *ReleaseInstall) Task() Task {
+	return r.Run
+}
+
+// urlPath returns the path portion of a URL, or the original string if it can't be parsed.
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Path
+}
+
+// detectArchiveFormat determines the format of a downloaded file, first from the URL's extension, then from magic bytes in the file.
+func detectArchiveFormat(rawURL string, file PathString) (ArchiveFormat, error) {
+	lower := strings.ToLower(urlPath(rawURL))
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return ArchiveTarZst, nil
+	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"):
+		return ArchiveTarXz, nil
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return ArchiveTarBz2, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip, nil
+	}
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	header = header[:n]
+	switch detectCompression(header) {
+	case TarGzip:
+		return ArchiveTarGz, nil
+	case TarZstd:
+		return ArchiveTarZst, nil
+	case TarXz:
+		return ArchiveTarXz, nil
+	case TarBzip2:
+		return ArchiveTarBz2, nil
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return ArchiveTar, nil
+	}
+	return ArchiveRaw, nil
+}
+
+type archiveMemberFunc func(name string, mode fs.FileMode, modTime time.Time, open func() (io.ReadCloser, error)) error
+
+// walkArchive calls fn for each regular file in the archive, in archive order.
+func walkArchive(ctx context.Context, file PathString, format ArchiveFormat, fn archiveMemberFunc) error {
+	switch format {
+	case ArchiveTar, ArchiveTarGz, ArchiveTarZst, ArchiveTarXz, ArchiveTarBz2:
+		f, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		// Compression is detected from content, which also handles a URL with a misleading extension.
+		r, _, err := decompressReader(ctx, f)
+		if err != nil {
+			return fmt.Errorf("unable to create decompressing reader for '%s': %w", file, err)
+		}
+		defer func() {
+			_ = r.Close()
+		}()
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read tar archive '%s': %w", file, err)
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := fn(header.Name, header.FileInfo().Mode(), header.ModTime, func() (io.ReadCloser, error) {
+				return io.NopCloser(tr), nil
+			}); err != nil {
+				return err
+			}
+		}
+	case ArchiveZip:
+		zr, err := zip.OpenReader(file.String())
+		if err != nil {
+			return fmt.Errorf("failed to open zip archive '%s': %w", file, err)
+		}
+		defer func() {
+			_ = zr.Close()
+		}()
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			if err := fn(f.Name, f.Mode(), f.Modified, f.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported archive format '%s'", format)
+	}
+}
+
+// stripComponents removes n leading elements from the slash separated archive path.
+// It returns false if nothing is left, or if the path would escape the target directory.
+func stripComponents(name string, n int) (string, bool) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+	parts := strings.Split(name, "/")
+	if len(name) == 0 || len(parts) <= n {
+		return "", false
+	}
+	return strings.Join(parts[n:], "/"), true
+}
+
+// installFile writes data to a temporary file next to target, then renames it into place so a running executable may be replaced.
+func installFile(src io.Reader, target PathString, perm fs.FileMode, modTime time.Time) error {
+	if err := target.Dir().MkdirAll(0755); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", target, err)
+	}
+	tmp := Path(target.String() + ".tmp-" + strconv.FormatInt(time.Now().UnixNano(), 36))
+	err := func() error {
+		out, err := tmp.OpenFile(os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = out.Close()
+		}()
+		if _, err := io.Copy(out, src); err != nil {
+			return err
+		}
+		return out.Close()
+	}()
+	if err == nil {
+		// Ensure the mode isn't affected by the umask.
+		err = os.Chmod(tmp.String(), perm)
+	}
+	if err == nil && !modTime.IsZero() {
+		err = os.Chtimes(tmp.String(), modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(tmp.String(), target.String())
+	}
+	if err != nil {
+		_ = tmp.Remove()
+		return fmt.Errorf("failed to install '%s': %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package modmake
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReleaseFiles() map[string][]byte {
+	return map[string][]byte{
+		"tool-1.0/bin/tool":         []byte("#!/bin/sh\necho tool\n"),
+		"tool-1.0/include/tool.h":   []byte("// header"),
+		"tool-1.0/README.md":        []byte("readme"),
+		"tool-1.0/bin/tool-helper":  []byte("helper"),
+		"tool-1.0/include/extra.h":  []byte("// extra"),
+		"tool-1.0/share/doc/doc.md": []byte("doc"),
+	}
+}
+
+func testTarGz(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(files) {
+		mode := int64(0644)
+		if name == "tool-1.0/bin/tool" {
+			mode = 0755
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func testZip(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(files) {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestInstallRelease(t *testing.T) {
+	files := testReleaseFiles()
+	tarGz := testTarGz(t, files)
+	zipData := testZip(t, files)
+	raw := []byte("raw executable")
+	var (
+		mux       sync.Mutex
+		requested []string
+	)
+	getRequested := func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]string{}, requested...)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		requested = append(requested, r.URL.Path)
+		mux.Unlock()
+		switch r.URL.Path {
+		case "/v1.0/tool_linux_amd64.tar.gz", "/v1.0/tool_linux_amd64":
+			_, _ = w.Write(tarGz)
+		case "/v1.0/tool_windows_amd64.zip":
+			_, _ = w.Write(zipData)
+		case "/v1.0/tool-raw":
+			_, _ = w.Write(raw)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	t.Run("TarGz", func(t *testing.T) {
+		target := Path(t.TempDir())
+		sum := sha256.Sum256(tarGz)
+		err := InstallRelease(srv.URL+"/v${VERSION}/tool_${GOOS}_${GOARCH}.tar.gz", target).
+			Var("VERSION", "1.0").
+			Platform("linux", "amd64").
+			SHA256("linux/amd64", hex.EncodeToString(sum[:])).
+			StripComponents(1).
+			Member("bin/tool", "include/*.h").
+			Run(ctx)
+		require.NoError(t, err)
+		requested := getRequested()
+		assert.Equal(t, "/v1.0/tool_linux_amd64.tar.gz", requested[len(requested)-1])
+		data, err := target.Join("bin", "tool").ReadFile()
+		require.NoError(t, err)
+		assert.Equal(t, files["tool-1.0/bin/tool"], data)
+		assert.True(t, target.Join("include", "tool.h").IsFile())
+		assert.True(t, target.Join("include", "extra.h").IsFile())
+		assert.False(t, target.Join("README.md").Exists(), "Unselected members should not be installed")
+		assert.False(t, target.Join("bin", "tool-helper").Exists(), "Unselected members should not be installed")
+		if runtime.GOOS != "windows" {
+			fi, err := target.Join("bin", "tool").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, 0755, int(fi.Mode().Perm()))
+			fi, err = target.Join("include", "tool.h").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, 0644, int(fi.Mode().Perm()))
+		}
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		target := Path(t.TempDir())
+		err := InstallRelease(srv.URL+"/v1.0/tool_${GOOS}_${GOARCH}.tar.gz", target).
+			Platform("linux", "amd64").
+			SHA256("linux/amd64", "0000000000000000000000000000000000000000000000000000000000000000").
+			Run(ctx)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		entries, err := os.ReadDir(target.String())
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Zip with executables", func(t *testing.T) {
+		target := Path(t.TempDir())
+		err := InstallRelease(srv.URL+"/v1.0/tool_${GOOS}_${GOARCH}.zip", target).
+			Platform("windows", "amd64").
+			StripComponents(2).
+			Member("tool*").
+			Executable("tool").
+			Run(ctx)
+		require.NoError(t, err)
+		assert.True(t, target.Join("tool").IsFile())
+		assert.True(t, target.Join("tool-helper").IsFile())
+		assert.True(t, target.Join("tool.h").IsFile())
+		assert.False(t, target.Join("doc.md").Exists())
+		if runtime.GOOS != "windows" {
+			fi, err := target.Join("tool").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, 0755, int(fi.Mode().Perm()))
+			fi, err = target.Join("tool-helper").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, 0644, int(fi.Mode().Perm()))
+		}
+	})
+
+	t.Run("Detect from content", func(t *testing.T) {
+		target := Path(t.TempDir())
+		err := InstallRelease(srv.URL+"/v1.0/tool_${GOOS}_${GOARCH}", target).
+			Platform("linux", "amd64").
+			StripComponents(1).
+			Member("README.md").
+			Run(ctx)
+		require.NoError(t, err)
+		assert.True(t, target.Join("README.md").IsFile())
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		target := Path(t.TempDir())
+		err := InstallRelease(srv.URL+"/v1.0/tool-raw", target).
+			Platform("windows", "amd64").
+			Name("tool${EXE}").
+			Run(ctx)
+		require.NoError(t, err)
+		data, err := target.Join("tool.exe").ReadFile()
+		require.NoError(t, err)
+		assert.Equal(t, raw, data)
+	})
+
+	t.Run("Unmatched member", func(t *testing.T) {
+		target := Path(t.TempDir())
+		err := InstallRelease(srv.URL+"/v1.0/tool_linux_amd64.tar.gz", target).
+			Member("bin/missing").
+			Run(ctx)
+		assert.ErrorContains(t, err, "bin/missing")
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		err := InstallRelease(srv.URL+"/v1.0/tool_linux_amd64.tar.gz", Path(t.TempDir())).
+			ExtractOptions(ExtractMaxSize(4)).
+			Run(ctx)
+		assert.ErrorIs(t, err, ErrArchiveLimit)
+		err = InstallRelease(srv.URL+"/v1.0/tool_linux_amd64.tar.gz", Path(t.TempDir())).
+			ExtractOptions(ExtractMaxEntries(1)).
+			Run(ctx)
+		assert.ErrorIs(t, err, ErrArchiveLimit)
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		target := Path(t.TempDir())
+		count := len(getRequested())
+		for _, install := range []*ReleaseInstall{
+			InstallRelease(srv.URL+"/v1.0/tool-raw", target).Name("${MODMAKE_MISSING_NAME?}"),
+			InstallRelease(srv.URL+"/v1.0/tool_linux_amd64.tar.gz", target).Member("${MODMAKE_MISSING_MEMBER?}"),
+			InstallRelease(srv.URL+"/v1.0/tool_linux_amd64.tar.gz", target).Executable("${MODMAKE_MISSING_EXE?}"),
+		} {
+			var err error
+			assert.NotPanics(t, func() {
+				err = install.Run(ctx)
+			})
+			assert.ErrorIs(t, err, ErrMissingVariable)
+		}
+		assert.Len(t, getRequested(), count, "Templates should be checked before downloading")
+	})
+}
+
+func TestStripComponents(t *testing.T) {
+	tests := map[string]struct {
+		name     string
+		n        int
+		expected string
+		ok       bool
+	}{
+		"No strip":    {name: "a/b/c", n: 0, expected: "a/b/c", ok: true},
+		"Strip one":   {name: "a/b/c", n: 1, expected: "b/c", ok: true},
+		"Strip all":   {name: "a/b/c", n: 3, ok: false},
+		"Leading dot": {name: "./a/b", n: 1, expected: "b", ok: true},
+		"Traversal":   {name: "../../etc/passwd", n: 0, expected: "etc/passwd", ok: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, ok := stripComponents(tc.name, tc.n)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	ustar := make([]byte, 512)
+	copy(ustar[257:], "ustar")
+	tests := map[string]struct {
+		url      string
+		content  []byte
+		expected ArchiveFormat
+	}{
+		"Extension":         {url: "https://example.com/tool.tar.xz", expected: ArchiveTarXz},
+		"Gzip content":      {url: "https://example.com/tool", content: []byte{0x1f, 0x8b, 0x08}, expected: ArchiveTarGz},
+		"Zstd content":      {url: "https://example.com/tool", content: []byte{0x28, 0xb5, 0x2f, 0xfd}, expected: ArchiveTarZst},
+		"Xz content":        {url: "https://example.com/tool", content: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, expected: ArchiveTarXz},
+		"Bzip2 content":     {url: "https://example.com/tool", content: []byte("BZh91AY"), expected: ArchiveTarBz2},
+		"Zip content":       {url: "https://example.com/tool", content: []byte("PK\x03\x04"), expected: ArchiveZip},
+		"Plain tar content": {url: "https://example.com/tool", content: ustar, expected: ArchiveTar},
+		"Raw content":       {url: "https://example.com/tool", content: []byte("#!/bin/sh\n"), expected: ArchiveRaw},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := Path(t.TempDir(), "download")
+			require.NoError(t, file.WriteFile(tc.content, 0644))
+			format, err := detectArchiveFormat(tc.url, file)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, format)
+		})
+	}
+}
@@ -4,19 +4,17 @@ import (
 	"context"
 	"fmt"
 	"regexp"
-	"sync"
 )
 
 const (
-	defaultLintVersion    = "latest"
-	linterV2VersionString = "github.com/golangci/golangci-lint/v2/cmd/golangci-lint@%s"
-	EnvLinterPath         = "MM_LINTER_PATH"
+	defaultLintVersion = "latest"
+	linterV2Package    = "github.com/golangci/golangci-lint/v2/cmd/golangci-lint"
+	linterToolName     = "golangci-lint"
+	EnvLinterPath      = "MM_LINTER_PATH"
 )
 
 var (
 	lintVersionPattern = regexp.MustCompile(`^(latest|v2\.\d+\.\d+)$`)
-	linterPath         string
-	linterInitOnce     sync.Once
 )
 
 // Linter provides a means to configure the linter in various ways.
@@ -27,6 +25,7 @@ type Linter struct {
 	disabledChecks []string
 	verbose        bool
 	otherArgs      []string
+	tools          *ToolManager
 }
 
 // Enable marks given check(s) as enabled.
@@ -79,12 +78,14 @@ func (lint *Linter) Arg(args ...string) *Linter {
 }
 
 func (lint *Linter) Run(ctx context.Context) error {
-	linterInitOnce.Do(func() {
-		linterPath = F("${" + EnvLinterPath + "}")
-		if len(linterPath) == 0 {
-			linterPath = Go().GOBIN().Join("golangci-lint").String()
+	linterPath := F("${" + EnvLinterPath + "}")
+	if len(linterPath) == 0 {
+		if lint.tools != nil {
+			linterPath = lint.tools.Path(linterToolName).String()
+		} else {
+			linterPath = Go().GOBIN().Join(linterToolName).String()
 		}
-	})
+	}
 
 	ctx, log := WithGroup(ctx, "lint")
 	var args []string
@@ -119,7 +120,7 @@ func (b *Build) LintLatest() *Linter {
 // Lint will enable code linting support for this module, and returns the Linter for further configuration.
 // The version parameter must be either "latest" or a string that can describe a version of a go module.
 //
-// The default path for invoking the linter is "${GOBIN}/golangci-lint", using [GoTools.GOBIN].
+// The linter is installed with the Build's [ToolManager], so the resolved version is recorded in the tool lockfile, and reused in later runs.
 // The environment variable MM_LINTER_PATH (see EnvLinterPath) can be used to override the invocation path to the golangci-lint executable.
 func (b *Build) Lint(version string) *Linter {
 	lintVersion := defaultLintVersion
@@ -129,10 +130,10 @@ func (b *Build) Lint(version string) *Linter {
 	if !lintVersionPattern.MatchString(lintVersion) {
 		panic(fmt.Sprintf("invalid linter version %s", lintVersion))
 	}
-	lintVersion = fmt.Sprintf(linterV2VersionString, lintVersion)
-	installLinter := b.AddNewStep("install-linter", "Installs golangci-lint", Go().Install(lintVersion))
+	tools := b.ToolManager().GoTool(linterToolName, linterV2Package, lintVersion)
+	installLinter := b.AddNewStep("install-linter", "Installs golangci-lint", tools.InstallTool(linterToolName))
 	b.Tools().DependsOn(installLinter)
-	linter := new(Linter)
+	linter := &Linter{tools: tools}
 	lintStep := b.AddNewStep("lint", "Analyses code for quality issues", linter)
 	b.Test().DependsOn(lintStep)
 	lintStep.DependsOn(installLinter)
@@ -0,0 +1,118 @@
+package modmake
+
+import (
+	"context"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	fatMagic        = 0xcafebabe
+	fatHeaderSize   = 8
+	fatArchSize     = 20
+	alignArm64      = 14 // Page alignment of 16KiB, matching what lipo uses for arm64.
+	alignDefault    = 12 // Page alignment of 4KiB.
+	universalDarwin = "universal"
+)
+
+type fatInput struct {
+	path   PathString
+	cpu    macho.Cpu
+	subCpu uint32
+	size   int64
+	align  uint32
+	offset int64
+}
+
+// MachOUniversal creates a Task that merges thin Mach-O executables for different architectures into a single universal (fat) binary.
+// This is the equivalent of running 'lipo -create', but works on any host OS.
+// Each input must be a thin Mach-O file, and no two inputs may target the same CPU type.
+func MachOUniversal(output PathString, inputs ...PathString) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "universal binary")
+		if len(inputs) < 2 {
+			return log.WrapErr(errors.New("at least two thin Mach-O inputs are required"))
+		}
+		var (
+			thin []*fatInput
+			cpus = map[macho.Cpu]PathString{}
+		)
+		for _, in := range inputs {
+			f, err := macho.Open(in.String())
+			if err != nil {
+				return log.WrapErr(fmt.Errorf("input '%s' is not a thin Mach-O file: %w", in, err))
+			}
+			cpu, subCpu := f.Cpu, f.SubCpu
+			_ = f.Close()
+			if other, ok := cpus[cpu]; ok {
+				return log.WrapErr(fmt.Errorf("inputs '%s' and '%s' both target CPU type %s", other, in, cpu))
+			}
+			cpus[cpu] = in
+			fi, err := in.Stat()
+			if err != nil {
+				return log.WrapErr(err)
+			}
+			align := uint32(alignDefault)
+			if cpu == macho.CpuArm64 {
+				align = alignArm64
+			}
+			thin = append(thin, &fatInput{path: in, cpu: cpu, subCpu: subCpu, size: fi.Size(), align: align})
+		}
+
+		offset := int64(fatHeaderSize + fatArchSize*len(thin))
+		for _, in := range thin {
+			alignment := int64(1) << in.align
+			offset = (offset + alignment - 1) / alignment * alignment
+			in.offset = offset
+			offset += in.size
+		}
+		if offset > math.MaxUint32 {
+			return log.WrapErr(errors.New("universal binary would exceed the 4GiB limit of the fat format"))
+		}
+
+		out, err := output.OpenFile(os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+		if err != nil {
+			return log.WrapErr(fmt.Errorf("failed to create universal binary '%s': %w", output, err))
+		}
+		defer func() {
+			_ = out.Close()
+		}()
+		header := []uint32{fatMagic, uint32(len(thin))}
+		for _, in := range thin {
+			header = append(header, uint32(in.cpu), in.subCpu, uint32(in.offset), uint32(in.size), in.align)
+		}
+		if err := binary.Write(out, binary.BigEndian, header); err != nil {
+			return log.WrapErr(err)
+		}
+		for _, in := range thin {
+			if err := ctx.Err(); err != nil {
+				return log.WrapErr(err)
+			}
+			if err := copyAt(out, in.path, in.offset); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to write '%s' to universal binary: %w", in.path, err))
+			}
+		}
+		log.Info("Wrote universal binary '%s' with %d architectures", output, len(thin))
+		return nil
+	}
+}
+
+func copyAt(out *os.File, source PathString, offset int64) error {
+	in, err := source.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	if _, err := out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
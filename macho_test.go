@@ -0,0 +1,67 @@
+package modmake
+
+import (
+	"context"
+	"debug/macho"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachOUniversal(t *testing.T) {
+	tmp := Path(t.TempDir())
+	ctx := context.Background()
+	var thin []PathString
+	for _, arch := range []string{"amd64", "arm64"} {
+		output := tmp.Join("testingbuild_" + arch)
+		err := Go().Build(Go().ToModulePath("testingbuild")).
+			OS("darwin").
+			Arch(arch).
+			CgoEnabled(false).
+			StripDebugSymbols().
+			OutputFilename(output).
+			Run(ctx)
+		require.NoError(t, err)
+		thin = append(thin, output)
+	}
+
+	universal := tmp.Join("testingbuild")
+	require.NoError(t, MachOUniversal(universal, thin...).Run(ctx))
+
+	fat, err := macho.OpenFat(universal.String())
+	require.NoError(t, err)
+	defer func() {
+		_ = fat.Close()
+	}()
+	require.Len(t, fat.Arches, 2)
+	assert.Equal(t, macho.CpuAmd64, fat.Arches[0].Cpu)
+	assert.Equal(t, macho.CpuArm64, fat.Arches[1].Cpu)
+	for _, arch := range fat.Arches {
+		assert.Zero(t, arch.Offset%(1<<arch.Align), "Each architecture should be aligned")
+		assert.NotNil(t, arch.Segment("__TEXT"), "Each architecture should be a complete Mach-O file")
+	}
+	fi, err := universal.Stat()
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&0100, "Universal binary should be executable")
+
+	assert.Error(t, MachOUniversal(universal, thin[0], thin[0]).Run(ctx), "Duplicate CPU types should be rejected")
+	assert.Error(t, MachOUniversal(universal, thin[0]).Run(ctx), "A single input should be rejected")
+	assert.Error(t, MachOUniversal(universal, thin[0], Path("macho.go")).Run(ctx), "Non-Mach-O inputs should be rejected")
+}
+
+func TestAppBuild_UniversalDarwin(t *testing.T) {
+	a := NewAppBuild("testapp", "cmd/modmake", "1.0.0")
+	v := a.UniversalDarwin()
+	assert.Equal(t, "darwin_universal", v.variant)
+	assert.Equal(t, Path("build/testapp_darwin_universal/testapp"), v.buildOutput)
+	assert.Equal(t, Path("build/testapp_darwin_universal_arm64/testapp"), a.thinOutput(v, a.binaries()[0], "arm64"))
+	assert.NotNil(t, a.pkgTask(v), "Universal variant should be packaged by default")
+
+	b := NewBuild()
+	b.ImportApp(a)
+	_, ok := b.StepOk("testapp:build-testapp_darwin_universal")
+	assert.True(t, ok)
+	_, ok = b.StepOk("testapp:package-testapp_darwin_universal")
+	assert.True(t, ok)
+}
@@ -1,6 +1,8 @@
 package modmake
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"os"
@@ -168,6 +170,8 @@ func (p PathString) Chdir() error {
 }
 
 // CopyTo copies the contents of the file referenced by this PathString to the file referenced by other, creating or truncating the file.
+// The permission bits of the source file are applied to the target file.
+// A read-only target, like one left by an earlier copy of a read-only source, is made writable by its owner before it's truncated.
 func (p PathString) CopyTo(other PathString) error {
 	in, err := p.Open()
 	if err != nil {
@@ -176,6 +180,15 @@ func (p PathString) CopyTo(other PathString) error {
 	defer func() {
 		_ = in.Close()
 	}()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if existing, err := other.Stat(); err == nil && existing.Mode().IsRegular() && existing.Mode().Perm()&0200 == 0 {
+		if err := os.Chmod(other.String(), existing.Mode().Perm()|0200); err != nil {
+			return err
+		}
+	}
 	out, err := other.Create()
 	if err != nil {
 		return err
@@ -187,7 +200,7 @@ func (p PathString) CopyTo(other PathString) error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return out.Chmod(fi.Mode().Perm())
 }
 
 // Cat will - assuming the PathString points to a file - read all data from the file and return it as a byte slice.
@@ -223,6 +236,28 @@ func (p PathString) WriteFile(data []byte, perm os.FileMode) error {
 	return os.WriteFile(p.String(), data, perm)
 }
 
+// SHA256 returns the hex encoded SHA-256 checksum of the file's content.
+func (p PathString) SHA256() (string, error) {
+	f, err := p.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	sum, _, err := hashSHA256(f)
+	return sum, err
+}
+
+func hashSHA256(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
 // Ext returns the file name extension used by path.
 // The extension is the suffix beginning at the final dot in the final element of path; it is empty if there is no dot.
 func (p PathString) Ext() string {
@@ -2,7 +2,9 @@ package modmake
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -64,3 +66,31 @@ func TestPathString_Join(t *testing.T) {
 		})
 	}
 }
+
+func TestPathString_SHA256(t *testing.T) {
+	file := Path(t.TempDir(), "file.txt")
+	assert.NoError(t, file.WriteFile([]byte("hello\n"), 0644))
+	sum, err := file.SHA256()
+	assert.NoError(t, err)
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", sum)
+
+	_, err = file.Dir().Join("missing").SHA256()
+	assert.Error(t, err)
+}
+
+func TestPathString_CopyTo_ReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Read-only permission bits aren't applied the same way on Windows")
+	}
+	tmp := Path(t.TempDir())
+	source, target := tmp.Join("source.txt"), tmp.Join("target.txt")
+	require.NoError(t, source.WriteFile([]byte("first"), 0444))
+	require.NoError(t, source.CopyTo(target))
+	require.NoError(t, source.CopyTo(target), "Copying over a read-only target should succeed")
+	data, err := target.ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+	fi, err := target.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, 0444, int(fi.Mode().Perm()))
+}
@@ -0,0 +1,290 @@
+package modmake
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/saylorsolutions/modmake/assert"
+)
+
+// DefaultPGOProfile is the name of the profile that 'go build -pgo=auto' looks for in the main package directory.
+const DefaultPGOProfile = "default.pgo"
+
+// PGOLoadFunc produces a Runner that writes a CPU profile to the given location, usually by applying load to a running service.
+//
+//	// Collect a 30-second profile from a service exposing net/http/pprof.
+//	workflow.Load(func(profile PathString) Runner {
+//		return Download("http://localhost:6060/debug/pprof/profile?seconds=30", profile)
+//	})
+type PGOLoadFunc func(profile PathString) Runner
+
+type pgoBenchmark struct {
+	pkg, pattern string
+}
+
+// PGOWorkflow collects CPU profiles from benchmarks and load tasks, and merges them into a profile used for profile-guided optimization.
+// Use NewPGOWorkflow to create a PGOWorkflow, and [GoBuild.PGO] or [AppBuild.PGO] to build with the collected profile.
+//
+// See https://go.dev/doc/pgo for more information about profile-guided optimization.
+type PGOWorkflow struct {
+	mainPath   string
+	output     PathString
+	benchmarks []pgoBenchmark
+	loads      []PGOLoadFunc
+	benchTime  string
+	count      int
+}
+
+// NewPGOWorkflow creates a PGOWorkflow for the given main package.
+// The merged profile is written to default.pgo in the main package directory by default, so it will be picked up by 'go build -pgo=auto'.
+// If mainPath is not prefixed with the module name, then it will be added.
+func NewPGOWorkflow(mainPath string) *PGOWorkflow {
+	assert.NotEmpty(&mainPath)
+	if !strings.HasPrefix(mainPath, Go().ModuleName()) {
+		mainPath = Go().ToModulePath(mainPath)
+	}
+	return &PGOWorkflow{
+		mainPath: mainPath,
+		output:   modulePathDir(mainPath).Join(DefaultPGOProfile),
+		count:    1,
+	}
+}
+
+// Output overrides the location of the merged profile.
+func (p *PGOWorkflow) Output(profile PathString) *PGOWorkflow {
+	if len(profile) == 0 {
+		panic("empty profile path")
+	}
+	p.output = profile
+	return p
+}
+
+// Profile returns the location where the merged profile will be written.
+func (p *PGOWorkflow) Profile() PathString {
+	return p.output
+}
+
+// Benchmark adds benchmarks matching the pattern in the given package to the collection workflow.
+func (p *PGOWorkflow) Benchmark(pkg, pattern string) *PGOWorkflow {
+	assert.NotEmpty(&pkg)
+	assert.NotEmpty(&pattern)
+	p.benchmarks = append(p.benchmarks, pgoBenchmark{pkg: pkg, pattern: pattern})
+	return p
+}
+
+// Load adds a PGOLoadFunc to the collection workflow, which is useful for collecting a profile from a representative workload.
+func (p *PGOWorkflow) Load(load PGOLoadFunc) *PGOWorkflow {
+	if load == nil {
+		panic("nil load function")
+	}
+	p.loads = append(p.loads, load)
+	return p
+}
+
+// BenchTime sets the -benchtime flag used when running benchmarks, like "5s" or "1000x".
+func (p *PGOWorkflow) BenchTime(benchTime string) *PGOWorkflow {
+	p.benchTime = strings.TrimSpace(benchTime)
+	return p
+}
+
+// Count sets the number of times each benchmark is run, which improves the stability of profiles and reported deltas.
+func (p *PGOWorkflow) Count(count int) *PGOWorkflow {
+	if count < 1 {
+		panic("count must be at least 1")
+	}
+	p.count = count
+	return p
+}
+
+func (p *PGOWorkflow) benchCommand(b pgoBenchmark, testBinary PathString, extraArgs ...string) *Command {
+	cmd := Go().Command("test", "-run=^$", "-bench="+b.pattern, "-count="+strconv.Itoa(p.count), "-o", testBinary.String()).
+		OptArg(len(p.benchTime) > 0, "-benchtime="+p.benchTime).
+		Arg(extraArgs...).
+		Arg(b.pkg)
+	return cmd
+}
+
+// Collect creates a Task that runs each benchmark and load function to collect CPU profiles, and merges them into the output profile.
+func (p *PGOWorkflow) Collect() Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "pgo collect")
+		if len(p.benchmarks) == 0 && len(p.loads) == 0 {
+			return log.WrapErr(errors.New("no benchmarks or load functions configured"))
+		}
+		tmp, err := os.MkdirTemp("", "modmake-pgo-*")
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		tmpDir := Path(tmp)
+		defer func() {
+			_ = tmpDir.RemoveAll()
+		}()
+
+		var profiles []string
+		for i, b := range p.benchmarks {
+			profile := tmpDir.Join(fmt.Sprintf("bench-%d.pprof", i))
+			log.Info("Collecting CPU profile from benchmarks '%s' in '%s'", b.pattern, b.pkg)
+			cmd := p.benchCommand(b, tmpDir.Join(fmt.Sprintf("bench-%d.test", i)), "-cpuprofile="+profile.String())
+			if err := cmd.Run(ctx); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to run benchmarks '%s' in '%s': %w", b.pattern, b.pkg, err))
+			}
+			profiles = append(profiles, profile.String())
+		}
+		for i, load := range p.loads {
+			profile := tmpDir.Join(fmt.Sprintf("load-%d.pprof", i))
+			log.Info("Collecting CPU profile from load task %d", i+1)
+			if err := load(profile).Run(ctx); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to run load task %d: %w", i+1, err))
+			}
+			if !profile.IsFile() {
+				return log.WrapErr(fmt.Errorf("load task %d did not write a profile to '%s'", i+1, profile))
+			}
+			profiles = append(profiles, profile.String())
+		}
+
+		var merged bytes.Buffer
+		if err := Go().Command("tool", "pprof", "-proto").Arg(profiles...).Silent().Stdout(&merged).Run(ctx); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to merge profiles: %w", err))
+		}
+		if err := p.output.Dir().MkdirAll(0755); err != nil {
+			return log.WrapErr(err)
+		}
+		if err := p.output.WriteFile(merged.Bytes(), 0644); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to write profile '%s': %w", p.output, err))
+		}
+		log.Info("Merged %d profile(s) into '%s'", len(profiles), p.output)
+		return nil
+	}
+}
+
+// PGODelta is a before and after measurement, comparing a build without profile-guided optimization to one with it.
+type PGODelta struct {
+	Without, With float64
+}
+
+// Percent returns the relative change from Without to With as a percentage.
+func (d PGODelta) Percent() float64 {
+	if d.Without == 0 {
+		return 0
+	}
+	return (d.With - d.Without) / d.Without * 100
+}
+
+// PGOReport describes the effect of profile-guided optimization on binary size and benchmark results.
+type PGOReport struct {
+	// BinarySize is the size of the main package executable in bytes.
+	BinarySize PGODelta
+	// Benchmarks maps benchmark names to their average ns/op.
+	Benchmarks map[string]PGODelta
+}
+
+// Compare builds the main package and runs configured benchmarks both with and without the collected profile, and reports the differences.
+// The profile must already exist, usually by running [PGOWorkflow.Collect] first.
+func (p *PGOWorkflow) Compare(ctx context.Context) (*PGOReport, error) {
+	if !p.output.IsFile() {
+		return nil, fmt.Errorf("profile '%s' does not exist", p.output)
+	}
+	profile, err := p.output.Abs()
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "modmake-pgo-*")
+	if err != nil {
+		return nil, err
+	}
+	tmpDir := Path(tmp)
+	defer func() {
+		_ = tmpDir.RemoveAll()
+	}()
+
+	report := &PGOReport{Benchmarks: map[string]PGODelta{}}
+	for i, pgo := range []string{"off", profile.String()} {
+		exe := tmpDir.Join(fmt.Sprintf("main-%d", i))
+		if err := Go().Build(p.mainPath).OutputFilename(exe).PGO(pgo).Silent().Run(ctx); err != nil {
+			return nil, fmt.Errorf("failed to build '%s' with -pgo=%s: %w", p.mainPath, pgo, err)
+		}
+		fi, err := exe.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			report.BinarySize.Without = float64(fi.Size())
+		} else {
+			report.BinarySize.With = float64(fi.Size())
+		}
+
+		for j, b := range p.benchmarks {
+			var output bytes.Buffer
+			cmd := p.benchCommand(b, tmpDir.Join(fmt.Sprintf("bench-%d-%d.test", i, j)), "-pgo="+pgo).Silent().Stdout(&output)
+			if err := cmd.Run(ctx); err != nil {
+				return nil, fmt.Errorf("failed to run benchmarks '%s' in '%s' with -pgo=%s: %w", b.pattern, b.pkg, pgo, err)
+			}
+			for name, nsPerOp := range parseBenchmarks(&output) {
+				delta := report.Benchmarks[name]
+				if i == 0 {
+					delta.Without = nsPerOp
+				} else {
+					delta.With = nsPerOp
+				}
+				report.Benchmarks[name] = delta
+			}
+		}
+	}
+	return report, nil
+}
+
+// Report creates a Task that runs [PGOWorkflow.Compare] and logs the results.
+func (p *PGOWorkflow) Report() Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "pgo report")
+		report, err := p.Compare(ctx)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Binary size: %.0f -> %.0f bytes (%+.2f%%)", report.BinarySize.Without, report.BinarySize.With, report.BinarySize.Percent())
+		names := keySlice(report.Benchmarks)
+		sort.Strings(names)
+		for _, name := range names {
+			delta := report.Benchmarks[name]
+			log.Info("%s: %.2f -> %.2f ns/op (%+.2f%%)", name, delta.Without, delta.With, delta.Percent())
+		}
+		return nil
+	}
+}
+
+var benchmarkLine = regexp.MustCompile(`^(Benchmark\S+)\s+\d+\s+([0-9.]+) ns/op`)
+
+// parseBenchmarks reads 'go test -bench' output, returning the average ns/op of each benchmark.
+func parseBenchmarks(r io.Reader) map[string]float64 {
+	var (
+		sums   = map[string]float64{}
+		counts = map[string]int{}
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := benchmarkLine.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		nsPerOp, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		sums[match[1]] += nsPerOp
+		counts[match[1]]++
+	}
+	results := map[string]float64{}
+	for name, sum := range sums {
+		results[name] = sum / float64(counts[name])
+	}
+	return results
+}
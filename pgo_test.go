@@ -0,0 +1,74 @@
+package modmake
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBenchmarks(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+pkg: github.com/saylorsolutions/modmake/testingtest
+BenchmarkEasy-8   	1000000000	         1.000 ns/op
+BenchmarkEasy-8   	1000000000	         2.000 ns/op
+BenchmarkHard-8   	      1000	      1500 ns/op	     128 B/op	       2 allocs/op
+PASS
+`
+	results := parseBenchmarks(strings.NewReader(output))
+	assert.Equal(t, map[string]float64{
+		"BenchmarkEasy-8": 1.5,
+		"BenchmarkHard-8": 1500,
+	}, results)
+}
+
+func TestPGODelta_Percent(t *testing.T) {
+	assert.InDelta(t, -10.0, PGODelta{Without: 200, With: 180}.Percent(), 0.001)
+	assert.Zero(t, PGODelta{With: 180}.Percent())
+}
+
+func TestGoBuild_PGO(t *testing.T) {
+	assert.Contains(t, Go().Build("./cmd/app").PGO("auto").Args(), "-pgo=auto")
+	a := NewAppBuild("testapp", "cmd/modmake", "1.0.0").PGO("default.pgo")
+	assert.Contains(t, a.goBuild(a.Variant("linux", "amd64")).Args(), "-pgo=default.pgo")
+}
+
+func TestPGOWorkflow(t *testing.T) {
+	workflow := NewPGOWorkflow("testingbuild")
+	assert.Equal(t, Go().ModuleRoot().Join("testingbuild", DefaultPGOProfile), workflow.Profile())
+
+	profile := Path(t.TempDir(), "merged.pgo")
+	var loadCalled bool
+	workflow.
+		Output(profile).
+		Benchmark("./testingtest", "Easy").
+		BenchTime("1000x").
+		Load(func(loadProfile PathString) Runner {
+			loadCalled = true
+			// Reuse a benchmark profile as a stand-in for a real workload.
+			return Go().Command("test", "-run=^$", "-bench=Easy", "-benchtime=1000x", "-o", loadProfile.Dir().Join("load.test").String(), "-cpuprofile="+loadProfile.String(), "./testingtest")
+		})
+	ctx := context.Background()
+	require.NoError(t, workflow.Collect().Run(ctx))
+	assert.True(t, loadCalled)
+	assert.True(t, profile.IsFile(), "Merged profile should be written")
+
+	assert.Error(t, NewPGOWorkflow("testingbuild").Output(profile).Collect().Run(ctx), "Collect should fail without benchmarks or load functions")
+	if testing.Short() {
+		t.Skip("Comparing builds with a new profile rebuilds the standard library")
+	}
+
+	report, err := workflow.Compare(ctx)
+	require.NoError(t, err)
+	assert.NotZero(t, report.BinarySize.Without)
+	assert.NotZero(t, report.BinarySize.With)
+	require.Len(t, report.Benchmarks, 1)
+	for name, delta := range report.Benchmarks {
+		assert.True(t, strings.HasPrefix(name, "BenchmarkEasy"))
+		assert.NotZero(t, delta.Without)
+		assert.NotZero(t, delta.With)
+	}
+}
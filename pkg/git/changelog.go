@@ -0,0 +1,235 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/saylorsolutions/modmake"
+)
+
+const (
+	recordSeparator = "\x1e"
+	fieldSeparator  = "\x1f"
+)
+
+var (
+	conventionalPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	breakingPattern     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s*(.+)$`)
+
+	// DefaultNotesTemplate is the template used to render release notes in [Changelog.Render] if no other template is given.
+	DefaultNotesTemplate = template.Must(template.New("releaseNotes").Parse(defaultNotesTemplate))
+)
+
+const defaultNotesTemplate = `## {{ .NextVersion.Tag }} ({{ .Date.Format "2006-01-02" }})
+{{- define "commit" }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Subject }} ({{ .ShortHash }})
+{{- end }}
+{{- if .Breaking }}
+
+### Breaking Changes
+{{ range .Breaking }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .BreakingNote }} ({{ .ShortHash }})
+{{- end }}
+{{- end }}
+{{- if .Features }}
+
+### Features
+{{ range .Features }}{{ template "commit" . }}{{ end }}
+{{- end }}
+{{- if .Fixes }}
+
+### Bug Fixes
+{{ range .Fixes }}{{ template "commit" . }}{{ end }}
+{{- end }}
+`
+
+// Commit is a single commit parsed according to the Conventional Commits specification.
+// See https://www.conventionalcommits.org for details.
+type Commit struct {
+	Hash         string
+	Type         string // Type is the lower case commit type, like "feat" or "fix". This is empty if the commit message is not conventional.
+	Scope        string
+	Subject      string
+	Body         string
+	Breaking     bool
+	BreakingNote string // BreakingNote describes the breaking change. This defaults to the Subject if no BREAKING CHANGE footer is given.
+}
+
+// ShortHash returns the first 7 characters of the commit hash.
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// IsConventional returns true if the commit message followed the Conventional Commits specification.
+func (c Commit) IsConventional() bool {
+	return len(c.Type) > 0
+}
+
+// ParseCommit parses a commit message into a Commit.
+// Messages that don't follow the Conventional Commits specification will have an empty Type, with the first line of the message as the Subject.
+func ParseCommit(hash, message string) Commit {
+	message = strings.TrimSpace(message)
+	header, body, _ := strings.Cut(message, "\n")
+	c := Commit{
+		Hash:    hash,
+		Subject: strings.TrimSpace(header),
+		Body:    strings.TrimSpace(body),
+	}
+	groups := conventionalPattern.FindStringSubmatch(c.Subject)
+	if groups == nil {
+		return c
+	}
+	c.Type = strings.ToLower(groups[1])
+	c.Scope = strings.TrimSpace(groups[2])
+	c.Breaking = groups[3] == "!"
+	c.Subject = strings.TrimSpace(groups[4])
+	if note := breakingPattern.FindStringSubmatch(c.Body); note != nil {
+		c.Breaking = true
+		c.BreakingNote = strings.TrimSpace(note[1])
+	}
+	if c.Breaking && len(c.BreakingNote) == 0 {
+		c.BreakingNote = c.Subject
+	}
+	return c
+}
+
+// Changelog groups commits made since the previous release.
+type Changelog struct {
+	PreviousTag     string  // PreviousTag is the most recent version tag reachable from HEAD, or empty if there isn't one.
+	PreviousVersion Version // PreviousVersion is the version parsed from PreviousTag, or 0.0.0 if there isn't one.
+	NextVersion     Version // NextVersion is the computed version for the next release.
+	Date            time.Time
+	Commits         []Commit // Commits are all commits since the previous release, newest first.
+	Breaking        []Commit // Breaking are commits that introduce breaking changes. These also appear in their type's group.
+	Features        []Commit // Features are commits with the "feat" type.
+	Fixes           []Commit // Fixes are commits with the "fix" type.
+	Other           []Commit // Other are all other commits.
+}
+
+// NewChangelog builds a Changelog from commits made since the most recent version tag reachable from HEAD.
+func NewChangelog(ctx context.Context) (*Changelog, error) {
+	prev, err := LatestTag(ctx)
+	if err != nil && !errors.Is(err, ErrNoVersionTags) {
+		return nil, err
+	}
+	rangeSpec := "HEAD"
+	if len(prev.Name) > 0 {
+		rangeSpec = prev.Name + "..HEAD"
+	}
+	commits, err := LogCommits(ctx, rangeSpec)
+	if err != nil {
+		return nil, err
+	}
+	log := newChangelog(commits)
+	log.PreviousTag = prev.Name
+	log.PreviousVersion = prev.Version
+	log.NextVersion = log.nextVersion()
+	return log, nil
+}
+
+func newChangelog(commits []Commit) *Changelog {
+	log := &Changelog{
+		Date:    time.Now(),
+		Commits: commits,
+	}
+	for _, c := range commits {
+		if c.Breaking {
+			log.Breaking = append(log.Breaking, c)
+		}
+		switch c.Type {
+		case "feat":
+			log.Features = append(log.Features, c)
+		case "fix":
+			log.Fixes = append(log.Fixes, c)
+		default:
+			log.Other = append(log.Other, c)
+		}
+	}
+	return log
+}
+
+// ReleaseType returns the ReleaseType called for by the commits.
+// Breaking changes call for a major release, features call for a minor release, and anything else calls for a patch release.
+func (c *Changelog) ReleaseType() ReleaseType {
+	switch {
+	case len(c.Breaking) > 0:
+		return MajorRelease
+	case len(c.Features) > 0:
+		return MinorRelease
+	default:
+		return PatchRelease
+	}
+}
+
+// nextVersion computes the next version based on the commits.
+// If there are no commits, then the previous version is returned.
+func (c *Changelog) nextVersion() Version {
+	if len(c.Commits) == 0 {
+		return c.PreviousVersion
+	}
+	next, _ := c.PreviousVersion.Bump(c.ReleaseType(), "")
+	return next
+}
+
+// Render executes the template with this Changelog as its data, writing the output to w.
+// If tmpl is nil, then DefaultNotesTemplate will be used.
+func (c *Changelog) Render(w io.Writer, tmpl *template.Template) error {
+	if tmpl == nil {
+		tmpl = DefaultNotesTemplate
+	}
+	return tmpl.Execute(w, c)
+}
+
+// WriteReleaseNotes creates a Task that renders release notes for commits since the previous release to the output file.
+// If tmpl is nil, then DefaultNotesTemplate will be used.
+func WriteReleaseNotes(output modmake.PathString, tmpl *template.Template) modmake.Task {
+	return func(ctx context.Context) error {
+		ctx, log := modmake.WithGroup(ctx, "release notes")
+		changes, err := NewChangelog(ctx)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		f, err := output.Create()
+		if err != nil {
+			return log.WrapErr(fmt.Errorf("failed to create release notes file: %w", err))
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		if err := changes.Render(f, tmpl); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to render release notes: %w", err))
+		}
+		log.Info("Wrote release notes for %s to '%s'", changes.NextVersion.Tag(), output)
+		return nil
+	}
+}
+
+// LogCommits parses commits in the given revision range, like "v1.0.0..HEAD", newest first.
+func LogCommits(ctx context.Context, revisionRange string) ([]Commit, error) {
+	output, err := ExecOutputCtx(ctx, "log", "--format=%H"+fieldSeparator+"%B"+recordSeparator, revisionRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log: %w", err)
+	}
+	var commits []Commit
+	for _, record := range strings.Split(output, recordSeparator) {
+		record = strings.TrimSpace(record)
+		if len(record) == 0 {
+			continue
+		}
+		hash, message, found := strings.Cut(record, fieldSeparator)
+		if !found {
+			return nil, fmt.Errorf("unexpected git log record format: %q", record)
+		}
+		commits = append(commits, ParseCommit(strings.TrimSpace(hash), message))
+	}
+	return commits, nil
+}
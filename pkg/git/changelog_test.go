@@ -0,0 +1,120 @@
+package git
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommit(t *testing.T) {
+	tests := map[string]struct {
+		message  string
+		expected Commit
+	}{
+		"Not conventional": {
+			message:  "Fixed some stuff\n\nWith details",
+			expected: Commit{Subject: "Fixed some stuff", Body: "With details"},
+		},
+		"Feature": {
+			message:  "feat: add a thing",
+			expected: Commit{Type: "feat", Subject: "add a thing"},
+		},
+		"Scoped fix": {
+			message:  "fix(tar): handle empty archives\n\nCloses #12",
+			expected: Commit{Type: "fix", Scope: "tar", Subject: "handle empty archives", Body: "Closes #12"},
+		},
+		"Upper case type": {
+			message:  "FEAT: shouting",
+			expected: Commit{Type: "feat", Subject: "shouting"},
+		},
+		"Breaking marker": {
+			message:  "feat(api)!: remove Build.Old",
+			expected: Commit{Type: "feat", Scope: "api", Subject: "remove Build.Old", Breaking: true, BreakingNote: "remove Build.Old"},
+		},
+		"Breaking footer": {
+			message: "refactor: rework steps\n\nSome details.\n\nBREAKING CHANGE: Step.Run requires a context",
+			expected: Commit{
+				Type:         "refactor",
+				Subject:      "rework steps",
+				Body:         "Some details.\n\nBREAKING CHANGE: Step.Run requires a context",
+				Breaking:     true,
+				BreakingNote: "Step.Run requires a context",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			tc.expected.Hash = "0123456789abcdef"
+			assert.Equal(t, tc.expected, ParseCommit("0123456789abcdef", tc.message))
+		})
+	}
+}
+
+func TestChangelog_NextVersion(t *testing.T) {
+	prev := Version{Major: 1, Minor: 2, Patch: 3}
+	tests := map[string]struct {
+		messages []string
+		expected string
+	}{
+		"No commits":       {expected: "1.2.3"},
+		"Only chores":      {messages: []string{"chore: tidy", "Merge branch 'x'"}, expected: "1.2.4"},
+		"Fixes":            {messages: []string{"fix: a bug", "docs: explain"}, expected: "1.2.4"},
+		"Features":         {messages: []string{"fix: a bug", "feat: a thing"}, expected: "1.3.0"},
+		"Breaking changes": {messages: []string{"feat: a thing", "fix!: change behavior"}, expected: "2.0.0"},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var commits []Commit
+			for _, msg := range tc.messages {
+				commits = append(commits, ParseCommit("abc", msg))
+			}
+			log := newChangelog(commits)
+			log.PreviousVersion = prev
+			assert.Equal(t, tc.expected, log.nextVersion().String())
+		})
+	}
+}
+
+func TestChangelog_Render(t *testing.T) {
+	log := newChangelog([]Commit{
+		ParseCommit("1111111111", "feat(tar): support zstd"),
+		ParseCommit("2222222222", "fix: handle empty input"),
+		ParseCommit("3333333333", "feat!: drop Go 1.20\n\nBREAKING CHANGE: Go 1.21 is now required"),
+		ParseCommit("4444444444", "chore: tidy modules"),
+	})
+	log.PreviousVersion = Version{Major: 1}
+	log.NextVersion = log.nextVersion()
+	log.Date = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf strings.Builder
+	require.NoError(t, log.Render(&buf, nil))
+	expected := `## v2.0.0 (2024-03-01)
+
+### Breaking Changes
+
+- Go 1.21 is now required (3333333)
+
+### Features
+
+- **tar:** support zstd (1111111)
+- drop Go 1.20 (3333333)
+
+### Bug Fixes
+
+- handle empty input (2222222)
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	custom := template.Must(template.New("custom").Parse(`{{ .NextVersion }}:{{ range .Other }} {{ .Subject }}{{ end }}`))
+	require.NoError(t, log.Render(&buf, custom))
+	assert.Equal(t, "2.0.0: tidy modules", buf.String())
+}
@@ -4,5 +4,7 @@ This includes:
   - Updating submodules with "--init" or "--remote"
   - Cloning repositories
   - Getting commit and branch details
+  - Generating changelogs and release notes from Conventional Commits
+  - Listing, bumping, and creating semantic version tags
 */
 package git
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidVersion = errors.New("invalid semantic version")
+
+	versionPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+)
+
+// Version is a parsed semantic version, as described by https://semver.org.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	Build               string
+}
+
+// ParseVersion parses a semantic version string, optionally prefixed with "v".
+func ParseVersion(version string) (Version, error) {
+	groups := versionPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if groups == nil {
+		return Version{}, fmt.Errorf("%w: '%s'", ErrInvalidVersion, version)
+	}
+	var (
+		v   Version
+		err error
+	)
+	if v.Major, err = strconv.Atoi(groups[1]); err != nil {
+		return Version{}, fmt.Errorf("%w: '%s': %v", ErrInvalidVersion, version, err)
+	}
+	if v.Minor, err = strconv.Atoi(groups[2]); err != nil {
+		return Version{}, fmt.Errorf("%w: '%s': %v", ErrInvalidVersion, version, err)
+	}
+	if v.Patch, err = strconv.Atoi(groups[3]); err != nil {
+		return Version{}, fmt.Errorf("%w: '%s': %v", ErrInvalidVersion, version, err)
+	}
+	v.Prerelease = groups[4]
+	v.Build = groups[5]
+	return v, nil
+}
+
+// String returns the version without a "v" prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + v.Prerelease
+	}
+	if len(v.Build) > 0 {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Tag returns the version with a "v" prefix, as is conventional for Go module tags.
+func (v Version) Tag() string {
+	return "v" + v.String()
+}
+
+// BumpMajor returns the next major version, resetting the minor and patch versions.
+// If this is a pre-release of a major version, like 2.0.0-rc.1, then the pre-release and build data is removed instead.
+func (v Version) BumpMajor() Version {
+	if len(v.Prerelease) > 0 && v.Minor == 0 && v.Patch == 0 {
+		return Version{Major: v.Major}
+	}
+	return Version{Major: v.Major + 1}
+}
+
+// BumpMinor returns the next minor version, resetting the patch version.
+// If this is a pre-release of a minor version, like 1.3.0-rc.1, then the pre-release and build data is removed instead.
+func (v Version) BumpMinor() Version {
+	if len(v.Prerelease) > 0 && v.Patch == 0 {
+		return Version{Major: v.Major, Minor: v.Minor}
+	}
+	return Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// BumpPatch returns the next patch version.
+// If this is a pre-release version, then the pre-release and build data is removed instead, since the release version is greater than the pre-release.
+func (v Version) BumpPatch() Version {
+	if len(v.Prerelease) > 0 {
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// Compare returns -1 if v is lower precedence than other, 1 if v is higher precedence, or 0 if they are equal.
+// Build metadata is not considered, as prescribed by the specification.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt(v.Minor, other.Minor)
+	case v.Patch != other.Patch:
+		return compareInt(v.Patch, other.Patch)
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case len(a) == 0:
+		// A release has higher precedence than a pre-release.
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			return compareInt(aNum, bNum)
+		case aErr == nil:
+			// Numeric identifiers have lower precedence.
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+	return compareInt(len(aParts), len(bParts))
+}
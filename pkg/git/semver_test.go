@@ -0,0 +1,89 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected Version
+		invalid  bool
+	}{
+		"Plain":               {input: "1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}},
+		"Tag prefix":          {input: "v1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}},
+		"Pre-release":         {input: "v1.0.0-rc.1", expected: Version{Major: 1, Prerelease: "rc.1"}},
+		"Build metadata":      {input: "1.0.0+build.5", expected: Version{Major: 1, Build: "build.5"}},
+		"Pre-release & build": {input: "1.0.0-alpha+sha.1", expected: Version{Major: 1, Prerelease: "alpha", Build: "sha.1"}},
+		"Missing patch":       {input: "v1.2", invalid: true},
+		"Leading zero":        {input: "01.2.3", invalid: true},
+		"Module tag":          {input: "cmd/modmake/v1.2.3", invalid: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			v, err := ParseVersion(tc.input)
+			if tc.invalid {
+				assert.ErrorIs(t, err, ErrInvalidVersion)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestVersion_Bump(t *testing.T) {
+	v := Version{Major: 1, Minor: 2, Patch: 3}
+	assert.Equal(t, "2.0.0", v.BumpMajor().String())
+	assert.Equal(t, "1.3.0", v.BumpMinor().String())
+	assert.Equal(t, "1.2.4", v.BumpPatch().String())
+	assert.Equal(t, "v1.2.3", v.Tag())
+
+	pre := Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}
+	assert.Equal(t, "1.2.3", pre.BumpPatch().String(), "Bumping a pre-release patch should release it")
+	assert.Equal(t, "1.3.0", pre.BumpMinor().String())
+	assert.Equal(t, "2.0.0", pre.BumpMajor().String())
+
+	preMinor := Version{Major: 1, Minor: 3, Prerelease: "rc.1", Build: "abc"}
+	assert.Equal(t, "1.3.0", preMinor.BumpMinor().String(), "Bumping a pre-release minor should release it")
+	assert.Equal(t, "2.0.0", preMinor.BumpMajor().String())
+
+	preMajor := Version{Major: 2, Prerelease: "rc.1"}
+	assert.Equal(t, "2.0.0", preMajor.BumpMajor().String(), "Bumping a pre-release major should release it")
+	assert.Equal(t, "2.0.0", preMajor.BumpMinor().String())
+	assert.Equal(t, "2.0.0", preMajor.BumpPatch().String())
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// Ordered by precedence, as given in the semver specification.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, err := ParseVersion(ordered[i])
+		require.NoError(t, err)
+		b, err := ParseVersion(ordered[i+1])
+		require.NoError(t, err)
+		assert.Equalf(t, -1, a.Compare(b), "%s should be less than %s", a, b)
+		assert.Equalf(t, 1, b.Compare(a), "%s should be greater than %s", b, a)
+		assert.Equal(t, 0, a.Compare(a))
+	}
+	a, _ := ParseVersion("1.0.0+build.1")
+	b, _ := ParseVersion("1.0.0+build.2")
+	assert.Equal(t, 0, a.Compare(b), "Build metadata should be ignored")
+}
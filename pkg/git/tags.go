@@ -0,0 +1,208 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/saylorsolutions/modmake"
+)
+
+var (
+	ErrNoVersionTags = errors.New("no version tags found")
+	ErrTagExists     = errors.New("tag already exists")
+	// ErrPrereleaseRegression is returned when a new pre-release version would have lower precedence than the current version.
+	ErrPrereleaseRegression = errors.New("pre-release version would have lower precedence")
+)
+
+// ReleaseType specifies which part of a Version should be incremented for a release.
+type ReleaseType int
+
+const (
+	PatchRelease ReleaseType = iota // PatchRelease increments the patch version.
+	MinorRelease                    // MinorRelease increments the minor version.
+	MajorRelease                    // MajorRelease increments the major version.
+	PreRelease                      // PreRelease increments a pre-release version. See [Version.BumpPrerelease].
+)
+
+func (r ReleaseType) String() string {
+	switch r {
+	case PatchRelease:
+		return "patch"
+	case MinorRelease:
+		return "minor"
+	case MajorRelease:
+		return "major"
+	case PreRelease:
+		return "pre-release"
+	default:
+		return fmt.Sprintf("ReleaseType(%d)", int(r))
+	}
+}
+
+// BumpPrerelease returns the next pre-release of the version that the base ReleaseType would produce, using the given identifier, like "rc".
+// A base of PreRelease is treated as PatchRelease.
+//
+//   - 1.2.3 with PatchRelease becomes 1.2.4-rc.1
+//   - 1.2.3 with MinorRelease becomes 1.3.0-rc.1
+//   - 1.2.4-rc.1 with PatchRelease becomes 1.2.4-rc.2
+//   - 1.3.0-rc.1 with MinorRelease becomes 1.3.0-rc.2
+//   - 1.2.4-rc.1 with MinorRelease becomes 1.3.0-rc.1
+//   - 1.2.4-beta.3 with PatchRelease becomes 1.2.4-rc.1
+//
+// ErrPrereleaseRegression is returned if the result would have lower precedence than v, like changing 1.2.4-rc.2 to 1.2.4-alpha.1.
+func (v Version) BumpPrerelease(base ReleaseType, id string) (Version, error) {
+	if len(id) == 0 {
+		id = "rc"
+	}
+	if base == PreRelease {
+		base = PatchRelease
+	}
+	next, _ := v.Bump(base, "")
+	if len(v.Prerelease) == 0 || next.Compare(Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}) != 0 {
+		next.Prerelease = id + ".1"
+		return next, nil
+	}
+	next.Prerelease = id + ".1"
+	prefix, num, found := strings.Cut(v.Prerelease, ".")
+	if found && prefix == id {
+		var n int
+		if _, err := fmt.Sscanf(num, "%d", &n); err == nil && fmt.Sprintf("%d", n) == num {
+			next.Prerelease = fmt.Sprintf("%s.%d", id, n+1)
+		}
+	}
+	if next.Compare(v) <= 0 {
+		return Version{}, fmt.Errorf("%w: %s would follow %s", ErrPrereleaseRegression, next, v)
+	}
+	return next, nil
+}
+
+// Bump returns the next Version for the given ReleaseType.
+// If a preID is given, or the ReleaseType is PreRelease, then a pre-release is created with [Version.BumpPrerelease].
+// An error is only returned for pre-releases.
+func (v Version) Bump(release ReleaseType, preID string) (Version, error) {
+	if release == PreRelease || len(preID) > 0 {
+		return v.BumpPrerelease(release, preID)
+	}
+	switch release {
+	case MajorRelease:
+		return v.BumpMajor(), nil
+	case MinorRelease:
+		return v.BumpMinor(), nil
+	default:
+		return v.BumpPatch(), nil
+	}
+}
+
+// VersionTag is a git tag that references a semantic version.
+type VersionTag struct {
+	Name    string
+	Version Version
+}
+
+// ParseVersionTags parses tag names as semantic versions, and returns them in ascending order of precedence.
+// Tags that are not semantic versions are ignored.
+func ParseVersionTags(tags []string) []VersionTag {
+	var versions []VersionTag
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		v, err := ParseVersion(tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, VersionTag{Name: tag, Version: v})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Version.Compare(versions[j].Version) < 0
+	})
+	return versions
+}
+
+// Tags lists all semantic version tags in the repository in ascending order of precedence.
+func Tags(ctx context.Context) ([]VersionTag, error) {
+	return listVersionTags(ctx, "tag", "--list")
+}
+
+// LatestTag returns the highest semantic version tag that is reachable from HEAD.
+// If there are no version tags reachable from HEAD, then ErrNoVersionTags is returned.
+func LatestTag(ctx context.Context) (VersionTag, error) {
+	tags, err := listVersionTags(ctx, "tag", "--list", "--merged", "HEAD")
+	if err != nil {
+		return VersionTag{}, err
+	}
+	if len(tags) == 0 {
+		return VersionTag{}, ErrNoVersionTags
+	}
+	return tags[len(tags)-1], nil
+}
+
+func listVersionTags(ctx context.Context, subcmd string, args ...string) ([]VersionTag, error) {
+	output, err := ExecOutputCtx(ctx, subcmd, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return ParseVersionTags(strings.Split(output, "\n")), nil
+}
+
+// NextVersion returns the next version for the given ReleaseType, based on the latest version tag reachable from HEAD.
+// If there are no version tags, then the next version is calculated from 0.0.0.
+// A PreRelease is based on the ReleaseType called for by the commits since the latest tag, as reported by [Changelog.ReleaseType].
+// So 'feat' commits after v1.2.3 produce v1.3.0-rc.1.
+func NextVersion(ctx context.Context, release ReleaseType, preID string) (Version, error) {
+	if release == PreRelease {
+		changes, err := NewChangelog(ctx)
+		if err != nil {
+			return Version{}, err
+		}
+		return changes.PreviousVersion.BumpPrerelease(changes.ReleaseType(), preID)
+	}
+	latest, err := LatestTag(ctx)
+	if err != nil && !errors.Is(err, ErrNoVersionTags) {
+		return Version{}, err
+	}
+	return latest.Version.Bump(release, preID)
+}
+
+// CreateTag creates a Task that adds an annotated tag for the version at HEAD.
+// The tag name will be [Version.Tag].
+// The Task will fail if the tag already exists.
+func CreateTag(version Version, message string) modmake.Task {
+	return func(ctx context.Context) error {
+		ctx, log := modmake.WithGroup(ctx, "create tag")
+		tag := version.Tag()
+		if len(strings.TrimSpace(message)) == 0 {
+			message = "Release " + tag
+		}
+		existing, err := ExecOutputCtx(ctx, "tag", "--list", tag)
+		if err != nil {
+			return log.WrapErr(fmt.Errorf("failed to list tags: %w", err))
+		}
+		if len(existing) > 0 {
+			return log.WrapErr(fmt.Errorf("%w: %s", ErrTagExists, tag))
+		}
+		if err := Exec("tag", "--annotate", tag, "--message", message).Run(ctx); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to create tag '%s': %w", tag, err))
+		}
+		log.Info("Created tag %s", tag)
+		return nil
+	}
+}
+
+// TagRelease creates a Task that tags HEAD with the next version for the given ReleaseType.
+// The Task will fail if there are uncommitted changes, as reported by [AssertNoChanges].
+// If a preID is given, or the ReleaseType is PreRelease, then a pre-release is tagged. See [NextVersion].
+func TagRelease(release ReleaseType, preID, message string) modmake.Task {
+	return func(ctx context.Context) error {
+		ctx, log := modmake.WithGroup(ctx, "tag release")
+		if err := AssertNoChanges().Run(ctx); err != nil {
+			return log.WrapErr(fmt.Errorf("refusing to tag a %s release: %w", release, err))
+		}
+		next, err := NextVersion(ctx, release, preID)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		return CreateTag(next, message).Run(ctx)
+	}
+}
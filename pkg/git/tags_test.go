@@ -0,0 +1,166 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/saylorsolutions/modmake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throwawayRepo creates a new git repository in a temp directory, and changes the working directory to it for the duration of the test.
+func throwawayRepo(t *testing.T) modmake.PathString {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "Modmake Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Modmake Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := modmake.Path(t.TempDir())
+	require.NoError(t, dir.Chdir())
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+	require.NoError(t, Exec("init", "--quiet").Run(context.Background()))
+	return dir
+}
+
+func commitFile(t *testing.T, name, content, message string) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, modmake.Path(name).WriteFile([]byte(content), 0600))
+	require.NoError(t, Exec("add", name).Run(ctx))
+	require.NoError(t, Exec("commit", "--quiet", "-m", message).Run(ctx))
+}
+
+func TestParseVersionTags(t *testing.T) {
+	tags := ParseVersionTags([]string{"v1.10.0", "v1.2.0", "latest", "v1.2.0-rc.1", "cmd/modmake/v2.0.0", "", "v0.9.9"})
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	assert.Equal(t, []string{"v0.9.9", "v1.2.0-rc.1", "v1.2.0", "v1.10.0"}, names)
+}
+
+func TestVersion_BumpPrerelease(t *testing.T) {
+	tests := map[string]struct {
+		input, id, expected string
+		base                ReleaseType
+		regression          bool
+	}{
+		"From release":           {input: "1.2.3", id: "rc", expected: "1.2.4-rc.1"},
+		"Increment":              {input: "1.2.4-rc.1", id: "rc", expected: "1.2.4-rc.2"},
+		"Change identifier":      {input: "1.2.4-beta.3", id: "rc", expected: "1.2.4-rc.1"},
+		"Default identifier":     {input: "1.2.4-rc.9", expected: "1.2.4-rc.10"},
+		"Non-numeric counter":    {input: "1.2.4-rc.x", id: "rc", regression: true},
+		"Minor from release":     {input: "1.2.3", id: "rc", base: MinorRelease, expected: "1.3.0-rc.1"},
+		"Major from release":     {input: "1.2.3", id: "beta", base: MajorRelease, expected: "2.0.0-beta.1"},
+		"Minor increment":        {input: "1.3.0-rc.1", id: "rc", base: MinorRelease, expected: "1.3.0-rc.2"},
+		"Minor from patch pre":   {input: "1.2.4-rc.1", id: "rc", base: MinorRelease, expected: "1.3.0-rc.1"},
+		"Patch from minor pre":   {input: "1.3.0-rc.1", id: "rc", base: PatchRelease, expected: "1.3.0-rc.2"},
+		"PreRelease base":        {input: "1.2.3", base: PreRelease, expected: "1.2.4-rc.1"},
+		"Lower identifier":       {input: "1.2.4-rc.2", id: "alpha", regression: true},
+		"Lower minor identifier": {input: "1.3.0-rc.2", id: "beta", base: MinorRelease, regression: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			v, err := ParseVersion(tc.input)
+			require.NoError(t, err)
+			next, err := v.BumpPrerelease(tc.base, tc.id)
+			if tc.regression {
+				assert.ErrorIs(t, err, ErrPrereleaseRegression)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, next.String())
+			assert.Equal(t, 1, next.Compare(v))
+		})
+	}
+}
+
+func TestVersion_BumpRelease(t *testing.T) {
+	v := Version{Major: 1, Minor: 2, Patch: 3}
+	next, err := v.Bump(MinorRelease, "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0", next.String())
+	next, err = v.Bump(MinorRelease, "rc")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0-rc.1", next.String(), "A preID should create a pre-release of the release type")
+	next, err = next.Bump(MinorRelease, "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0", next.String())
+}
+
+func TestTagRelease(t *testing.T) {
+	ctx := context.Background()
+	throwawayRepo(t)
+	commitFile(t, "README.md", "# Test", "chore: initial commit")
+
+	_, err := LatestTag(ctx)
+	assert.ErrorIs(t, err, ErrNoVersionTags)
+
+	require.NoError(t, TagRelease(MinorRelease, "", "").Run(ctx))
+	latest, err := LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", latest.Name)
+	msg, err := ExecOutputCtx(ctx, "tag", "--list", "--format=%(contents:subject)", "v0.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "Release v0.1.0", msg, "Tag should be annotated")
+
+	commitFile(t, "README.md", "# Test\nMore", "fix: content")
+	require.NoError(t, TagRelease(PreRelease, "rc", "First candidate").Run(ctx))
+	require.NoError(t, TagRelease(PreRelease, "rc", "Second candidate").Run(ctx), "Tagging the same commit again should bump the pre-release")
+	require.NoError(t, TagRelease(PatchRelease, "", "").Run(ctx))
+	tags, err := Tags(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	assert.Equal(t, []string{"v0.1.0", "v0.1.1-rc.1", "v0.1.1-rc.2", "v0.1.1"}, names)
+
+	commitFile(t, "feature.txt", "feature", "feat: new feature")
+	require.NoError(t, TagRelease(PreRelease, "rc", "").Run(ctx))
+	latest, err = LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0-rc.1", latest.Name, "Pre-releases should be based on the commits since the last tag")
+	commitFile(t, "fix.txt", "fix", "fix: a fix")
+	require.NoError(t, TagRelease(PreRelease, "rc", "").Run(ctx))
+	latest, err = LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0-rc.2", latest.Name)
+	assert.ErrorIs(t, TagRelease(PreRelease, "alpha", "").Run(ctx), ErrPrereleaseRegression)
+
+	assert.ErrorIs(t, CreateTag(Version{Minor: 1, Patch: 1}, "").Run(ctx), ErrTagExists)
+
+	require.NoError(t, modmake.Path("README.md").WriteFile([]byte("dirty"), 0600))
+	assert.Error(t, TagRelease(MajorRelease, "", "").Run(ctx), "Should refuse to tag a dirty tree")
+	_, err = ExecOutputCtx(ctx, "rev-parse", "v1.0.0")
+	assert.Error(t, err, "Tag should not have been created")
+}
+
+func TestNewChangelog(t *testing.T) {
+	ctx := context.Background()
+	throwawayRepo(t)
+	commitFile(t, "a.txt", "a", "feat: first feature")
+	require.NoError(t, CreateTag(Version{Major: 1}, "").Run(ctx))
+	commitFile(t, "b.txt", "b", "fix(b): a fix\n\nWith a body")
+	commitFile(t, "c.txt", "c", "feat: another feature")
+
+	log, err := NewChangelog(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", log.PreviousTag)
+	assert.Equal(t, "1.1.0", log.NextVersion.String())
+	require.Len(t, log.Commits, 2)
+	assert.Equal(t, "another feature", log.Commits[0].Subject, "Commits should be newest first")
+	require.Len(t, log.Fixes, 1)
+	assert.Equal(t, "b", log.Fixes[0].Scope)
+	assert.Equal(t, "With a body", log.Fixes[0].Body)
+	assert.Len(t, log.Fixes[0].Hash, 40)
+}
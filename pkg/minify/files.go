@@ -1,6 +1,8 @@
 package minify
 
 import (
+	"context"
+	"fmt"
 	mm "github.com/saylorsolutions/modmake"
 	"path/filepath"
 )
@@ -11,6 +13,30 @@ func (mini *Minifier) MapFile(source mm.PathString) *Minifier {
 	return mini
 }
 
+// MapFileSet will attempt to minify each file selected by the [mm.FileSet], and add embed entries into the configured mapping file.
+// The FileSet is evaluated when the minify step runs, so its files don't need to exist until then.
+func (mini *Minifier) MapFileSet(set *mm.FileSet) *Minifier {
+	if set == nil {
+		panic("nil file set")
+	}
+	mini.tasks = mini.tasks.Then(mm.Task(func(ctx context.Context) error {
+		files, err := set.Files()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no files selected in '%s'", set.Root())
+		}
+		for _, file := range files {
+			if err := mini.minAndMapFile(file).Run(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return mini
+}
+
 // MapJSBundle will attempt to bundle one or more JS files into one bundle file, and add embed entries into the configured mapping file.
 // Source files should have the ".js" file extension.
 func (mini *Minifier) MapJSBundle(bundleName string, sources ...mm.PathString) *Minifier {
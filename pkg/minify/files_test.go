@@ -55,6 +55,13 @@ func setupWorkingDirectory(t *testing.T) *workingDir {
 	}
 }
 
+// testTools returns a ToolManager that installs to a temp directory, so tests don't write to the repository.
+func testTools(t *testing.T) *mm.ToolManager {
+	t.Helper()
+	tmp := mm.Path(t.TempDir())
+	return mm.NewToolManager().BinDir(tmp.Join("bin")).LockFile(tmp.Join("modmake-tools.lock"))
+}
+
 func getFilesWithExt(t *testing.T, dir mm.PathString, ext string) []string {
 	t.Helper()
 	var files []string
@@ -83,6 +90,7 @@ func TestMinifier_MapFile(t *testing.T) {
 	mappingFile := work.tmp.Join("assets.go")
 	assetDir := work.tmp.Join("content")
 	minifier, err := minify.New(mappingFile, "content",
+		minify.Tools(testTools(t)),
 		minify.Version("latest"),
 		minify.HashDigits(6),
 		minify.ClearBeforeWrite(),
@@ -138,3 +146,22 @@ func TestMinifier_MapFile(t *testing.T) {
 	assert.Contains(t, strContent, "Testsvg []byte")
 	assert.Contains(t, strContent, "TestsvgName = \"")
 }
+
+func TestMinifier_MapFileSet(t *testing.T) {
+	work := setupWorkingDirectory(t)
+	mappingFile := work.tmp.Join("assets.go")
+	assetDir := work.tmp.Join("content")
+	minifier, err := minify.New(mappingFile, "content", minify.Tools(testTools(t)), minify.HashDigits(6))
+	require.NoError(t, err)
+
+	b := mm.NewBuild()
+	minifier.Apply(b)
+	minifier.MapFileSet(mm.NewFileSet(work.tmp).Include("*.css", "*.svg").Exclude("test2.css"))
+	require.NoError(t, b.ExecuteErr("minify"))
+
+	cssFiles := getFilesWithExt(t, assetDir, ".css")
+	require.Len(t, cssFiles, 1)
+	assert.True(t, regexp.MustCompile(`^test-[a-f0-9]{6}\.css$`).MatchString(filepath.Base(cssFiles[0])))
+	require.Len(t, getFilesWithExt(t, assetDir, ".svg"), 1)
+	assert.Empty(t, getFilesWithExt(t, assetDir, ".js"))
+}
@@ -22,17 +22,17 @@ import (
 )
 
 const (
-	hashSeparator = "-"
-	minifyV2Path  = "github.com/tdewolff/minify/v2/cmd/minify@%s"
+	hashSeparator    = "-"
+	minifyV2Package  = "github.com/tdewolff/minify/v2/cmd/minify"
+	minifierToolName = "minify"
 	// EnvMinifyPath defines an environment variable that can be used to override the invocation path of minify.
 	EnvMinifyPath = "MM_MINIFY_PATH"
 )
 
 var (
 	minifyVersionPattern = regexp.MustCompile(`^(latest|v2\.\d+\.\d+)$`)
-	minifierPath         string
-	minifierInitOnce     sync.Once
-	minifierInstallOnce  sync.Once
+	defaultTools         *mm.ToolManager
+	defaultToolsOnce     sync.Once
 
 	//go:embed mappingFile.got
 	mappingTemplateText string
@@ -60,6 +60,7 @@ type Minifier struct {
 	minifyVersion     string
 	clearBeforeWrite  bool
 	packageName       string
+	tools             *mm.ToolManager
 	tasks             mm.Task
 }
 
@@ -90,6 +91,19 @@ func Version(version string) ConfigFunc {
 	}
 }
 
+// Tools installs minify with the given ToolManager, like the one returned by [mm.Build.ToolManager].
+// By default, minify is installed with a ToolManager created with [mm.NewToolManager], which is shared by all Minifiers.
+// Either way, the resolved version is recorded in the tool lockfile, and the EnvMinifyPath environment variable still takes precedence if it's set.
+func Tools(tools *mm.ToolManager) ConfigFunc {
+	return func(mini *Minifier) error {
+		if tools == nil {
+			return errors.New("nil tool manager")
+		}
+		mini.tools = tools
+		return nil
+	}
+}
+
 // PackageName sets the package name used in the mapping file.
 // The default when no package is specified is the parent directory name.
 func PackageName(packageName string) ConfigFunc {
@@ -147,7 +161,11 @@ func New(mappingFile mm.PathString, assetDirName string, configFuncs ...ConfigFu
 		}
 		mini.packageName = dir.Base().String()
 	}
-	mini.tasks = installTask(mini.minifyVersion)
+	if mini.tools == nil {
+		mini.tools = sharedTools()
+	}
+	mini.tools.GoTool(minifierToolName, minifyV2Package, mini.minifyVersion)
+	mini.tasks = mini.tools.InstallTool(minifierToolName)
 	if mini.clearBeforeWrite {
 		mini.tasks = mini.tasks.Then(mm.WithoutContext(func() error {
 			return assetDir.RemoveAll()
@@ -174,10 +192,6 @@ func New(mappingFile mm.PathString, assetDirName string, configFuncs ...ConfigFu
 		}
 		return nil
 	}))
-	minifierInitOnce.Do(func() {
-		defaultPath := mm.Go().GOBIN().Join("minify").String()
-		minifierPath = mm.F(fmt.Sprintf("${%s:%s}", EnvMinifyPath, defaultPath))
-	})
 	return mini, nil
 }
 
@@ -367,7 +381,7 @@ func (mini *Minifier) minifyFile(ctx context.Context, out io.Writer, source mm.P
 }
 
 func (mini *Minifier) getMinifiedContent(ctx context.Context, out io.Writer, source mm.PathString) error {
-	err := mm.Exec(minifierPath).Stdout(out).
+	err := mm.Exec(mini.minifierPath()).Stdout(out).
 		Arg(mini.singleFileArgs()...).
 		TrailingArg(source.String()).
 		LogGroup("minify").
@@ -378,6 +392,13 @@ func (mini *Minifier) getMinifiedContent(ctx context.Context, out io.Writer, sou
 	return nil
 }
 
+func (mini *Minifier) minifierPath() string {
+	if path := os.Getenv(EnvMinifyPath); len(path) > 0 {
+		return path
+	}
+	return mini.tools.Path(minifierToolName).String()
+}
+
 func (mini *Minifier) Run(ctx context.Context) error {
 	return mm.Print("Minifying files").Then(mini.tasks).Finally(func(terr error) error {
 		var err error
@@ -398,15 +419,12 @@ func (mini *Minifier) Apply(b *mm.Build) {
 	b.Generate().DependsOnRunner("minify", "Minifies web asssets", mini)
 }
 
-func installTask(version string) mm.Task {
-	return func(ctx context.Context) error {
-		var err error
-		minifierInstallOnce.Do(func() {
-			ctx, _ = mm.WithGroup(ctx, "install-minify")
-			err = mm.Go().Install(fmt.Sprintf(minifyV2Path, version)).Run(ctx)
-		})
-		return err
-	}
+// sharedTools returns the ToolManager used when one isn't given with Tools, so all Minifiers share the same tool directory and lockfile.
+func sharedTools() *mm.ToolManager {
+	defaultToolsOnce.Do(func() {
+		defaultTools = mm.NewToolManager()
+	})
+	return defaultTools
 }
 
 func embedSymbolFromSource(source mm.PathString) (string, error) {
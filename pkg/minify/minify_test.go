@@ -40,6 +40,13 @@ func setupWorkingDirectory(t *testing.T) *workingDir {
 	}
 }
 
+// testTools returns a ToolManager that installs to a temp directory, so tests don't write to the repository.
+func testTools(t *testing.T) *mm.ToolManager {
+	t.Helper()
+	tmp := mm.Path(t.TempDir())
+	return mm.NewToolManager().BinDir(tmp.Join("bin")).LockFile(tmp.Join("modmake-tools.lock"))
+}
+
 func getJSFiles(t *testing.T, dir mm.PathString) []string {
 	t.Helper()
 	var jsFiles []string
@@ -67,6 +74,7 @@ func TestMinify(t *testing.T) {
 	work := setupWorkingDirectory(t)
 	mappingFile := work.tmp.Join("mapping")
 	minifier, err := New(mappingFile, "assets",
+		Tools(testTools(t)),
 		Version("latest"),
 		HashDigits(6),
 		ClearBeforeWrite(),
@@ -0,0 +1,11 @@
+/*
+Package provenance provides support for recording how build artifacts were produced.
+
+Provenance is expressed as an [in-toto statement] with a [SLSA provenance] predicate, and may optionally be signed with a local ed25519 key.
+Signed statements are written as a [DSSE envelope], which is what most supply-chain policy tools expect to verify.
+
+[in-toto statement]: https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md
+[SLSA provenance]: https://slsa.dev/spec/v1.0/provenance
+[DSSE envelope]: https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+*/
+package provenance
@@ -0,0 +1,304 @@
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"time"
+
+	mm "github.com/saylorsolutions/modmake"
+	"github.com/saylorsolutions/modmake/pkg/git"
+)
+
+// Recorder collects details about a build and produces a provenance [Statement] for its artifacts.
+// Use New to create a Recorder, then configure it before calling [Recorder.Write] in a step that runs after packaging.
+type Recorder struct {
+	buildType    string
+	builderID    string
+	started      time.Time
+	artifacts    []mm.PathString
+	artifactDirs []mm.PathString
+	goBuilds     []*mm.GoBuild
+	params       map[string]string
+	envKeys      []string
+	signingKey   mm.PathString
+}
+
+// New creates a new Recorder using the [DefaultBuildType], and the current time as the build start time.
+func New() *Recorder {
+	return &Recorder{
+		buildType: DefaultBuildType,
+		builderID: "modmake",
+		started:   time.Now().UTC(),
+		params:    map[string]string{},
+	}
+}
+
+// BuildType overrides the build type URI recorded in the statement.
+func (r *Recorder) BuildType(buildType string) *Recorder {
+	if len(buildType) > 0 {
+		r.buildType = buildType
+	}
+	return r
+}
+
+// BuilderID sets the identity of the builder, typically a URI identifying the CI system or host that ran the build.
+// Defaults to "modmake".
+func (r *Recorder) BuilderID(id string) *Recorder {
+	if len(id) > 0 {
+		r.builderID = id
+	}
+	return r
+}
+
+// Artifact adds one or more files as subjects of the statement.
+func (r *Recorder) Artifact(files ...mm.PathString) *Recorder {
+	r.artifacts = append(r.artifacts, files...)
+	return r
+}
+
+// ArtifactDir adds every regular file in the given directory tree as a subject of the statement.
+// Subject names are relative to the directory.
+// Typically, this would be [mm.DistPath].
+func (r *Recorder) ArtifactDir(dir mm.PathString) *Recorder {
+	r.artifactDirs = append(r.artifactDirs, dir)
+	return r
+}
+
+// GoBuild records the arguments of a [mm.GoBuild] as an external parameter of the build.
+func (r *Recorder) GoBuild(gb *mm.GoBuild) *Recorder {
+	if gb != nil {
+		r.goBuilds = append(r.goBuilds, gb)
+	}
+	return r
+}
+
+// Param records an arbitrary named parameter of the build.
+func (r *Recorder) Param(key, value string) *Recorder {
+	r.params[key] = value
+	return r
+}
+
+// EnvParam records the value of one or more environment variables as parameters of the build, like those set with 'modmake -e'.
+func (r *Recorder) EnvParam(keys ...string) *Recorder {
+	r.envKeys = append(r.envKeys, keys...)
+	return r
+}
+
+// SignWith specifies a PEM encoded ed25519 private key file used to sign the statement.
+// When set, [Recorder.Write] outputs a DSSE [Envelope] instead of a plain [Statement].
+// See [GenerateKey] to create a key pair.
+func (r *Recorder) SignWith(privateKeyFile mm.PathString) *Recorder {
+	r.signingKey = privateKeyFile
+	return r
+}
+
+// Statement produces the provenance [Statement] from the current state of the build.
+// The output path, if not empty, will be excluded from the statement's subjects.
+func (r *Recorder) Statement(ctx context.Context, output mm.PathString) (*Statement, error) {
+	subjects, err := r.subjects(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("no artifacts found to attest")
+	}
+
+	external := map[string]any{}
+	if steps := mm.InvokedSteps(ctx); len(steps) > 0 {
+		external["steps"] = steps
+	}
+	if len(r.goBuilds) > 0 {
+		var builds [][]string
+		for _, gb := range r.goBuilds {
+			builds = append(builds, gb.Args())
+		}
+		external["goBuilds"] = builds
+	}
+	if len(r.params) > 0 {
+		external["parameters"] = r.params
+	}
+	if len(r.envKeys) > 0 {
+		env := mm.Environment()
+		values := map[string]string{}
+		for _, key := range r.envKeys {
+			values[key] = env[key]
+		}
+		external["environment"] = values
+	}
+
+	internal := map[string]any{}
+	goVersion, err := goVersion()
+	if err != nil {
+		return nil, err
+	}
+	internal["goVersion"] = goVersion
+
+	deps, err := sourceDependency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := time.Now().UTC()
+	started := r.started
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType:            r.buildType,
+				ExternalParameters:   external,
+				InternalParameters:   internal,
+				ResolvedDependencies: deps,
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: r.builderID},
+				Metadata: Metadata{
+					StartedOn:  &started,
+					FinishedOn: &finished,
+				},
+			},
+		},
+	}, nil
+}
+
+// Write creates a Task that writes the provenance statement to the output file.
+// If a signing key has been specified with [Recorder.SignWith], then a signed DSSE envelope is written instead.
+func (r *Recorder) Write(output mm.PathString) mm.Task {
+	return func(ctx context.Context) error {
+		ctx, log := mm.WithGroup(ctx, "provenance")
+		statement, err := r.Statement(ctx, output)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		var doc any = statement
+		if len(r.signingKey) > 0 {
+			key, err := LoadPrivateKey(r.signingKey)
+			if err != nil {
+				return log.WrapErr(err)
+			}
+			env, err := Sign(statement, key)
+			if err != nil {
+				return log.WrapErr(err)
+			}
+			doc = env
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if err := output.Dir().MkdirAll(0755); err != nil {
+			return log.WrapErr(err)
+		}
+		if err := output.WriteFile(data, 0644); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to write provenance file '%s': %w", output, err))
+		}
+		log.Info("Wrote provenance for %d artifact(s) to '%s'", len(statement.Subject), output)
+		return nil
+	}
+}
+
+func (r *Recorder) subjects(output mm.PathString) ([]Subject, error) {
+	var excluded string
+	if len(output) > 0 {
+		abs, err := output.Abs()
+		if err != nil {
+			return nil, err
+		}
+		excluded = abs.String()
+	}
+	seen := map[string]bool{}
+	var subjects []Subject
+	add := func(file mm.PathString, name string) error {
+		abs, err := file.Abs()
+		if err != nil {
+			return err
+		}
+		if abs.String() == excluded || seen[abs.String()] {
+			return nil
+		}
+		seen[abs.String()] = true
+		digest, err := file.SHA256()
+		if err != nil {
+			return err
+		}
+		subjects = append(subjects, Subject{Name: name, Digest: map[string]string{"sha256": digest}})
+		return nil
+	}
+	for _, file := range r.artifacts {
+		if err := add(file, file.ToSlash()); err != nil {
+			return nil, fmt.Errorf("failed to digest artifact '%s': %w", file, err)
+		}
+	}
+	for _, dir := range r.artifactDirs {
+		err := filepath.WalkDir(dir.String(), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := dir.Rel(mm.Path(path))
+			if err != nil {
+				return err
+			}
+			return add(mm.Path(path), rel.ToSlash())
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to digest artifacts in '%s': %w", dir, err)
+		}
+	}
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].Name < subjects[j].Name
+	})
+	return subjects, nil
+}
+
+func goVersion() (version string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to query go version: %v", r)
+		}
+	}()
+	return mm.Go().GetEnv("GOVERSION"), nil
+}
+
+// stripUserInfo removes credentials from a remote URL, so they aren't recorded in the provenance statement.
+// Remotes that aren't URLs, like "git@host:org/repo.git", are returned as-is.
+func stripUserInfo(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || len(u.Scheme) == 0 || u.User == nil {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
+
+func sourceDependency(ctx context.Context) ([]ResourceDescriptor, error) {
+	commit, err := git.ExecOutputCtx(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git commit hash: %w", err)
+	}
+	var uri string
+	// Prints the remote name if no URL is configured, rather than failing.
+	if remote, err := git.ExecOutputCtx(ctx, "ls-remote", "--get-url", "origin"); err == nil && len(remote) > 0 && remote != "origin" {
+		uri = "git+" + stripUserInfo(remote)
+	} else {
+		root, err := git.ExecOutputCtx(ctx, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate git repository root: %w", err)
+		}
+		uri = "git+file://" + filepath.ToSlash(root)
+	}
+	if branch, err := git.ExecOutputCtx(ctx, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && len(branch) > 0 && branch != "HEAD" {
+		uri += "@refs/heads/" + branch
+	}
+	return []ResourceDescriptor{
+		{URI: uri, Digest: map[string]string{"gitCommit": commit}},
+	}, nil
+}
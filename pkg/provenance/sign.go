@@ -0,0 +1,190 @@
+package provenance
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	mm "github.com/saylorsolutions/modmake"
+)
+
+var (
+	ErrInvalidKey       = errors.New("invalid ed25519 key")
+	ErrInvalidSignature = errors.New("signature verification failed")
+)
+
+// Envelope is a DSSE envelope holding a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a single signature of an Envelope's payload.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// GenerateKey creates a Task that writes a new ed25519 key pair as PEM files.
+// The private key is written in PKCS #8 form with 0600 permissions, and the public key in PKIX form.
+// Existing files will not be overwritten.
+func GenerateKey(privateKeyFile, publicKeyFile mm.PathString) mm.Task {
+	return func(ctx context.Context) error {
+		_, log := mm.WithGroup(ctx, "generate key")
+		if privateKeyFile.Exists() || publicKeyFile.Exists() {
+			return log.WrapErr(fmt.Errorf("refusing to overwrite existing key files '%s' and '%s'", privateKeyFile, publicKeyFile))
+		}
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		pubDER, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if err := privateKeyFile.WriteFile(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to write private key: %w", err))
+		}
+		if err := publicKeyFile.WriteFile(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to write public key: %w", err))
+		}
+		log.Info("Generated signing key '%s'", privateKeyFile)
+		return nil
+	}
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 ed25519 private key from the given file.
+func LoadPrivateKey(file mm.PathString) (ed25519.PrivateKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidKey, key)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX ed25519 public key from the given file.
+func LoadPublicKey(file mm.PathString) (ed25519.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported public key type %T", ErrInvalidKey, key)
+	}
+	return pub, nil
+}
+
+func readPEM(file mm.PathString) (*pem.Block, error) {
+	data, err := file.ReadFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file '%s': %w", file, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data found in '%s'", ErrInvalidKey, file)
+	}
+	return block, nil
+}
+
+// KeyID returns the identifier of a public key, which is the hex encoded SHA-256 digest of its PKIX encoding.
+func KeyID(pub ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign will wrap the Statement in a signed DSSE Envelope.
+func Sign(statement *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statement: %w", err)
+	}
+	pub, ok := key.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	keyID, err := KeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(key, pae(PayloadType, payload))
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{
+			{KeyID: keyID, Sig: base64.StdEncoding.EncodeToString(sig)},
+		},
+	}, nil
+}
+
+// Verify checks that the Envelope has a valid signature from the given public key, and returns the contained Statement.
+func Verify(env *Envelope, pub ed25519.PublicKey) (*Statement, error) {
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type '%s'", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	keyID, err := KeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	message := pae(env.PayloadType, payload)
+	verified := false
+	for _, sig := range env.Signatures {
+		if len(sig.KeyID) > 0 && sig.KeyID != keyID {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(pub, message, raw) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("failed to decode statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// pae implements the DSSE pre-authentication encoding.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
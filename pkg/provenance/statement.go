@@ -0,0 +1,64 @@
+package provenance
+
+import "time"
+
+const (
+	StatementType    = "https://in-toto.io/Statement/v1"                          // StatementType is the in-toto statement type used for provenance.
+	PredicateType    = "https://slsa.dev/provenance/v1"                           // PredicateType is the SLSA provenance predicate type.
+	PayloadType      = "application/vnd.in-toto+json"                             // PayloadType is the DSSE payload type of a signed statement.
+	DefaultBuildType = "https://github.com/saylorsolutions/modmake/provenance/v1" // DefaultBuildType is used when no other build type is specified.
+)
+
+// Statement is an in-toto statement that attests to the provenance of one or more subjects.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject identifies an artifact by name and digest.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is the SLSA provenance predicate.
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs to the build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	InternalParameters   map[string]any       `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor references an input to the build, like a source repository at a specific commit.
+type ResourceDescriptor struct {
+	URI    string            `json:"uri,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// RunDetails describes the environment that executed the build.
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the entity that executed the build.
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Metadata holds timing information about the build.
+type Metadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
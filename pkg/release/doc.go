@@ -0,0 +1,7 @@
+/*
+Package release provides support for publishing packaged artifacts as a release.
+
+Releases are published with the GitHub REST API, which is also supported by GitHub Enterprise and Gitea.
+The API base URL may be configured to target any compatible service.
+*/
+package release
@@ -0,0 +1,415 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mm "github.com/saylorsolutions/modmake"
+	"github.com/saylorsolutions/modmake/assert"
+)
+
+const (
+	DefaultBaseURL       = "https://api.github.com" // DefaultBaseURL is the API base URL for github.com.
+	DefaultTokenEnv      = "GITHUB_TOKEN"           // DefaultTokenEnv is the environment variable that holds the API token by default.
+	DefaultChecksumsFile = "checksums.txt"          // DefaultChecksumsFile is the name of the uploaded checksums asset by default.
+)
+
+var (
+	ErrNoToken = errors.New("no API token available")
+)
+
+// Publisher creates or updates a release for a tag, and uploads artifacts to it.
+// Use NewPublisher to create a Publisher.
+type Publisher struct {
+	client        *http.Client
+	baseURL       string
+	tokenEnv      string
+	owner, repo   string
+	tag           string
+	name          string
+	target        string
+	notes         string
+	notesFile     mm.PathString
+	draft         bool
+	prerelease    bool
+	artifactDir   mm.PathString
+	artifacts     []mm.PathString
+	checksumsFile string
+}
+
+// NewPublisher creates a new Publisher for the owner/repo repository and the given tag.
+// By default, all files in [mm.DistPath] will be uploaded along with a checksums file, and the release name will be the tag.
+func NewPublisher(owner, repo, tag string) *Publisher {
+	assert.NotEmpty(&owner)
+	assert.NotEmpty(&repo)
+	assert.NotEmpty(&tag)
+	return &Publisher{
+		client:        http.DefaultClient,
+		baseURL:       DefaultBaseURL,
+		tokenEnv:      DefaultTokenEnv,
+		owner:         owner,
+		repo:          repo,
+		tag:           tag,
+		name:          tag,
+		artifactDir:   mm.Path(mm.DistPath),
+		checksumsFile: DefaultChecksumsFile,
+	}
+}
+
+// BaseURL overrides the API base URL, which is useful for targeting GitHub Enterprise or Gitea.
+// For GitHub Enterprise this is usually "https://HOST/api/v3", and "https://HOST/api/v1" for Gitea.
+func (p *Publisher) BaseURL(baseURL string) *Publisher {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+	return p
+}
+
+// Client overrides the HTTP client used to make API requests.
+func (p *Publisher) Client(client *http.Client) *Publisher {
+	if client != nil {
+		p.client = client
+	}
+	return p
+}
+
+// TokenEnv sets the environment variable from which the API token is read.
+// Defaults to GITHUB_TOKEN.
+func (p *Publisher) TokenEnv(key string) *Publisher {
+	p.tokenEnv = key
+	return p
+}
+
+// Name sets the name of the release, which defaults to the tag.
+func (p *Publisher) Name(name string) *Publisher {
+	p.name = name
+	return p
+}
+
+// Target sets the commitish value used to create the tag if it doesn't already exist.
+// Unused if the tag already exists.
+func (p *Publisher) Target(commitish string) *Publisher {
+	p.target = commitish
+	return p
+}
+
+// Notes sets the release notes body.
+func (p *Publisher) Notes(notes string) *Publisher {
+	p.notes = notes
+	return p
+}
+
+// NotesFile sets a file to be read for the release notes body at the time the release is published.
+// This overrides the value set with [Publisher.Notes].
+func (p *Publisher) NotesFile(file mm.PathString) *Publisher {
+	p.notesFile = file
+	return p
+}
+
+// Draft marks the release as a draft.
+func (p *Publisher) Draft() *Publisher {
+	p.draft = true
+	return p
+}
+
+// Prerelease marks the release as a pre-release.
+func (p *Publisher) Prerelease() *Publisher {
+	p.prerelease = true
+	return p
+}
+
+// ArtifactDir sets the directory from which all files will be uploaded, which defaults to [mm.DistPath].
+// Files in subdirectories are included, and are uploaded with their base name.
+// Passing an empty string will disable uploading a directory.
+func (p *Publisher) ArtifactDir(dir mm.PathString) *Publisher {
+	p.artifactDir = dir
+	return p
+}
+
+// Artifact adds specific files to upload.
+func (p *Publisher) Artifact(files ...mm.PathString) *Publisher {
+	p.artifacts = append(p.artifacts, files...)
+	return p
+}
+
+// ChecksumsFile sets the name of the uploaded SHA-256 checksums asset.
+// Passing an empty string will disable uploading checksums.
+func (p *Publisher) ChecksumsFile(name string) *Publisher {
+	p.checksumsFile = name
+	return p
+}
+
+type releaseRequest struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+type releaseResponse struct {
+	ID        int64   `json:"id"`
+	TagName   string  `json:"tag_name"`
+	HTMLURL   string  `json:"html_url"`
+	UploadURL string  `json:"upload_url"`
+	Assets    []asset `json:"assets"`
+}
+
+type asset struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type upload struct {
+	name string
+	data func() (io.ReadCloser, int64, error)
+}
+
+// Publish returns a Task that creates or updates the release, and uploads all artifacts and checksums.
+// Existing assets with the same name as an uploaded artifact will be replaced.
+func (p *Publisher) Publish() mm.Task {
+	return func(ctx context.Context) error {
+		ctx, log := mm.WithGroup(ctx, "publish release")
+		token := mm.Environment()[strings.ToUpper(p.tokenEnv)]
+		if len(token) == 0 {
+			return log.WrapErr(fmt.Errorf("%w: set the %s environment variable", ErrNoToken, p.tokenEnv))
+		}
+		uploads, err := p.uploads()
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		notes := p.notes
+		if len(p.notesFile) > 0 {
+			data, err := p.notesFile.ReadFile()
+			if err != nil {
+				return log.WrapErr(fmt.Errorf("failed to read release notes: %w", err))
+			}
+			notes = string(data)
+		}
+		req := releaseRequest{
+			TagName:         p.tag,
+			TargetCommitish: p.target,
+			Name:            p.name,
+			Body:            notes,
+			Draft:           p.draft,
+			Prerelease:      p.prerelease,
+		}
+
+		rel, found, err := p.findRelease(ctx, token)
+		switch {
+		case err != nil:
+			return log.WrapErr(fmt.Errorf("failed to query release for tag '%s': %w", p.tag, err))
+		case !found:
+			log.Info("Creating release for tag '%s'", p.tag)
+			if _, err := p.call(ctx, token, http.MethodPost, p.repoURL("releases"), req, &rel); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to create release: %w", err))
+			}
+		default:
+			log.Info("Updating existing release for tag '%s'", p.tag)
+			req.TargetCommitish = ""
+			if _, err := p.call(ctx, token, http.MethodPatch, p.repoURL("releases", fmt.Sprintf("%d", rel.ID)), req, &rel); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to update release: %w", err))
+			}
+		}
+
+		existing := map[string]int64{}
+		for _, a := range rel.Assets {
+			existing[a.Name] = a.ID
+		}
+		uploadURL, _, _ := strings.Cut(rel.UploadURL, "{")
+		if len(uploadURL) == 0 {
+			return log.WrapErr(errors.New("release response did not include an upload URL"))
+		}
+		for _, up := range uploads {
+			if id, ok := existing[up.name]; ok {
+				log.Info("Replacing existing asset '%s'", up.name)
+				if _, err := p.call(ctx, token, http.MethodDelete, p.repoURL("releases", "assets", fmt.Sprintf("%d", id)), nil, nil); err != nil {
+					return log.WrapErr(fmt.Errorf("failed to delete existing asset '%s': %w", up.name, err))
+				}
+			}
+			if err := p.upload(ctx, token, uploadURL, up); err != nil {
+				return log.WrapErr(fmt.Errorf("failed to upload asset '%s': %w", up.name, err))
+			}
+			log.Info("Uploaded '%s'", up.name)
+		}
+		log.Info("Published release %s", rel.HTMLURL)
+		return nil
+	}
+}
+
+func (p *Publisher) uploads() ([]upload, error) {
+	files := append([]mm.PathString{}, p.artifacts...)
+	if len(p.artifactDir) > 0 {
+		if !p.artifactDir.IsDir() {
+			return nil, fmt.Errorf("artifact directory '%s' does not exist", p.artifactDir)
+		}
+		err := filepath.WalkDir(p.artifactDir.String(), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, mm.Path(path))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+	}
+	var (
+		uploads   []upload
+		names     = map[string]mm.PathString{}
+		checksums = map[string]string{}
+	)
+	for _, file := range files {
+		file := file
+		name := file.Base().String()
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("artifacts '%s' and '%s' would both be uploaded as '%s'", other, file, name)
+		}
+		names[name] = file
+		sum, err := file.SHA256()
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum artifact '%s': %w", file, err)
+		}
+		checksums[name] = sum
+		uploads = append(uploads, upload{
+			name: name,
+			data: func() (io.ReadCloser, int64, error) {
+				f, err := file.Open()
+				if err != nil {
+					return nil, 0, err
+				}
+				fi, err := f.Stat()
+				if err != nil {
+					_ = f.Close()
+					return nil, 0, err
+				}
+				return f, fi.Size(), nil
+			},
+		})
+	}
+	if len(uploads) == 0 {
+		return nil, errors.New("no artifacts to upload")
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].name < uploads[j].name
+	})
+	if len(p.checksumsFile) > 0 {
+		if _, ok := names[p.checksumsFile]; ok {
+			return nil, fmt.Errorf("checksums file '%s' conflicts with an artifact of the same name", p.checksumsFile)
+		}
+		var buf bytes.Buffer
+		for _, up := range uploads {
+			_, _ = fmt.Fprintf(&buf, "%s  %s\n", checksums[up.name], up.name)
+		}
+		content := buf.Bytes()
+		uploads = append(uploads, upload{
+			name: p.checksumsFile,
+			data: func() (io.ReadCloser, int64, error) {
+				return io.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+			},
+		})
+	}
+	return uploads, nil
+}
+
+// releasesPageSize is the number of releases requested for each page when finding a release.
+const releasesPageSize = 100
+
+// findRelease looks up the release for the Publisher's tag.
+// Releases are listed rather than looked up by tag, because the tag lookup doesn't return draft releases.
+func (p *Publisher) findRelease(ctx context.Context, token string) (releaseResponse, bool, error) {
+	for page := 1; ; page++ {
+		var releases []releaseResponse
+		target := fmt.Sprintf("%s?per_page=%d&page=%d", p.repoURL("releases"), releasesPageSize, page)
+		if _, err := p.call(ctx, token, http.MethodGet, target, nil, &releases); err != nil {
+			return releaseResponse{}, false, err
+		}
+		for _, rel := range releases {
+			if rel.TagName == p.tag {
+				return rel, true, nil
+			}
+		}
+		if len(releases) < releasesPageSize {
+			return releaseResponse{}, false, nil
+		}
+	}
+}
+
+func (p *Publisher) repoURL(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, seg := range segments {
+		escaped[i] = url.PathEscape(seg)
+	}
+	return fmt.Sprintf("%s/repos/%s/%s/%s", p.baseURL, url.PathEscape(p.owner), url.PathEscape(p.repo), strings.Join(escaped, "/"))
+}
+
+func (p *Publisher) upload(ctx context.Context, token, uploadURL string, up upload) error {
+	body, size, err := up.data()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL+"?name="+url.QueryEscape(up.name), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	_, err = p.do(req, token, nil)
+	return err
+}
+
+func (p *Publisher) call(ctx context.Context, token, method, target string, reqBody, respBody any) (int, error) {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, err
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return p.do(req, token, respBody)
+}
+
+func (p *Publisher) do(req *http.Request, token string, respBody any) (int, error) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("unexpected status '%s' from %s %s: %s", resp.Status, req.Method, req.URL.Redacted(), strings.TrimSpace(string(msg)))
+	}
+	if respBody != nil {
+		if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,257 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	mm "github.com/saylorsolutions/modmake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI is a minimal stand-in for the GitHub release API.
+type fakeAPI struct {
+	mux      sync.Mutex
+	server   *httptest.Server
+	nextID   int64
+	releases map[string]*fakeRelease
+	deleted  []int64
+}
+
+type fakeRelease struct {
+	releaseRequest
+	ID     int64
+	Assets map[string]fakeAsset
+}
+
+type fakeAsset struct {
+	ID      int64
+	Content string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{releases: map[string]*fakeRelease{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/releases", api.listOrCreate)
+	mux.HandleFunc("/repos/owner/repo/releases/", api.updateOrDelete)
+	mux.HandleFunc("/uploads/", api.upload)
+	api.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+func (api *fakeAPI) response(rel *fakeRelease) releaseResponse {
+	resp := releaseResponse{
+		ID:        rel.ID,
+		TagName:   rel.TagName,
+		HTMLURL:   fmt.Sprintf("%s/releases/%s", api.server.URL, rel.TagName),
+		UploadURL: fmt.Sprintf("%s/uploads/%d/assets{?name,label}", api.server.URL, rel.ID),
+	}
+	for name, a := range rel.Assets {
+		resp.Assets = append(resp.Assets, asset{ID: a.ID, Name: name})
+	}
+	return resp
+}
+
+func (api *fakeAPI) respond(w http.ResponseWriter, rel *fakeRelease) {
+	_ = json.NewEncoder(w).Encode(api.response(rel))
+}
+
+// listOrCreate lists releases, including drafts, in pages.
+func (api *fakeAPI) listOrCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.create(w, r)
+		return
+	}
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	var all []releaseResponse
+	for _, rel := range api.releases {
+		all = append(all, api.response(rel))
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID < all[j].ID
+	})
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	start := (page - 1) * perPage
+	if page < 1 || perPage < 1 || start >= len(all) {
+		all = nil
+	} else {
+		all = all[start:min(start+perPage, len(all))]
+	}
+	if all == nil {
+		all = []releaseResponse{}
+	}
+	_ = json.NewEncoder(w).Encode(all)
+}
+
+func (api *fakeAPI) create(w http.ResponseWriter, r *http.Request) {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	var req releaseRequest
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	api.nextID++
+	rel := &fakeRelease{releaseRequest: req, ID: api.nextID, Assets: map[string]fakeAsset{}}
+	api.releases[req.TagName] = rel
+	w.WriteHeader(http.StatusCreated)
+	api.respond(w, rel)
+}
+
+func (api *fakeAPI) updateOrDelete(w http.ResponseWriter, r *http.Request) {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	id := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/releases/")
+	if r.Method == http.MethodDelete {
+		for _, rel := range api.releases {
+			for name, a := range rel.Assets {
+				if "assets/"+fmt.Sprintf("%d", a.ID) == id {
+					delete(rel.Assets, name)
+					api.deleted = append(api.deleted, a.ID)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	for _, rel := range api.releases {
+		if fmt.Sprintf("%d", rel.ID) == id && r.Method == http.MethodPatch {
+			var req releaseRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rel.Name, rel.Body, rel.Draft, rel.Prerelease = req.Name, req.Body, req.Draft, req.Prerelease
+			api.respond(w, rel)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (api *fakeAPI) upload(w http.ResponseWriter, r *http.Request) {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	name := r.URL.Query().Get("name")
+	data, _ := io.ReadAll(r.Body)
+	for _, rel := range api.releases {
+		if fmt.Sprintf("/uploads/%d/assets", rel.ID) == r.URL.Path {
+			if _, ok := rel.Assets[name]; ok {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			api.nextID++
+			rel.Assets[name] = fakeAsset{ID: api.nextID, Content: string(data)}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	api := newFakeAPI(t)
+	t.Setenv("TEST_RELEASE_TOKEN", "secret")
+	dist := mm.Path(t.TempDir())
+	require.NoError(t, dist.Join("app").MkdirAll(0755))
+	require.NoError(t, dist.Join("app", "app_linux_amd64_1.0.0.tar.gz").WriteFile([]byte("linux"), 0644))
+	require.NoError(t, dist.Join("app", "app_windows_amd64_1.0.0.zip").WriteFile([]byte("windows"), 0644))
+
+	publisher := NewPublisher("owner", "repo", "v1.0.0").
+		BaseURL(api.server.URL + "/").
+		TokenEnv("TEST_RELEASE_TOKEN").
+		ArtifactDir(dist).
+		Notes("First release")
+	require.NoError(t, publisher.Publish().Run(context.Background()))
+
+	rel, ok := api.releases["v1.0.0"]
+	require.True(t, ok, "Release should have been created")
+	assert.Equal(t, "v1.0.0", rel.Name)
+	assert.Equal(t, "First release", rel.Body)
+	require.Len(t, rel.Assets, 3)
+	assert.Equal(t, "linux", rel.Assets["app_linux_amd64_1.0.0.tar.gz"].Content)
+	assert.Equal(t, "windows", rel.Assets["app_windows_amd64_1.0.0.zip"].Content)
+	assert.Equal(t,
+		"caf90169eefa5f807d577486b9f795ab86ae2983c5c20806cff959117e90af18  app_linux_amd64_1.0.0.tar.gz\n",
+		strings.SplitAfter(rel.Assets[DefaultChecksumsFile].Content, "\n")[0],
+	)
+
+	// Publishing again should update the release and replace assets.
+	require.NoError(t, dist.Join("app", "app_linux_amd64_1.0.0.tar.gz").WriteFile([]byte("linux, again"), 0644))
+	require.NoError(t, publisher.Notes("Updated notes").Prerelease().Publish().Run(context.Background()))
+	assert.Len(t, api.releases, 1)
+	assert.Equal(t, "Updated notes", rel.Body)
+	assert.True(t, rel.Prerelease)
+	assert.Len(t, api.deleted, 3)
+	require.Len(t, rel.Assets, 3)
+	assert.Equal(t, "linux, again", rel.Assets["app_linux_amd64_1.0.0.tar.gz"].Content)
+}
+
+func TestPublisher_Publish_Draft(t *testing.T) {
+	api := newFakeAPI(t)
+	t.Setenv("TEST_RELEASE_TOKEN", "secret")
+	// Enough other releases that the draft is on a later page.
+	for i := 0; i < releasesPageSize+10; i++ {
+		api.nextID++
+		tag := fmt.Sprintf("v0.0.%d", i)
+		api.releases[tag] = &fakeRelease{releaseRequest: releaseRequest{TagName: tag}, ID: api.nextID, Assets: map[string]fakeAsset{}}
+	}
+	dist := mm.Path(t.TempDir())
+	require.NoError(t, dist.Join("app.tar.gz").WriteFile([]byte("app"), 0644))
+	publisher := NewPublisher("owner", "repo", "v1.0.0").
+		BaseURL(api.server.URL).
+		TokenEnv("TEST_RELEASE_TOKEN").
+		ArtifactDir(dist).
+		Draft()
+	require.NoError(t, publisher.Publish().Run(context.Background()))
+	require.NoError(t, publisher.Notes("Updated notes").Publish().Run(context.Background()))
+	assert.Len(t, api.releases, releasesPageSize+11, "Re-publishing a draft should update it rather than create another")
+	rel := api.releases["v1.0.0"]
+	require.NotNil(t, rel)
+	assert.True(t, rel.Draft)
+	assert.Equal(t, "Updated notes", rel.Body)
+}
+
+func TestPublisher_Publish_NoToken(t *testing.T) {
+	api := newFakeAPI(t)
+	t.Setenv("TEST_RELEASE_TOKEN", "")
+	err := NewPublisher("owner", "repo", "v1.0.0").
+		BaseURL(api.server.URL).
+		TokenEnv("TEST_RELEASE_TOKEN").
+		Publish().Run(context.Background())
+	assert.ErrorIs(t, err, ErrNoToken)
+}
+
+func TestPublisher_Publish_Unauthorized(t *testing.T) {
+	api := newFakeAPI(t)
+	t.Setenv("TEST_RELEASE_TOKEN", "wrong")
+	dist := mm.Path(t.TempDir())
+	require.NoError(t, dist.Join("artifact.zip").WriteFile([]byte("artifact"), 0644))
+	err := NewPublisher("owner", "repo", "v1.0.0").
+		BaseURL(api.server.URL).
+		TokenEnv("TEST_RELEASE_TOKEN").
+		ArtifactDir(dist).
+		Publish().Run(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
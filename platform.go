@@ -0,0 +1,210 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Platform is a GOOS/GOARCH combination supported by the Go toolchain, as reported by 'go tool dist list -json'.
+type Platform struct {
+	GOOS         string
+	GOARCH       string
+	CgoSupported bool
+	FirstClass   bool
+}
+
+// String returns the platform in "GOOS/GOARCH" format.
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// Platforms returns the list of platforms supported by this Go toolchain.
+func (g *GoTools) Platforms(ctx context.Context) ([]Platform, error) {
+	var (
+		output    bytes.Buffer
+		platforms []Platform
+	)
+	if err := g.Command("tool", "dist", "list", "-json").CaptureStdin().Silent().Stdout(&output).Run(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list platforms: %w", err)
+	}
+	if err := json.NewDecoder(&output).Decode(&platforms); err != nil {
+		return nil, fmt.Errorf("failed to decode platform list: %w", err)
+	}
+	return platforms, nil
+}
+
+// VariantMatrix creates AppVariants for many platforms at once, based on the platforms supported by the Go toolchain.
+// Use [AppBuild.Variants] to create a VariantMatrix.
+//
+//	// Build and package for every first-class platform.
+//	app.Variants().FirstClass().Add()
+//
+//	// Build for all linux platforms except s390x, with separate variants for GOARM=6 and GOARM=7.
+//	app.Variants().
+//		Include("linux/*").
+//		Exclude("*/s390x").
+//		Levels("linux/arm", "GOARM", "6", "7").
+//		Add()
+type VariantMatrix struct {
+	app          *AppBuild
+	includes     []string
+	excludes     []string
+	firstClass   bool
+	cgoSupported bool
+	levels       []variantLevels
+	platforms    func(ctx context.Context) ([]Platform, error)
+}
+
+type variantLevels struct {
+	pattern, key string
+	values       []string
+}
+
+// Variants creates a VariantMatrix for this AppBuild.
+// Variants are not added until [VariantMatrix.Add] is called.
+func (a *AppBuild) Variants() *VariantMatrix {
+	return &VariantMatrix{
+		app:       a,
+		platforms: Go().Platforms,
+	}
+}
+
+// Include limits the matrix to platforms matching any of the given patterns.
+// Patterns are matched against "GOOS/GOARCH" with [path.Match], so "linux/*" matches all linux platforms, and "*/arm64" matches all arm64 platforms.
+// If no patterns are included, then all platforms are matched.
+func (m *VariantMatrix) Include(patterns ...string) *VariantMatrix {
+	m.includes = append(m.includes, validPlatformPatterns(patterns)...)
+	return m
+}
+
+// Exclude removes platforms matching any of the given patterns from the matrix.
+// Patterns are matched the same way as [VariantMatrix.Include].
+func (m *VariantMatrix) Exclude(patterns ...string) *VariantMatrix {
+	m.excludes = append(m.excludes, validPlatformPatterns(patterns)...)
+	return m
+}
+
+// FirstClass limits the matrix to first-class ports of Go.
+// See https://go.dev/wiki/PortingPolicy#first-class-ports for details.
+func (m *VariantMatrix) FirstClass() *VariantMatrix {
+	m.firstClass = true
+	return m
+}
+
+// CgoSupported limits the matrix to platforms that support cgo.
+func (m *VariantMatrix) CgoSupported() *VariantMatrix {
+	m.cgoSupported = true
+	return m
+}
+
+// Levels replaces the variant for each platform matching the pattern with a variant for each value, where the environment variable named by key is set to that value.
+// Each variant is named "${GOOS}_${GOARCH}_${VALUE}".
+// This is useful for GOARM, GOAMD64, and similar settings.
+//
+//	// Creates linux_amd64_v1 and linux_amd64_v3 variants.
+//	app.Variants().Include("linux/amd64").Levels("linux/amd64", "GOAMD64", "v1", "v3").Add()
+func (m *VariantMatrix) Levels(pattern, key string, values ...string) *VariantMatrix {
+	validPlatformPatterns([]string{pattern})
+	key = strings.TrimSpace(key)
+	if len(key) == 0 {
+		panic("empty environment variable name")
+	}
+	if len(values) == 0 {
+		panic("no values given for " + key)
+	}
+	m.levels = append(m.levels, variantLevels{pattern: pattern, key: key, values: values})
+	return m
+}
+
+func validPlatformPatterns(patterns []string) []string {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("invalid platform pattern '%s': %v", pattern, err))
+		}
+	}
+	return patterns
+}
+
+func matchesAny(patterns []string, platform string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, platform); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns the platforms that match the criteria of this VariantMatrix, sorted by GOOS and GOARCH.
+func (m *VariantMatrix) Matches(ctx context.Context) ([]Platform, error) {
+	all, err := m.platforms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matched []Platform
+	for _, p := range all {
+		if m.firstClass && !p.FirstClass {
+			continue
+		}
+		if m.cgoSupported && !p.CgoSupported {
+			continue
+		}
+		if len(m.includes) > 0 && !matchesAny(m.includes, p.String()) {
+			continue
+		}
+		if matchesAny(m.excludes, p.String()) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].String() < matched[j].String()
+	})
+	return matched, nil
+}
+
+// Add creates an AppVariant for each matching platform, and returns the variants created.
+// Variants that already exist in the AppBuild are skipped, so platforms that need special treatment may be declared with [AppBuild.Variant] first.
+// This will panic if the platform list can't be retrieved from the Go toolchain.
+func (m *VariantMatrix) Add() []*AppVariant {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	platforms, err := m.Matches(ctx)
+	if err != nil {
+		panic(err)
+	}
+	var added []*AppVariant
+	addVariant := func(name, os, arch string) *AppVariant {
+		if m.app.hasVariant(name) {
+			return nil
+		}
+		v := m.app.NamedVariant(name, os, arch)
+		added = append(added, v)
+		return v
+	}
+	for _, p := range platforms {
+		base := p.GOOS + "_" + p.GOARCH
+		var levels *variantLevels
+		for i := range m.levels {
+			if ok, _ := path.Match(m.levels[i].pattern, p.String()); ok {
+				levels = &m.levels[i]
+				break
+			}
+		}
+		if levels == nil {
+			addVariant(base, p.GOOS, p.GOARCH)
+			continue
+		}
+		for _, value := range levels.values {
+			if v := addVariant(base+"_"+value, p.GOOS, p.GOARCH); v != nil {
+				v.Env(levels.key, value)
+			}
+		}
+	}
+	return added
+}
@@ -0,0 +1,104 @@
+package modmake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoTools_Platforms(t *testing.T) {
+	platforms, err := Go().Platforms(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, platforms, Platform{GOOS: "linux", GOARCH: "amd64", CgoSupported: true, FirstClass: true})
+}
+
+func testMatrix(a *AppBuild) *VariantMatrix {
+	m := a.Variants()
+	m.platforms = func(ctx context.Context) ([]Platform, error) {
+		return []Platform{
+			{GOOS: "windows", GOARCH: "amd64", CgoSupported: true, FirstClass: true},
+			{GOOS: "linux", GOARCH: "arm", CgoSupported: true, FirstClass: true},
+			{GOOS: "linux", GOARCH: "amd64", CgoSupported: true, FirstClass: true},
+			{GOOS: "linux", GOARCH: "s390x", CgoSupported: true, FirstClass: false},
+			{GOOS: "js", GOARCH: "wasm", CgoSupported: false, FirstClass: false},
+			{GOOS: "darwin", GOARCH: "arm64", CgoSupported: true, FirstClass: true},
+		}, nil
+	}
+	return m
+}
+
+func variantNames(variants []*AppVariant) []string {
+	var names []string
+	for _, v := range variants {
+		names = append(names, v.variant)
+	}
+	return names
+}
+
+func TestVariantMatrix_Add(t *testing.T) {
+	tests := map[string]struct {
+		matrix   func(m *VariantMatrix) *VariantMatrix
+		expected []string
+	}{
+		"All": {
+			matrix: func(m *VariantMatrix) *VariantMatrix {
+				return m
+			},
+			expected: []string{"darwin_arm64", "js_wasm", "linux_amd64", "linux_arm", "linux_s390x", "windows_amd64"},
+		},
+		"First class": {
+			matrix: func(m *VariantMatrix) *VariantMatrix {
+				return m.FirstClass()
+			},
+			expected: []string{"darwin_arm64", "linux_amd64", "linux_arm", "windows_amd64"},
+		},
+		"Cgo": {
+			matrix: func(m *VariantMatrix) *VariantMatrix {
+				return m.CgoSupported()
+			},
+			expected: []string{"darwin_arm64", "linux_amd64", "linux_arm", "linux_s390x", "windows_amd64"},
+		},
+		"Patterns": {
+			matrix: func(m *VariantMatrix) *VariantMatrix {
+				return m.Include("linux/*", "*/wasm").Exclude("*/s390x")
+			},
+			expected: []string{"js_wasm", "linux_amd64", "linux_arm"},
+		},
+		"Levels": {
+			matrix: func(m *VariantMatrix) *VariantMatrix {
+				return m.Include("linux/*").FirstClass().
+					Levels("linux/arm", "GOARM", "6", "7").
+					Levels("*/amd64", "GOAMD64", "v1", "v3")
+			},
+			expected: []string{"linux_amd64_v1", "linux_amd64_v3", "linux_arm_6", "linux_arm_7"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := NewAppBuild("testapp", "cmd/modmake", "1.0.0")
+			added := tc.matrix(testMatrix(a)).Add()
+			assert.Equal(t, tc.expected, variantNames(added))
+			assert.Equal(t, tc.expected, variantNames(a.variants))
+		})
+	}
+}
+
+func TestVariantMatrix_Levels(t *testing.T) {
+	a := NewAppBuild("testapp", "cmd/modmake", "1.0.0")
+	existing := a.Variant("linux", "amd64").NoPackage()
+	added := testMatrix(a).Include("linux/*").FirstClass().Levels("linux/arm", "GOARM", "6", "7").Add()
+	assert.Equal(t, []string{"linux_arm_6", "linux_arm_7"}, variantNames(added), "Existing variants should be skipped")
+	assert.Nil(t, existing.packageFunc, "Existing variants should not be changed")
+
+	gb := a.goBuild(added[0])
+	assert.Contains(t, gb.cmd.env, "GOARM=6")
+	assert.Contains(t, gb.cmd.env, "GOARCH=arm")
+	assert.Equal(t, Path("build/testapp_linux_arm_7/testapp"), added[1].buildOutput)
+
+	assert.Panics(t, func() {
+		a.Variants().Include("[")
+	}, "Invalid patterns should panic")
+}
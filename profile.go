@@ -0,0 +1,214 @@
+package modmake
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvProfile is the environment variable that selects a build profile when the --profile flag isn't passed to [Build.ExecuteErr].
+	EnvProfile = "MODMAKE_PROFILE"
+	// ProfileEnvSkip is a key in a profile file with a comma separated list of steps that should be skipped by default.
+	ProfileEnvSkip = "MODMAKE_SKIP"
+	// ProfileEnvNoSkip is a key in a profile file with a comma separated list of steps that should not be skipped by default.
+	ProfileEnvNoSkip = "MODMAKE_NO_SKIP"
+	// ProfileEnvFlags is a key in a profile file with whitespace separated build flags that should be applied by default.
+	ProfileEnvFlags = "MODMAKE_FLAGS"
+)
+
+// Profile is a named set of defaults for a [Build], like "dev", "ci", or "release", which is selected with the --profile flag.
+// A profile may set environment variables, default build flags, and steps that should or shouldn't be skipped.
+// Environment variables that are already set take precedence over profile values, and flags passed when executing the build take precedence over profile flags.
+//
+// Profiles may also be defined, or extended, without changing code with a profile file named like "modmake.NAME.env" in the module root.
+// A profile file is read with [ReadEnvFile], and its values override values set in code.
+// The special keys [ProfileEnvSkip], [ProfileEnvNoSkip], and [ProfileEnvFlags] are used to configure the profile rather than setting environment variables.
+//
+//	# modmake.ci.env
+//	CGO_ENABLED=0
+//	MODMAKE_SKIP=generate
+//	MODMAKE_NO_SKIP=benchmark
+//	MODMAKE_FLAGS=--timeout=30m
+type Profile struct {
+	name        string
+	description string
+	env         EnvMap
+	envFiles    []PathString
+	skip        []string
+	noSkip      []string
+	flags       []string
+}
+
+// Profile defines a new named Profile for this Build.
+// This will panic if the name is empty, or if a profile with the same name is already defined.
+func (b *Build) Profile(name, description string) *Profile {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 {
+		panic("empty profile name")
+	}
+	if b.profiles == nil {
+		b.profiles = map[string]*Profile{}
+	}
+	if _, ok := b.profiles[name]; ok {
+		panic(fmt.Errorf("profile '%s' already exists", name))
+	}
+	p := &Profile{
+		name:        name,
+		description: description,
+		env:         EnvMap{},
+	}
+	b.profiles[name] = p
+	return p
+}
+
+// Profiles returns the names of profiles defined in code for this Build.
+func (b *Build) Profiles() []string {
+	return sortedKeys(b.profiles)
+}
+
+// Env sets an environment variable when the profile is selected, unless it's already set.
+func (p *Profile) Env(key, value string) *Profile {
+	if len(key) == 0 {
+		panic("empty environment variable name")
+	}
+	p.env[key] = value
+	return p
+}
+
+// EnvFile reads environment variables from each of the files with [ReadEnvFiles] when the profile is selected.
+// Values from later files take precedence, and files that don't exist are skipped.
+func (p *Profile) EnvFile(files ...PathString) *Profile {
+	p.envFiles = append(p.envFiles, files...)
+	return p
+}
+
+// Skip marks steps that should be skipped by default when the profile is selected.
+func (p *Profile) Skip(steps ...string) *Profile {
+	p.skip = append(p.skip, steps...)
+	return p
+}
+
+// NoSkip marks steps that should not be skipped by default when the profile is selected, like the benchmark step.
+func (p *Profile) NoSkip(steps ...string) *Profile {
+	p.noSkip = append(p.noSkip, steps...)
+	return p
+}
+
+// Flags sets default build flags when the profile is selected, like "--timeout=30m" or "--debug".
+// Flags passed when executing the build are applied after these, so they take precedence.
+// This will panic if the --profile flag is included.
+func (p *Profile) Flags(flags ...string) *Profile {
+	if err := validateProfileFlags(flags); err != nil {
+		panic(err)
+	}
+	p.flags = append(p.flags, flags...)
+	return p
+}
+
+func validateProfileFlags(flags []string) error {
+	for _, f := range flags {
+		if f == "--profile" || strings.HasPrefix(f, "--profile=") || f == "-profile" || strings.HasPrefix(f, "-profile=") {
+			return errors.New("a profile cannot select another profile")
+		}
+	}
+	return nil
+}
+
+// profileFile returns the path to the profile file for the named profile within the module root.
+func profileFile(root PathString, name string) PathString {
+	return root.Join(fmt.Sprintf("modmake.%s.env", name))
+}
+
+// loadProfile combines the profile defined in code, if any, with its profile file in the module root, if any.
+// An error is returned if neither exist, or if the profile file is invalid.
+func (b *Build) loadProfile(root PathString, name string) (*Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	loaded := &Profile{name: name, env: EnvMap{}}
+	defined, ok := b.profiles[name]
+	if ok {
+		for k, v := range defined.env {
+			loaded.env[k] = v
+		}
+		loaded.skip = append(loaded.skip, defined.skip...)
+		loaded.noSkip = append(loaded.noSkip, defined.noSkip...)
+		loaded.flags = append(loaded.flags, defined.flags...)
+		fileEnv, err := ReadEnvFiles(defined.envFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read environment files for profile '%s': %w", name, err)
+		}
+		for k, v := range fileEnv {
+			loaded.env[k] = v
+		}
+	}
+	file := profileFile(root, name)
+	fileEnv, err := ReadEnvFile(file)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if !ok {
+			return nil, fmt.Errorf("profile '%s' is not defined, and profile file '%s' doesn't exist", name, file)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read profile file for profile '%s': %w", name, err)
+	default:
+		for k, v := range fileEnv {
+			switch strings.ToUpper(k) {
+			case ProfileEnvSkip:
+				loaded.skip = append(loaded.skip, splitList(v)...)
+			case ProfileEnvNoSkip:
+				loaded.noSkip = append(loaded.noSkip, splitList(v)...)
+			case ProfileEnvFlags:
+				flags := strings.Fields(v)
+				if err := validateProfileFlags(flags); err != nil {
+					return nil, fmt.Errorf("invalid %s in profile file '%s': %w", ProfileEnvFlags, file, err)
+				}
+				loaded.flags = append(loaded.flags, flags...)
+			default:
+				loaded.env[k] = v
+			}
+		}
+	}
+	return loaded, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// apply sets the profile's environment variables that aren't already set, and applies its default skip settings.
+func (p *Profile) apply(b *Build) error {
+	for _, key := range sortedKeys(p.env) {
+		if _, ok := os.LookupEnv(key); ok {
+			b.logger.Debug("Environment variable '%s' is already set, ignoring the value from profile '%s'\n", key, p.name)
+			continue
+		}
+		if err := os.Setenv(key, p.env[key]); err != nil {
+			return fmt.Errorf("failed to set environment variable '%s' for profile '%s': %w", key, p.name, err)
+		}
+	}
+	for _, skip := range p.skip {
+		step, ok := b.StepOk(skip)
+		if !ok {
+			b.logger.Warn("Profile '%s' skips step '%s', but it doesn't exist in this model\n", p.name, skip)
+			continue
+		}
+		step.Skip()
+	}
+	for _, noskip := range p.noSkip {
+		step, ok := b.StepOk(noskip)
+		if !ok {
+			b.logger.Warn("Profile '%s' un-skips step '%s', but it doesn't exist in this model\n", p.name, noskip)
+			continue
+		}
+		step.UnSkip()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package modmake
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type profileCapture struct {
+	ran      []string
+	value    string
+	preset   string
+	deadline time.Duration
+}
+
+func profileTestBuild(t *testing.T) (*Build, *profileCapture) {
+	t.Cleanup(func() {
+		_ = os.Unsetenv("MODMAKE_PROFILE_VALUE")
+	})
+	t.Setenv("MODMAKE_PROFILE_PRESET", "environment")
+	var capture profileCapture
+	record := func(name string) Task {
+		return func(ctx context.Context) error {
+			capture.ran = append(capture.ran, name)
+			return nil
+		}
+	}
+	b := NewBuild()
+	b.Test().Skip()
+	slow := b.AddNewStep("slow", "", record("slow")).Skip()
+	lint := b.AddNewStep("lint", "", record("lint"))
+	b.AddNewStep("capture", "", Task(func(ctx context.Context) error {
+		capture.value = os.Getenv("MODMAKE_PROFILE_VALUE")
+		capture.preset = os.Getenv("MODMAKE_PROFILE_PRESET")
+		if deadline, ok := ctx.Deadline(); ok {
+			capture.deadline = time.Until(deadline)
+		}
+		return nil
+	})).DependsOn(slow).DependsOn(lint)
+	b.Profile("ci", "Continuous integration").
+		Env("MODMAKE_PROFILE_VALUE", "ci").
+		Env("MODMAKE_PROFILE_PRESET", "profile").
+		Skip("lint").
+		NoSkip("slow").
+		Flags("--timeout=1h")
+	return b, &capture
+}
+
+func TestBuild_Profile(t *testing.T) {
+	b, capture := profileTestBuild(t)
+	require.NoError(t, b.ExecuteErr("--profile", "ci", "capture"))
+	assert.Equal(t, []string{"slow"}, capture.ran, "Profile skip settings should be applied")
+	assert.Equal(t, "ci", capture.value)
+	assert.Equal(t, "environment", capture.preset, "Environment variables that are already set take precedence")
+	assert.Greater(t, capture.deadline, 50*time.Minute, "Profile flags should be applied")
+	assert.Equal(t, []string{"ci"}, b.Profiles())
+}
+
+func TestBuild_ProfileOverrides(t *testing.T) {
+	b, capture := profileTestBuild(t)
+	t.Setenv(EnvProfile, "ci")
+	require.NoError(t, b.ExecuteErr("--timeout=2h", "--no-skip", "lint", "capture"))
+	assert.Equal(t, []string{"slow", "lint"}, capture.ran, "Explicit flags should override profile skip settings")
+	assert.Greater(t, capture.deadline, 110*time.Minute, "Explicit flags should override profile flags")
+}
+
+func TestBuild_UnknownProfile(t *testing.T) {
+	b, capture := profileTestBuild(t)
+	err := b.ExecuteErr("--profile", "missing", "capture")
+	assert.ErrorContains(t, err, "profile 'missing' is not defined")
+
+	t.Setenv(EnvProfile, "missing")
+	require.NoError(t, b.ExecuteErr("capture"), "An unknown profile from the environment is ignored")
+	assert.Equal(t, []string{"lint"}, capture.ran)
+
+	assert.Panics(t, func() {
+		b.Profile("CI", "")
+	})
+	assert.Panics(t, func() {
+		b.Profile("other", "").Flags("--profile=ci")
+	})
+}
+
+func TestBuild_ProfileFile(t *testing.T) {
+	tmp := Path(t.TempDir())
+	require.NoError(t, tmp.Join("shared.env").WriteFile([]byte("SHARED=shared\nREGION=us-east-1\n"), 0644))
+	require.NoError(t, tmp.Join("modmake.release.env").WriteFile([]byte(`REGION=eu-west-1
+MODMAKE_SKIP=lint, test
+MODMAKE_NO_SKIP=benchmark
+MODMAKE_FLAGS=--debug --timeout=30m
+`), 0644))
+
+	b := NewBuild()
+	b.Profile("release", "").Env("REGION", "local").EnvFile(tmp.Join("shared.env")).Flags("--dry-run")
+	profile, err := b.loadProfile(tmp, "Release")
+	require.NoError(t, err)
+	assert.Equal(t, EnvMap{"REGION": "eu-west-1", "SHARED": "shared"}, profile.env, "Profile files should override code values")
+	assert.Equal(t, []string{"lint", "test"}, profile.skip)
+	assert.Equal(t, []string{"benchmark"}, profile.noSkip)
+	assert.Equal(t, []string{"--dry-run", "--debug", "--timeout=30m"}, profile.flags)
+
+	require.NoError(t, tmp.Join("modmake.adhoc.env").WriteFile([]byte("ADHOC=true\n"), 0644))
+	profile, err = b.loadProfile(tmp, "adhoc")
+	require.NoError(t, err, "Profiles may be defined only with a file")
+	assert.Equal(t, EnvMap{"ADHOC": "true"}, profile.env)
+
+	_, err = b.loadProfile(Path(t.TempDir()), "adhoc")
+	assert.ErrorContains(t, err, "profile 'adhoc' is not defined", "Profile files are read from the module root, not the working directory")
+
+	require.NoError(t, tmp.Join("modmake.loop.env").WriteFile([]byte("MODMAKE_FLAGS=--debug --profile=release\n"), 0644))
+	assert.NotPanics(t, func() {
+		_, err = b.loadProfile(tmp, "loop")
+	})
+	assert.ErrorContains(t, err, "a profile cannot select another profile")
+}
@@ -0,0 +1,252 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedactedValue replaces secret values in redacted output.
+const RedactedValue = "***"
+
+// ErrSecretNotFound is returned when a secret can't be found, or has an empty value.
+var ErrSecretNotFound = errors.New("secret not found")
+
+var secretRegistry struct {
+	mux    sync.RWMutex
+	values map[string]struct{}
+	// sorted holds the registered values longest first, so a secret that contains another is fully redacted.
+	sorted []string
+}
+
+// RegisterSecret registers values that should be redacted from step logs, [StepContextError] messages, and the output Commands write to os.Stdout and os.Stderr.
+// Output captured with [Command.Stdout] or [Command.Stderr] is only redacted if [Command.RedactOutput] is used.
+// Empty values are ignored, and very short values should be avoided since they'd redact unrelated text.
+// Secrets created with [NewSecret], [SecretFromEnv], [SecretFromFile], and [SecretFrom] are registered automatically.
+func RegisterSecret(values ...string) {
+	secretRegistry.mux.Lock()
+	defer secretRegistry.mux.Unlock()
+	if secretRegistry.values == nil {
+		secretRegistry.values = map[string]struct{}{}
+	}
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		if _, ok := secretRegistry.values[value]; ok {
+			continue
+		}
+		secretRegistry.values[value] = struct{}{}
+		secretRegistry.sorted = append(secretRegistry.sorted, value)
+	}
+	sort.SliceStable(secretRegistry.sorted, func(i, j int) bool {
+		return len(secretRegistry.sorted[i]) > len(secretRegistry.sorted[j])
+	})
+}
+
+func hasSecrets() bool {
+	secretRegistry.mux.RLock()
+	defer secretRegistry.mux.RUnlock()
+	return len(secretRegistry.sorted) > 0
+}
+
+// Redact replaces every registered secret value in s with [RedactedValue].
+func Redact(s string) string {
+	secretRegistry.mux.RLock()
+	defer secretRegistry.mux.RUnlock()
+	for _, value := range secretRegistry.sorted {
+		s = strings.ReplaceAll(s, value, RedactedValue)
+	}
+	return s
+}
+
+// Secret is a sensitive value, like a publishing token, that's redacted from logs and command output.
+// Formatting a Secret, like with fmt.Sprintf("%v", secret), produces [RedactedValue], so the value must be retrieved with Value when it's needed.
+type Secret struct {
+	name  string
+	value string
+}
+
+// NewSecret creates a Secret with a descriptive name, and registers its value for redaction.
+func NewSecret(name, value string) Secret {
+	RegisterSecret(value)
+	return Secret{name: name, value: value}
+}
+
+// Name returns the descriptive name of the Secret, like the environment variable or file it was read from.
+func (s Secret) Name() string {
+	return s.name
+}
+
+// Value returns the secret value.
+// Be careful not to pass it anywhere it could be persisted, like a file that will be included in an artifact.
+func (s Secret) Value() string {
+	return s.value
+}
+
+// String returns [RedactedValue], so the value isn't exposed by formatting.
+func (s Secret) String() string {
+	return RedactedValue
+}
+
+// GoString returns a redacted representation for the %#v verb.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("modmake.Secret{name: %q, value: %q}", s.name, RedactedValue)
+}
+
+// SecretFromEnv creates a Secret from the value of an environment variable.
+// [ErrSecretNotFound] is returned if the variable is unset or empty.
+func SecretFromEnv(key string) (Secret, error) {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return Secret{}, fmt.Errorf("%w: environment variable '%s' is not set", ErrSecretNotFound, key)
+	}
+	return NewSecret(key, value), nil
+}
+
+// SecretFromFile creates a Secret from the content of a file, like a mounted CI secret, with trailing line endings removed.
+// [ErrSecretNotFound] is returned if the file doesn't exist or is empty.
+func SecretFromFile(file PathString) (Secret, error) {
+	data, err := file.ReadFile()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Secret{}, fmt.Errorf("%w: file '%s' doesn't exist", ErrSecretNotFound, file)
+		}
+		return Secret{}, fmt.Errorf("failed to read secret file '%s': %w", file, err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if len(value) == 0 {
+		return Secret{}, fmt.Errorf("%w: file '%s' is empty", ErrSecretNotFound, file)
+	}
+	return NewSecret(file.String(), value), nil
+}
+
+// SecretProvider looks up secrets by name from an external source, like a vault or a cloud secret manager.
+type SecretProvider interface {
+	// LookupSecret returns the value of the named secret, or an error wrapping [ErrSecretNotFound] if it doesn't exist.
+	LookupSecret(ctx context.Context, name string) (string, error)
+}
+
+// SecretProviderFunc adapts a function to the [SecretProvider] interface.
+type SecretProviderFunc func(ctx context.Context, name string) (string, error)
+
+// LookupSecret calls the function.
+func (f SecretProviderFunc) LookupSecret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// SecretFrom creates a Secret from the value returned by the provider.
+// [ErrSecretNotFound] is returned if the provider returns an empty value.
+func SecretFrom(ctx context.Context, provider SecretProvider, name string) (Secret, error) {
+	if provider == nil {
+		panic("nil secret provider")
+	}
+	value, err := provider.LookupSecret(ctx, name)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to look up secret '%s': %w", name, err)
+	}
+	if len(value) == 0 {
+		return Secret{}, fmt.Errorf("%w: secret '%s' is empty", ErrSecretNotFound, name)
+	}
+	return NewSecret(name, value), nil
+}
+
+// redactFlushDelay is how long a partial line is held by a redactWriter before it's written anyway, so prompts and progress output aren't held indefinitely.
+const redactFlushDelay = 100 * time.Millisecond
+
+// redactWriter redacts secrets from complete lines before writing them, so a secret split across writes is still redacted.
+// Lines end with "\n" or "\r", and a partial line is written after redactFlushDelay, so a secret split across writes with a pause between them may not be redacted.
+type redactWriter struct {
+	mux     sync.Mutex
+	w       io.Writer
+	buf     []byte
+	timer   *time.Timer
+	pending bool
+	err     error
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.buf = append(r.buf, p...)
+	if i := bytes.LastIndexAny(r.buf, "\r\n"); i >= 0 {
+		if err := r.write(i + 1); err != nil {
+			return 0, err
+		}
+	}
+	if len(r.buf) > 0 && !r.pending {
+		r.pending = true
+		if r.timer == nil {
+			r.timer = time.AfterFunc(redactFlushDelay, r.flushPending)
+		} else {
+			r.timer.Reset(redactFlushDelay)
+		}
+	}
+	return len(p), nil
+}
+
+// write redacts and writes the first n buffered bytes.
+func (r *redactWriter) write(n int) error {
+	if _, err := io.WriteString(r.w, Redact(string(r.buf[:n]))); err != nil {
+		r.err = err
+		return err
+	}
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+	return nil
+}
+
+func (r *redactWriter) flushPending() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if !r.pending || r.err != nil {
+		return
+	}
+	r.pending = false
+	_ = r.write(len(r.buf))
+}
+
+// Flush writes any remaining partial line, and stops the pending timeout.
+func (r *redactWriter) Flush() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.pending = false
+	if r.err != nil || len(r.buf) == 0 {
+		return r.err
+	}
+	return r.write(len(r.buf))
+}
+
+// redactOutput wraps a Command's output writer with a redactWriter if any secrets are registered.
+// Only os.Stdout and os.Stderr are wrapped unless force is true, since other writers may be capturing binary data or output that's needed as-is.
+func redactOutput(w io.Writer, force bool) (io.Writer, func()) {
+	if !hasSecrets() || w == nil || w == io.Discard || (!force && w != io.Writer(os.Stdout) && w != io.Writer(os.Stderr)) {
+		return w, func() {}
+	}
+	rw := &redactWriter{w: w}
+	return rw, func() {
+		_ = rw.Flush()
+	}
+}
+
+// sameWriter returns true if both writers are the same comparable value.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
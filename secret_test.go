@@ -0,0 +1,160 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("redact-short", "redact-short-and-long", "")
+	assert.Equal(t, "token=*** other=***", Redact("token=redact-short-and-long other=redact-short"))
+	assert.Equal(t, "nothing to redact", Redact("nothing to redact"))
+}
+
+func TestSecret_Format(t *testing.T) {
+	secret := NewSecret("TOKEN", "format-secret-value")
+	assert.Equal(t, "format-secret-value", secret.Value())
+	assert.Equal(t, "TOKEN", secret.Name())
+	for _, verb := range []string{"%s", "%v", "%+v", "%#v", "%q"} {
+		assert.NotContains(t, fmt.Sprintf(verb, secret), "format-secret-value", verb)
+	}
+}
+
+func TestSecret_Logs(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogOutput(&buf)
+	SetStepDebug(true)
+	t.Cleanup(func() {
+		SetLogOutput(os.Stderr)
+		SetStepDebug(false)
+	})
+	secret := NewSecret("TOKEN", "log-secret-value")
+	ctx, log := WithLogger(context.Background(), "secrets")
+	log.Info("Publishing with token %s", secret.Value())
+	log.Warn("Token: " + secret.Value())
+	log.Debug("Token: %s", secret.Value())
+	assert.NotContains(t, buf.String(), "log-secret-value")
+	assert.Contains(t, buf.String(), "Publishing with token ***")
+
+	err := log.WrapErr(fmt.Errorf("failed to authenticate with %s", secret.Value()))
+	assert.Equal(t, "failed to authenticate with ***", err.Error())
+
+	buf.Reset()
+	_ = Exec("publish", "--token", secret.Value()).Run(ctx)
+	assert.NotContains(t, buf.String(), "log-secret-value", "Command echoes should be redacted")
+}
+
+func TestSecret_CommandOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The 'echo' command isn't available on Windows")
+	}
+	requireExecutable(t, "echo")
+	secret := NewSecret("TOKEN", "output-secret-value")
+	out, err := os.Create(Path(t.TempDir(), "stdout.txt").String())
+	require.NoError(t, err)
+	defer func() {
+		_ = out.Close()
+	}()
+	stdout := os.Stdout
+	os.Stdout = out
+	cmd := Exec("echo", "-n", "token:").SecretEnv("TOKEN", secret).Arg(secret.Value())
+	err = cmd.Run(context.Background())
+	os.Stdout = stdout
+	require.NoError(t, err)
+
+	data, err := Path(out.Name()).ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "token: ***", string(data), "Partial lines should be flushed")
+
+	var buf bytes.Buffer
+	require.NoError(t, Exec("echo", secret.Value()).Stdout(&buf).Run(context.Background()))
+	assert.Equal(t, secret.Value()+"\n", buf.String(), "Captured output should not be redacted by default")
+
+	buf.Reset()
+	require.NoError(t, Exec("echo", secret.Value()).Stdout(&buf).RedactOutput().Run(context.Background()))
+	assert.Equal(t, "***\n", buf.String(), "Captured output should be redacted when requested")
+
+	buf.Reset()
+	require.NoError(t, Exec("sh", "-c", "echo out:$TOKEN; echo err:$TOKEN >&2").SecretEnv("TOKEN", secret).Output(&buf).RedactOutput().Run(context.Background()))
+	assert.Equal(t, "out:***\nerr:***\n", buf.String(), "A shared writer should be redacted")
+}
+
+type lockedBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.String()
+}
+
+func TestRedactWriter(t *testing.T) {
+	RegisterSecret("writer-secret-value")
+	var out lockedBuffer
+	w, flush := redactOutput(&out, true)
+
+	_, err := w.Write([]byte("progress: writer-sec"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ret-value 50%\rprogress: "))
+	require.NoError(t, err)
+	assert.Equal(t, "progress: *** 50%\r", out.String(), "Carriage returns should end a line")
+
+	_, err = w.Write([]byte("100%"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return out.String() == "progress: *** 50%\rprogress: 100%"
+	}, time.Second, 10*time.Millisecond, "Partial lines should be written after a delay")
+
+	_, err = w.Write([]byte(" writer-secret-value"))
+	require.NoError(t, err)
+	flush()
+	assert.Equal(t, "progress: *** 50%\rprogress: 100% ***", out.String())
+}
+
+func TestSecretFrom(t *testing.T) {
+	t.Setenv("MODMAKE_TEST_SECRET", "env-secret-value")
+	secret, err := SecretFromEnv("MODMAKE_TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "env-secret-value", secret.Value())
+	_, err = SecretFromEnv("MODMAKE_TEST_SECRET_MISSING")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	file := Path(t.TempDir(), "token")
+	_, err = SecretFromFile(file)
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	require.NoError(t, file.WriteFile([]byte("file-secret-value\n"), 0600))
+	secret, err = SecretFromFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret-value", secret.Value())
+
+	provider := SecretProviderFunc(func(ctx context.Context, name string) (string, error) {
+		if name == "publish" {
+			return "provider-secret-value", nil
+		}
+		return "", ErrSecretNotFound
+	})
+	secret, err = SecretFrom(context.Background(), provider, "publish")
+	require.NoError(t, err)
+	assert.Equal(t, "provider-secret-value", secret.Value())
+	_, err = SecretFrom(context.Background(), provider, "missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	assert.Equal(t, "*** *** ***", Redact("env-secret-value file-secret-value provider-secret-value"))
+}
@@ -63,6 +63,7 @@ type Step struct {
 	shouldSkipDeps bool
 	build          *Build
 	dryRun         bool
+	hermetic       bool
 }
 
 func newStep(name, description string) *Step {
@@ -203,6 +204,9 @@ func (s *Step) Run(ctx context.Context) error {
 		log.Warn("Skipping step")
 		return nil
 	}
+	if s.hermetic {
+		ctx = WithHermeticEnv(ctx)
+	}
 
 	if len(s.beforeOp) > 0 {
 		log.Info("Running before hooks...")
@@ -276,6 +280,13 @@ func (s *Step) UnSkip() *Step {
 	return s
 }
 
+// Hermetic makes every [Command] run by this Step's hooks and operation start from a minimal environment, as described in [Command.Hermetic].
+// Dependencies are not affected.
+func (s *Step) Hermetic() *Step {
+	s.hermetic = true
+	return s
+}
+
 // SkipDependencies will prevent running dependency Steps for this Step.
 func (s *Step) SkipDependencies() *Step {
 	s.shouldSkipDeps = true
@@ -50,23 +50,23 @@ type stepLogger struct {
 
 func (l *stepLogger) Info(msg string, args ...any) {
 	msg = strings.TrimSuffix(msg, "\n")
-	log.Printf("%s%s\n", l.logPrefix(okColor), fmt.Sprintf(msg, args...))
+	log.Printf("%s%s\n", l.logPrefix(okColor), Redact(fmt.Sprintf(msg, args...)))
 }
 
 func (l *stepLogger) Warn(msg string, args ...any) {
 	msg = strings.TrimSuffix(msg, "\n")
-	log.Printf("%s%s %s\n", l.logPrefix(warnColor), warnColor("WARN"), fmt.Sprintf(msg, args...))
+	log.Printf("%s%s %s\n", l.logPrefix(warnColor), warnColor("WARN"), Redact(fmt.Sprintf(msg, args...)))
 }
 
 func (l *stepLogger) Error(msg string, args ...any) {
 	msg = strings.TrimSuffix(msg, "\n")
-	log.Printf("%s%s %s\n", l.logPrefix(errColor), errColor("ERROR"), fmt.Sprintf(msg, args...))
+	log.Printf("%s%s %s\n", l.logPrefix(errColor), errColor("ERROR"), Redact(fmt.Sprintf(msg, args...)))
 }
 
 func (l *stepLogger) Debug(msg string, args ...any) {
 	msg = strings.TrimSuffix(msg, "\n")
 	if _stepDebugLog {
-		log.Printf("%s%s %s\n", l.logPrefix(debugColor), debugColor("DEBUG"), fmt.Sprintf(msg, args...))
+		log.Printf("%s%s %s\n", l.logPrefix(debugColor), debugColor("DEBUG"), Redact(fmt.Sprintf(msg, args...)))
 	}
 }
 
@@ -154,8 +154,9 @@ type StepContextError struct {
 	LogGroup string
 }
 
+// Error returns the inner error's message, with registered secrets redacted.
 func (err *StepContextError) Error() string {
-	return err.inner.Error()
+	return Redact(err.inner.Error())
 }
 
 func (err *StepContextError) Unwrap() error {
@@ -2,23 +2,31 @@ package modmake
 
 import (
 	"archive/tar"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"time"
 )
 
-// TarArchive represents a Runner that performs operations on a tar archive that uses gzip compression.
+// TarArchive represents a Runner that performs operations on a tar archive, which may be compressed.
 // The Runner is created with Tar.
 type TarArchive struct {
-	err      error
-	path     PathString
-	addFiles map[PathString]string
+	err         error
+	path        PathString
+	addFiles    map[PathString]string
+	addDirs     []archiveDir
+	addSets     []archiveFileSet
+	modTime     time.Time
+	compression TarCompression
+	level       int
 }
 
-// Tar will create a new TarArchive to contextualize follow-on operations that act on a tar.gz file.
-// Adding a ".tar.gz" suffix to the location path string is recommended, but not required.
+// Tar will create a new TarArchive to contextualize follow-on operations that act on a tar file.
+// Compression used when creating the archive is zstd or xz if the location ends in ".tar.zst" or ".tar.xz", and gzip otherwise.
+// Use [TarArchive.Compression] to set it explicitly, like [TarNoCompression] for a plain tar archive.
+// See [TarCompression] for the executables needed by zstd and xz compression.
+// Compression is detected from the archive's content when extracting.
 func Tar(location PathString) *TarArchive {
 	if len(location) == 0 {
 		panic("empty location")
@@ -26,9 +34,28 @@ func Tar(location PathString) *TarArchive {
 	return &TarArchive{
 		path:     location,
 		addFiles: map[PathString]string{},
+		level:    -1,
 	}
 }
 
+// Compression sets the compression used when creating or updating the archive.
+func (t *TarArchive) Compression(compression TarCompression) *TarArchive {
+	compression.validate()
+	t.compression = compression
+	return t
+}
+
+// CompressionLevel sets the compression level, which must be valid for the selected compression.
+// Gzip supports levels 0-9, zstd supports 1-19, and xz supports 0-9.
+// The compression's default level is used if this isn't set.
+func (t *TarArchive) CompressionLevel(level int) *TarArchive {
+	if level < 0 {
+		panic("negative compression level")
+	}
+	t.level = level
+	return t
+}
+
 // AddFile adds the referenced file with the same archive path as what is given.
 // The archive path will be converted to slash format.
 func (t *TarArchive) AddFile(sourcePath PathString) *TarArchive {
@@ -53,12 +80,45 @@ func (t *TarArchive) AddFileWithPath(sourcePath, archivePath PathString) *TarArc
 	return t
 }
 
+// AddDir recursively adds the contents of the source directory, with archive paths under the archivePrefix.
+// The archivePrefix will be converted to slash format, and may be empty to add the contents at the root of the archive.
+// Directory entries are included in the archive, and symlinks are preserved unless options say otherwise.
+// The directory is walked when the archive is written, so it doesn't need to exist until then.
+func (t *TarArchive) AddDir(sourceDir, archivePrefix PathString, options ...ArchiveDirOption) *TarArchive {
+	if t.err != nil {
+		return t
+	}
+	t.addDirs = append(t.addDirs, newArchiveDir(sourceDir, archivePrefix, options))
+	return t
+}
+
+// AddFileSet adds the files selected by the [FileSet], with archive paths relative to its root under the archivePrefix.
+// The archivePrefix will be converted to slash format, and may be empty to add the files at the root of the archive.
+// The FileSet is evaluated when the archive is written.
+func (t *TarArchive) AddFileSet(set *FileSet, archivePrefix PathString) *TarArchive {
+	if t.err != nil {
+		return t
+	}
+	t.addSets = append(t.addSets, newArchiveFileSet(set, archivePrefix))
+	return t
+}
+
+// ModTime sets a fixed modification time for all entries, and clears ownership information, so the archive is reproducible.
+// Entries are always written in sorted order.
+func (t *TarArchive) ModTime(modTime time.Time) *TarArchive {
+	t.modTime = modTime
+	return t
+}
+
 // Create will return a Runner that creates a new tar file with the given files loaded.
 // If a file with the given name already exists, then it will be truncated first.
 // Ensure that all files referenced with AddFile (or AddFileWithPath) and directories exist before running this Runner, because it doesn't try to create them.
 func (t *TarArchive) Create() Task {
 	runner := Task(func(ctx context.Context) error {
 		ctx, log := WithGroup(ctx, "tar create")
+		if err := requireCompressor(t.createCompression()); err != nil {
+			return log.WrapErr(err)
+		}
 		tarFile, err := t.path.Create()
 		if err != nil {
 			return log.WrapErr(err)
@@ -66,19 +126,7 @@ func (t *TarArchive) Create() Task {
 		defer func() {
 			_ = tarFile.Close()
 		}()
-		gz := gzip.NewWriter(tarFile)
-		defer func() {
-			_ = gz.Close()
-		}()
-		tw := tar.NewWriter(gz)
-		defer func() {
-			_ = tw.Close()
-		}()
-		err = t.writeFilesToTarArchive(ctx, tw)
-		if err != nil {
-			return log.WrapErr(err)
-		}
-		return nil
+		return log.WrapErr(t.writeArchive(ctx, tarFile))
 	})
 	return ContextAware(runner)
 }
@@ -90,6 +138,9 @@ func (t *TarArchive) Create() Task {
 func (t *TarArchive) Update() Task {
 	runner := Task(func(ctx context.Context) error {
 		ctx, log := WithGroup(ctx, "tar update")
+		if err := requireCompressor(t.createCompression()); err != nil {
+			return log.WrapErr(err)
+		}
 		tarFile, err := t.path.OpenFile(os.O_RDWR, 0600)
 		if err != nil {
 			return log.WrapErr(err)
@@ -97,57 +148,85 @@ func (t *TarArchive) Update() Task {
 		defer func() {
 			_ = tarFile.Close()
 		}()
-		gz := gzip.NewWriter(tarFile)
-		defer func() {
-			_ = gz.Close()
-		}()
-		tw := tar.NewWriter(gz)
-		defer func() {
-			_ = tw.Close()
-		}()
-		err = t.writeFilesToTarArchive(ctx, tw)
-		if err != nil {
-			return log.WrapErr(err)
-		}
-		return nil
+		return log.WrapErr(t.writeArchive(ctx, tarFile))
 	})
 	return ContextAware(runner)
 }
 
+// createCompression returns the compression used when creating or updating the archive.
+func (t *TarArchive) createCompression() TarCompression {
+	if len(t.compression) == 0 {
+		return tarCompressionFromPath(t.path)
+	}
+	return t.compression
+}
+
+// writeArchive compresses and writes all entries to the file, and truncates any remaining data.
+func (t *TarArchive) writeArchive(ctx context.Context, tarFile *os.File) error {
+	cw, err := compressWriter(ctx, tarFile, t.createCompression(), t.level)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(cw)
+	if err := t.writeFilesToTarArchive(ctx, tw); err != nil {
+		_ = tw.Close()
+		_ = cw.Close()
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		_ = cw.Close()
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	offset, err := tarFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	return tarFile.Truncate(offset)
+}
+
 func (t *TarArchive) writeFilesToTarArchive(ctx context.Context, tw *tar.Writer) error {
 	ctx, log := WithGroup(ctx, "write files")
-	for source, target := range t.addFiles {
+	entries, err := collectArchiveEntries(t.addFiles, t.addDirs, t.addSets)
+	if err != nil {
+		return log.WrapErr(err)
+	}
+	for _, entry := range entries {
 		if err := ctx.Err(); err != nil {
 			return log.WrapErr(err)
 		}
-		source, target := source, target
+		entry := entry
 		err := func() error {
-			if !source.Exists() {
-				return fmt.Errorf("unable to locate source file: %s", source)
+			header, err := tar.FileInfoHeader(entry.info, entry.linkname)
+			if err != nil {
+				return fmt.Errorf("failed to get file info for '%s': %w", entry.source, err)
+			}
+			header.Name = entry.name
+			header.Mode = int64(entry.perm())
+			if !t.modTime.IsZero() {
+				header.ModTime = t.modTime
+				header.AccessTime = time.Time{}
+				header.ChangeTime = time.Time{}
+				header.Uid, header.Gid = 0, 0
+				header.Uname, header.Gname = "", ""
 			}
-			f, err := source.Open()
+			if entry.isDir() || entry.isSymlink() {
+				return tw.WriteHeader(header)
+			}
+			f, err := entry.source.Open()
 			if err != nil {
 				return err
 			}
 			defer func() {
 				_ = f.Close()
 			}()
-			fi, err := f.Stat()
-			if err != nil {
-				return err
-			}
-			header, err := tar.FileInfoHeader(fi, target)
-			if err != nil {
-				return fmt.Errorf("failed to get file info for '%s': %w", source, err)
-			}
 			if err := tw.WriteHeader(header); err != nil {
 				return err
 			}
-			_, err = io.Copy(tw, f)
-			if err != nil {
-				return err
-			}
-			return nil
+			_, err = io.CopyN(tw, f, header.Size)
+			return err
 		}()
 		if err != nil {
 			return log.WrapErr(err)
@@ -157,8 +236,13 @@ func (t *TarArchive) writeFilesToTarArchive(ctx context.Context, tw *tar.Writer)
 }
 
 // Extract will extract the named tar archive to the given directory.
+// The archive may be uncompressed, or use gzip, zstd, xz, or bzip2 compression, which is detected automatically.
 // Any errors encountered while doing so will be immediately returned.
-func (t *TarArchive) Extract(extractDir PathString) Task {
+//
+// Entries that would be written outside the extraction directory are rejected with [ErrUnsafeArchiveEntry], including through symlinks and hard links.
+// File modes and modification times are restored, and size and entry limits are enforced to guard against decompression bombs.
+// Options may be passed to change these limits, or to allow unsafe entries from a trusted archive.
+func (t *TarArchive) Extract(extractDir PathString, options ...ExtractOption) Task {
 	runner := Task(func(ctx context.Context) error {
 		ctx, log := WithGroup(ctx, "tar extract")
 		src, err := t.path.Open()
@@ -168,15 +252,18 @@ func (t *TarArchive) Extract(extractDir PathString) Task {
 		defer func() {
 			_ = src.Close()
 		}()
-		err = extractDir.MkdirAll(0700)
+		ex, err := newExtractor(extractDir, options)
 		if err != nil {
-			return log.WrapErr(fmt.Errorf("unable to create extraction directory: %w", err))
+			return log.WrapErr(err)
 		}
-		gz, err := gzip.NewReader(src)
+		dr, _, err := decompressReader(ctx, src)
 		if err != nil {
-			return log.WrapErr(fmt.Errorf("unable to create gzip reader for '%s': %w", t.path, err))
+			return log.WrapErr(fmt.Errorf("unable to create decompressing reader for '%s': %w", t.path, err))
 		}
-		tr := tar.NewReader(gz)
+		defer func() {
+			_ = dr.Close()
+		}()
+		tr := tar.NewReader(dr)
 		for {
 			header, err := tr.Next()
 			if err == io.EOF {
@@ -184,38 +271,31 @@ func (t *TarArchive) Extract(extractDir PathString) Task {
 				break
 			}
 			if err != nil {
-				return err
-			}
-			if header.FileInfo().IsDir() {
-				continue
+				return log.WrapErr(err)
 			}
 			if err := ctx.Err(); err != nil {
 				return err
 			}
-			err = func() error {
-				output := extractDir.Join(header.Name)
-				outputDir := output.Dir()
-				if err := outputDir.MkdirAll(0700); err != nil {
-					return fmt.Errorf("failed to make parent directory for file '%s' at '%s': %w", header.Name, outputDir, err)
-				}
-				out, err := output.Create()
-				if err != nil {
-					return fmt.Errorf("failed to create file '%s': %w", output, err)
-				}
-				defer func() {
-					_ = out.Close()
-				}()
-				_, err = io.CopyN(out, tr, header.Size)
-				if err != nil {
-					return fmt.Errorf("failed to extract '%s': %w", output, err)
-				}
-				return nil
-			}()
+			switch header.Typeflag {
+			case tar.TypeDir:
+				err = ex.dir(header.Name, header.FileInfo().Mode(), header.ModTime)
+			case tar.TypeReg:
+				err = ex.file(header.Name, header.FileInfo().Mode(), header.ModTime, tr)
+			case tar.TypeSymlink:
+				err = ex.symlink(header.Name, header.Linkname)
+			case tar.TypeLink:
+				err = ex.hardlink(header.Name, header.Linkname)
+			case tar.TypeXGlobalHeader:
+				continue
+			default:
+				log.Warn("Skipping unsupported entry '%s' with type '%c'", header.Name, header.Typeflag)
+				continue
+			}
 			if err != nil {
 				return log.WrapErr(err)
 			}
 		}
-		return nil
+		return log.WrapErr(ex.finish())
 	})
 	return ContextAware(runner)
 }
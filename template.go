@@ -0,0 +1,204 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrRequiredValue is returned when rendering a template that calls "required" with an empty value.
+var ErrRequiredValue = errors.New("required value missing")
+
+// TemplateExt is the file extension that identifies templates in [RenderTemplateDir].
+const TemplateExt = ".tmpl"
+
+// TemplateFuncs returns the functions available to templates rendered with [RenderTemplate] and [RenderTemplateDir], so they can be used with other templates too.
+// The env parameter is merged over the environment for lookups, like the optional EnvMap passed to [F], and may be nil.
+//
+// Environment functions:
+//   - env NAME: the value of the environment variable NAME, or an empty string. Names are case-insensitive.
+//   - envOr NAME DEFAULT: the value of the environment variable NAME, or DEFAULT if it's unset or empty.
+//   - F STRING: interpolates ${VAR:default} references in STRING with [FErr], failing rendering if a required variable is missing.
+//
+// Value functions:
+//   - required MESSAGE VALUE: returns VALUE, or fails rendering with [ErrRequiredValue] and MESSAGE if VALUE is empty.
+//   - default DEFAULT VALUE: returns VALUE, or DEFAULT if VALUE is empty.
+//   - upper, lower, trim, trimPrefix PREFIX, trimSuffix SUFFIX, replace OLD NEW, contains SUBSTR, split SEP, and join SEP operate on strings like their [strings] package counterparts, taking the string last so they can be used in pipelines.
+//   - now: the current time.
+//
+// Git functions, which fail rendering if git isn't available or the working directory isn't in a repository:
+//   - gitCommit: the full hash of the HEAD commit.
+//   - gitShortCommit: the abbreviated hash of the HEAD commit.
+//   - gitBranch: the name of the current branch.
+//   - version: a version string from "git describe --tags --always --dirty", like "v1.2.3-4-gabc1234-dirty".
+//
+// Path functions, operating on strings or [PathString]s and returning strings:
+//   - base, dir, ext, toSlash, and fromSlash work like their [filepath] counterparts.
+//   - joinPath ELEM...: joins path elements with [filepath.Join].
+//   - abs PATH: the absolute form of PATH.
+//   - readFile PATH: the content of the file at PATH.
+func TemplateFuncs(ctx context.Context, env EnvMap) template.FuncMap {
+	lookup := func(name string) string {
+		m := Environment()
+		m.merge(env)
+		return m[strings.ToUpper(name)]
+	}
+	git := func(args ...string) (string, error) {
+		var buf strings.Builder
+		if err := Exec(append([]string{"git"}, args...)...).LogGroup("git").Stdout(&buf).Stderr(io.Discard).Run(ctx); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	empty := func(val any) bool {
+		if val == nil {
+			return true
+		}
+		return len(fmt.Sprint(val)) == 0
+	}
+	return template.FuncMap{
+		"env": lookup,
+		"envOr": func(name, def string) string {
+			if val := lookup(name); len(val) > 0 {
+				return val
+			}
+			return def
+		},
+		"F": func(s string) (string, error) {
+			return FErr(s, env)
+		},
+		"required": func(msg string, val any) (any, error) {
+			if empty(val) {
+				return nil, fmt.Errorf("%w: %s", ErrRequiredValue, msg)
+			}
+			return val, nil
+		},
+		"default": func(def, val any) any {
+			if empty(val) {
+				return def
+			}
+			return val
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+		"now":        time.Now,
+		"gitCommit": func() (string, error) {
+			return git("rev-parse", "HEAD")
+		},
+		"gitShortCommit": func() (string, error) {
+			return git("rev-parse", "--short", "HEAD")
+		},
+		"gitBranch": func() (string, error) {
+			return git("rev-parse", "--abbrev-ref", "HEAD")
+		},
+		"version": func() (string, error) {
+			return git("describe", "--tags", "--always", "--dirty")
+		},
+		"base":      func(p any) string { return filepath.Base(fmt.Sprint(p)) },
+		"dir":       func(p any) string { return filepath.Dir(fmt.Sprint(p)) },
+		"ext":       func(p any) string { return filepath.Ext(fmt.Sprint(p)) },
+		"toSlash":   func(p any) string { return filepath.ToSlash(fmt.Sprint(p)) },
+		"fromSlash": func(p any) string { return filepath.FromSlash(fmt.Sprint(p)) },
+		"joinPath": func(elems ...any) string {
+			parts := make([]string, len(elems))
+			for i, elem := range elems {
+				parts[i] = fmt.Sprint(elem)
+			}
+			return filepath.Join(parts...)
+		},
+		"abs": func(p any) (string, error) {
+			return filepath.Abs(fmt.Sprint(p))
+		},
+		"readFile": func(p any) (string, error) {
+			data, err := Path(fmt.Sprint(p)).ReadFile()
+			return string(data), err
+		},
+	}
+}
+
+// RenderTemplate creates a Task that renders the [text/template] file at src to dst with the given data, creating dst's directory if needed.
+// Templates may use the functions described in [TemplateFuncs], and referencing a missing map key is an error.
+// If data is an EnvMap, then it's also used by the env, envOr, and F functions.
+// The rendered file has the same permission bits as src, so an executable template produces an executable script.
+// Nothing is written if rendering fails.
+func RenderTemplate(src, dst PathString, data any) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "render template")
+		if err := renderTemplate(ctx, src, dst, data); err != nil {
+			return log.WrapErr(err)
+		}
+		log.Debug("Rendered '%s' to '%s'", src, dst)
+		return nil
+	}
+}
+
+// RenderTemplateDir creates a Task that recursively renders each file ending with [TemplateExt] in srcDir to the same relative path in dstDir, without the extension.
+// Other files are copied as they are, so a directory of config files and scripts may be assembled in one step.
+// See [RenderTemplate] for details about rendering.
+func RenderTemplateDir(srcDir, dstDir PathString, data any) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "render template dir")
+		var rendered, copied int
+		err := srcDir.Walk(func(file PathString, rel string, d fs.DirEntry) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			target := dstDir.Join(rel)
+			if strings.HasSuffix(rel, TemplateExt) {
+				rendered++
+				return renderTemplate(ctx, file, Path(strings.TrimSuffix(target.String(), TemplateExt)), data)
+			}
+			if err := target.Dir().MkdirAll(0755); err != nil {
+				return err
+			}
+			copied++
+			return file.CopyTo(target)
+		})
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		log.Info("Rendered %d template(s) and copied %d file(s) to '%s'", rendered, copied, dstDir)
+		return nil
+	}
+}
+
+func renderTemplate(ctx context.Context, src, dst PathString, data any) error {
+	content, err := src.ReadFile()
+	if err != nil {
+		return fmt.Errorf("failed to read template '%s': %w", src, err)
+	}
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	env, _ := data.(EnvMap)
+	tmpl, err := template.New(src.Base().String()).
+		Option("missingkey=error").
+		Funcs(TemplateFuncs(ctx, env)).
+		Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse template '%s': %w", src, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render template '%s': %w", src, err)
+	}
+	return installFile(&buf, dst, fi.Mode().Perm(), time.Time{})
+}
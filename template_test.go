@@ -0,0 +1,111 @@
+package modmake
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	t.Setenv("MODMAKE_TEMPLATE_USER", "service")
+	src := tmp.Join("app.service.tmpl")
+	require.NoError(t, src.WriteFile([]byte(`[Service]
+User={{ env "modmake_template_user" }}
+Group={{ envOr "MODMAKE_TEMPLATE_GROUP" "nogroup" }}
+ExecStart={{ joinPath "/opt" .NAME "bin" (base .BINARY) | toSlash }}
+{{- range split "," .PORTS }}
+Port={{ . }}
+{{- end }}
+{{- if eq .DEBUG "true" }}
+Environment=DEBUG=1
+{{- end }}
+Description={{ F "${NAME} v${VERSION:dev}" | upper }}
+`), 0644))
+	dst := tmp.Join("out", "app.service")
+	data := EnvMap{"NAME": "app", "BINARY": "build/app", "PORTS": "80,443", "DEBUG": "true", "MODMAKE_TEMPLATE_GROUP": "staff"}
+	require.NoError(t, RenderTemplate(src, dst, data).Run(ctx))
+	rendered, err := dst.ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, `[Service]
+User=service
+Group=staff
+ExecStart=/opt/app/bin/app
+Port=80
+Port=443
+Environment=DEBUG=1
+Description=APP VDEV
+`, string(rendered))
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	src := tmp.Join("config.tmpl")
+	dst := tmp.Join("config")
+
+	require.NoError(t, src.WriteFile([]byte(`{{ required "a database URL must be set" .DB_URL }}`), 0644))
+	err := RenderTemplate(src, dst, map[string]string{"DB_URL": ""}).Run(ctx)
+	assert.ErrorIs(t, err, ErrRequiredValue)
+	assert.ErrorContains(t, err, "a database URL must be set")
+	assert.False(t, dst.Exists(), "Nothing should be written if rendering fails")
+	require.NoError(t, RenderTemplate(src, dst, map[string]string{"DB_URL": "postgres://db"}).Run(ctx))
+	rendered, err := dst.ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db", string(rendered))
+
+	require.NoError(t, src.WriteFile([]byte(`{{ .MISSING }}`), 0644))
+	err = RenderTemplate(src, tmp.Join("missing"), map[string]string{}).Run(ctx)
+	assert.ErrorContains(t, err, "MISSING")
+
+	require.NoError(t, src.WriteFile([]byte(`{{ .Unclosed `), 0644))
+	err = RenderTemplate(src, tmp.Join("unclosed"), nil).Run(ctx)
+	assert.ErrorContains(t, err, "failed to parse template")
+}
+
+func TestRenderTemplate_Git(t *testing.T) {
+	requireExecutable(t, "git")
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	src := tmp.Join("version.tmpl")
+	require.NoError(t, src.WriteFile([]byte(`{{ gitCommit }} {{ gitShortCommit }} {{ version }}`), 0644))
+	err := RenderTemplate(src, tmp.Join("version"), nil).Run(ctx)
+	if err != nil {
+		t.Skipf("Not running in a git repository: %v", err)
+	}
+	rendered, err := tmp.Join("version").ReadFile()
+	require.NoError(t, err)
+	fields := strings.Fields(string(rendered))
+	require.Len(t, fields, 3)
+	assert.Len(t, fields[0], 40)
+	assert.True(t, strings.HasPrefix(fields[0], fields[1]))
+	assert.NotEmpty(t, fields[2])
+}
+
+func TestRenderTemplateDir(t *testing.T) {
+	ctx := context.Background()
+	tmp := Path(t.TempDir())
+	src := tmp.Join("src")
+	require.NoError(t, src.Join("bin").MkdirAll(0755))
+	require.NoError(t, src.Join("bin", "install.sh.tmpl").WriteFile([]byte("#!/bin/sh\ncp {{ .NAME }} /usr/local/bin\n"), 0755))
+	require.NoError(t, src.Join("LICENSE").WriteFile([]byte("license"), 0644))
+	dst := tmp.Join("dst")
+
+	require.NoError(t, RenderTemplateDir(src, dst, EnvMap{"NAME": "app"}).Run(ctx))
+	rendered, err := dst.Join("bin", "install.sh").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\ncp app /usr/local/bin\n", string(rendered))
+	assert.True(t, dst.Join("LICENSE").IsFile())
+	assert.False(t, dst.Join("bin", "install.sh.tmpl").Exists())
+	if runtime.GOOS != "windows" {
+		fi, err := dst.Join("bin", "install.sh").Stat()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), fi.Mode().Perm(), "Rendered templates should keep the template's mode")
+	}
+}
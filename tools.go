@@ -0,0 +1,474 @@
+package modmake
+
+import (
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/saylorsolutions/modmake/assert"
+)
+
+const (
+	// DefaultToolsDir is the directory, relative to the module root, where a ToolManager installs tools by default.
+	DefaultToolsDir = ".modmake/bin"
+	// DefaultToolsLockFile is the file, relative to the module root, where a ToolManager records resolved tool versions and hashes by default.
+	// This file should be committed to source control.
+	DefaultToolsLockFile = "modmake-tools.lock"
+
+	toolSourceGo       = "go"
+	toolSourceDownload = "download"
+)
+
+var (
+	ErrToolNotDeclared = errors.New("tool not declared")
+)
+
+// ToolManager installs declared tools into a project-local directory, and records resolved versions and hashes in a lockfile.
+// This keeps tool versions consistent between projects and runs, without relying on a shared GOBIN.
+// Use NewToolManager or [Build.ToolManager] to create a ToolManager.
+//
+//	tools := b.ToolManager().
+//		GoTool("stringer", "golang.org/x/tools/cmd/stringer", "v0.22.0")
+//	b.Generate().DependsOnRunner("stringer", "", tools.Command("stringer", "-type", "Kind"))
+type ToolManager struct {
+	mux      sync.Mutex
+	binDir   PathString
+	lockFile PathString
+	tools    []*Tool
+}
+
+// Tool is a tool declared with a ToolManager.
+type Tool struct {
+	name    string
+	source  string
+	module  string
+	version string
+	url     string
+	member  string
+	sha256  map[string]string
+}
+
+// NewToolManager creates a ToolManager that installs tools to [DefaultToolsDir], with a lockfile at [DefaultToolsLockFile].
+func NewToolManager() *ToolManager {
+	root := Go().ModuleRoot()
+	return &ToolManager{
+		binDir:   root.Join(DefaultToolsDir),
+		lockFile: root.Join(DefaultToolsLockFile),
+	}
+}
+
+// BinDir overrides the directory where tools are installed.
+func (m *ToolManager) BinDir(dir PathString) *ToolManager {
+	if len(dir) == 0 {
+		panic("empty tool directory")
+	}
+	m.binDir = dir
+	return m
+}
+
+// LockFile overrides the location of the lockfile.
+func (m *ToolManager) LockFile(file PathString) *ToolManager {
+	if len(file) == 0 {
+		panic("empty lock file")
+	}
+	m.lockFile = file
+	return m
+}
+
+func (m *ToolManager) declare(tool *Tool) *Tool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for i, existing := range m.tools {
+		if existing.name == tool.name {
+			if existing.source != tool.source || existing.module != tool.module || existing.url != tool.url {
+				panic(fmt.Sprintf("tool '%s' is already declared with a different source", tool.name))
+			}
+			m.tools[i] = tool
+			return tool
+		}
+	}
+	m.tools = append(m.tools, tool)
+	return tool
+}
+
+// GoTool declares a tool installed with 'go install pkg@version'.
+// The version may be "latest", in which case the resolved version is recorded in the lockfile and used for later installs.
+func (m *ToolManager) GoTool(name, pkg, version string) *ToolManager {
+	assert.NotEmpty(&name)
+	assert.NotEmpty(&pkg)
+	assert.NotEmpty(&version)
+	m.declare(&Tool{
+		name:    name,
+		source:  toolSourceGo,
+		module:  pkg,
+		version: version,
+	})
+	return m
+}
+
+// DownloadTool declares a tool that's downloaded from a URL, which may reference a release archive (.tar.gz, .tar.zst, .tar.xz, .tar.bz2, .tar, or .zip), or an executable.
+// The URL is expanded with [F], and may reference these variables in addition to the environment:
+//   - VERSION: The given version.
+//   - OS: The host's GOOS.
+//   - ARCH: The host's GOARCH.
+//   - EXE: ".exe" on windows, and empty otherwise.
+//
+// Use [Tool.SHA256] to set expected checksums of the download.
+// Checksums of downloads are always recorded in the lockfile, and verified on later installs.
+func (m *ToolManager) DownloadTool(name, version, url string) *Tool {
+	assert.NotEmpty(&name)
+	assert.NotEmpty(&version)
+	assert.NotEmpty(&url)
+	return m.declare(&Tool{
+		name:    name,
+		source:  toolSourceDownload,
+		version: version,
+		url:     url,
+		sha256:  map[string]string{},
+	})
+}
+
+// SHA256 sets the expected checksum of a download for a platform in "GOOS/GOARCH" format.
+func (t *Tool) SHA256(platform, checksum string) *Tool {
+	t.sha256[platform] = strings.ToLower(strings.TrimSpace(checksum))
+	return t
+}
+
+// Member sets the path to the executable within a downloaded archive.
+// By default, the archive is searched for a file with the tool's name.
+func (t *Tool) Member(path string) *Tool {
+	t.member = filepath.ToSlash(path)
+	return t
+}
+
+func (m *ToolManager) tool(name string) (*Tool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, tool := range m.tools {
+		if tool.name == name {
+			return tool, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrToolNotDeclared, name)
+}
+
+// Path returns the installed location of the named tool.
+func (m *ToolManager) Path(name string) PathString {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return m.binDir.Join(name)
+}
+
+// Command creates a Command that invokes the named tool.
+// The tool directory is prepended to the PATH, so tools are able to invoke other managed tools.
+// Tools must be installed before the Command runs, usually by the tools step (see [Build.ToolManager]).
+func (m *ToolManager) Command(name string, args ...string) *Command {
+	abs, err := m.binDir.Abs()
+	if err != nil {
+		abs = m.binDir
+	}
+	cmd := Exec(m.Path(name).String()).
+		Arg(args...).
+		Env("PATH", abs.String()+string(os.PathListSeparator)+os.Getenv("PATH")).
+		LogGroup(name)
+	if _, err := m.tool(name); err != nil {
+		cmd.err = err
+	}
+	return cmd
+}
+
+// Install creates a Task that installs all declared tools that aren't already installed at their locked version, and updates the lockfile.
+func (m *ToolManager) Install() Task {
+	return m.install(false)
+}
+
+// Update creates a Task that re-resolves and installs all declared tools, ignoring versions in the lockfile.
+// Checksums of downloads are recorded again, so this should only be used when intentionally changing versions.
+func (m *ToolManager) Update() Task {
+	return m.install(true)
+}
+
+// InstallTool creates a Task that installs a single tool like [ToolManager.Install].
+func (m *ToolManager) InstallTool(name string) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "install "+name)
+		tool, err := m.tool(name)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		return log.WrapErr(m.installTools(ctx, false, tool))
+	}
+}
+
+func (m *ToolManager) install(update bool) Task {
+	return func(ctx context.Context) error {
+		ctx, log := WithGroup(ctx, "install tools")
+		m.mux.Lock()
+		tools := append([]*Tool{}, m.tools...)
+		m.mux.Unlock()
+		return log.WrapErr(m.installTools(ctx, update, tools...))
+	}
+}
+
+// ToolLock is the structure of the lockfile written by a ToolManager.
+type ToolLock struct {
+	Tools map[string]LockedTool `json:"tools"`
+}
+
+// LockedTool records how a tool was resolved.
+type LockedTool struct {
+	Source    string `json:"source"`
+	Package   string `json:"package,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Requested string `json:"requested"`
+	Version   string `json:"version"`
+	// Sum is the go.sum hash of the module providing a Go tool.
+	Sum string `json:"sum,omitempty"`
+	// SHA256 maps "GOOS/GOARCH" platforms to the checksum of a downloaded tool.
+	SHA256 map[string]string `json:"sha256,omitempty"`
+}
+
+func (m *ToolManager) readLock() (*ToolLock, error) {
+	lock := &ToolLock{Tools: map[string]LockedTool{}}
+	data, err := m.lockFile.ReadFile()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return lock, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse tool lock file '%s': %w", m.lockFile, err)
+	}
+	if lock.Tools == nil {
+		lock.Tools = map[string]LockedTool{}
+	}
+	return lock, nil
+}
+
+func (m *ToolManager) writeLock(lock *ToolLock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.lockFile.WriteFile(append(data, '\n'), 0644)
+}
+
+func (m *ToolManager) installTools(ctx context.Context, update bool, tools ...*Tool) error {
+	// Serialize installs, since they share the lockfile.
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if err := m.binDir.MkdirAll(0755); err != nil {
+		return fmt.Errorf("failed to create tool directory '%s': %w", m.binDir, err)
+	}
+	lock, err := m.readLock()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, tool := range tools {
+		var (
+			locked, hasLock = lock.Tools[tool.name]
+			resolved        LockedTool
+		)
+		if update || !hasLock || !tool.matchesLock(locked) {
+			locked = LockedTool{}
+		}
+		switch tool.source {
+		case toolSourceGo:
+			resolved, err = m.installGoTool(ctx, tool, locked, update)
+		default:
+			resolved, err = m.installDownloadTool(ctx, tool, locked, update)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to install tool '%s': %w", tool.name, err)
+		}
+		if !hasLock || !resolved.equal(lock.Tools[tool.name]) {
+			lock.Tools[tool.name] = resolved
+			changed = true
+		}
+	}
+	if changed {
+		if err := m.writeLock(lock); err != nil {
+			return fmt.Errorf("failed to write tool lock file '%s': %w", m.lockFile, err)
+		}
+	}
+	return nil
+}
+
+func (t *Tool) matchesLock(locked LockedTool) bool {
+	return locked.Source == t.source &&
+		locked.Package == t.module &&
+		locked.URL == t.url &&
+		locked.Requested == t.version
+}
+
+func (l LockedTool) equal(other LockedTool) bool {
+	if l.Source != other.Source || l.Package != other.Package || l.URL != other.URL ||
+		l.Requested != other.Requested || l.Version != other.Version || l.Sum != other.Sum ||
+		len(l.SHA256) != len(other.SHA256) {
+		return false
+	}
+	for platform, sum := range l.SHA256 {
+		if other.SHA256[platform] != sum {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *ToolManager) installGoTool(ctx context.Context, tool *Tool, locked LockedTool, force bool) (LockedTool, error) {
+	_, log := WithGroup(ctx, tool.name)
+	version := tool.version
+	if len(locked.Version) > 0 {
+		version = locked.Version
+	}
+	path := m.Path(tool.name)
+	resolve := func(info *buildinfo.BuildInfo) LockedTool {
+		return LockedTool{
+			Source:    toolSourceGo,
+			Package:   tool.module,
+			Requested: tool.version,
+			Version:   info.Main.Version,
+			Sum:       info.Main.Sum,
+		}
+	}
+	if info, err := buildinfo.ReadFile(path.String()); err == nil && !force && info.Path == tool.module && info.Main.Version == version {
+		log.Debug("%s@%s is already installed", tool.module, version)
+		return resolve(info), nil
+	}
+
+	binDir, err := m.binDir.Abs()
+	if err != nil {
+		return LockedTool{}, err
+	}
+	log.Info("Installing %s@%s", tool.module, version)
+	if err := Go().Install(tool.module+"@"+version).Env("GOBIN", binDir.String()).Run(ctx); err != nil {
+		return LockedTool{}, err
+	}
+	info, err := buildinfo.ReadFile(path.String())
+	if err != nil {
+		return LockedTool{}, fmt.Errorf("failed to read build info from '%s': %w", path, err)
+	}
+	if len(locked.Sum) > 0 && info.Main.Sum != locked.Sum {
+		return LockedTool{}, fmt.Errorf("%w: module %s@%s has sum %s, but %s was locked", ErrChecksumMismatch, info.Main.Path, info.Main.Version, info.Main.Sum, locked.Sum)
+	}
+	return resolve(info), nil
+}
+
+func (m *ToolManager) installDownloadTool(ctx context.Context, tool *Tool, locked LockedTool, force bool) (LockedTool, error) {
+	ctx, log := WithGroup(ctx, tool.name)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	expected := tool.sha256[platform]
+	if len(expected) == 0 {
+		expected = locked.SHA256[platform]
+	}
+	if len(locked.SHA256[platform]) > 0 && len(expected) > 0 && locked.SHA256[platform] != expected {
+		return LockedTool{}, fmt.Errorf("%w: configured checksum %s does not match locked checksum %s", ErrChecksumMismatch, expected, locked.SHA256[platform])
+	}
+	resolved := LockedTool{
+		Source:    toolSourceDownload,
+		URL:       tool.url,
+		Requested: tool.version,
+		Version:   tool.version,
+		SHA256:    map[string]string{},
+	}
+	for p, sum := range locked.SHA256 {
+		resolved.SHA256[p] = sum
+	}
+
+	path := m.Path(tool.name)
+	stamp := m.binDir.Join("." + tool.name + ".stamp")
+	if data, err := stamp.ReadFile(); err == nil && !force && path.IsFile() && len(expected) > 0 && string(data) == tool.version+" "+expected {
+		log.Debug("%s %s is already installed", tool.name, tool.version)
+		resolved.SHA256[platform] = expected
+		return resolved, nil
+	}
+
+	exe := ""
+	if runtime.GOOS == "windows" {
+		exe = ".exe"
+	}
+	tmp, err := os.MkdirTemp("", "modmake-tool-*")
+	if err != nil {
+		return LockedTool{}, err
+	}
+	tmpDir := Path(tmp)
+	defer func() {
+		_ = tmpDir.RemoveAll()
+	}()
+	release := InstallRelease(tool.url, tmpDir).
+		Var("VERSION", tool.version).
+		Name(tool.name + exe)
+	if len(expected) > 0 {
+		release.SHA256(platform, expected)
+	}
+	log.Info("Installing %s %s", tool.name, tool.version)
+	actual, err := release.install(ctx, log)
+	if err != nil {
+		return LockedTool{}, err
+	}
+	executable, err := tool.findMember(tmpDir, exe)
+	if err != nil {
+		return LockedTool{}, err
+	}
+	if err := executable.CopyTo(path); err != nil {
+		return LockedTool{}, err
+	}
+	if err := os.Chmod(path.String(), 0755); err != nil {
+		return LockedTool{}, err
+	}
+	if err := stamp.WriteFile([]byte(tool.version+" "+actual), 0644); err != nil {
+		return LockedTool{}, err
+	}
+	resolved.SHA256[platform] = actual
+	return resolved, nil
+}
+
+// findMember locates the tool's executable in an extracted archive.
+func (t *Tool) findMember(dir PathString, exe string) (PathString, error) {
+	if len(t.member) > 0 {
+		member := dir.Join(filepath.FromSlash(t.member))
+		if !member.IsFile() {
+			return "", fmt.Errorf("archive does not contain '%s'", t.member)
+		}
+		return member, nil
+	}
+	var found PathString
+	err := filepath.WalkDir(dir.String(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (d.Name() == t.name+exe || d.Name() == t.name) {
+			found = Path(path)
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("unable to find '%s' in archive, use Member to specify its path", t.name+exe)
+	}
+	return found, nil
+}
+
+// ToolManager returns the ToolManager for this Build, creating it if needed.
+// The first call adds an "install-tools" step as a dependency of the tools step, which installs all declared tools.
+func (b *Build) ToolManager() *ToolManager {
+	if b.toolManager == nil {
+		b.toolManager = NewToolManager()
+		b.Tools().DependsOnRunner("install-tools", "Installs managed tools to "+DefaultToolsDir, b.toolManager.Install())
+	}
+	return b.toolManager
+}
@@ -0,0 +1,145 @@
+package modmake
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testToolArchive(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "tool-1.0.0/" + name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func readToolLock(t *testing.T, m *ToolManager) *ToolLock {
+	lock, err := m.readLock()
+	require.NoError(t, err)
+	return lock
+}
+
+func TestToolManager_DownloadTool(t *testing.T) {
+	toolName := "mytool"
+	if runtime.GOOS == "windows" {
+		toolName += ".exe"
+	}
+	archive := testToolArchive(t, toolName, []byte("#!/bin/sh\necho hello\n"))
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+	var (
+		mux      sync.Mutex
+		requests []string
+	)
+	requestCount := func() int {
+		mux.Lock()
+		defer mux.Unlock()
+		return len(requests)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		requests = append(requests, r.URL.Path)
+		mux.Unlock()
+		switch r.URL.Path {
+		case "/v1.0.0/mytool_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.gz":
+			_, _ = w.Write(archive)
+		case "/v1.0.0/raw":
+			_, _ = w.Write([]byte("raw tool"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tmp := Path(t.TempDir())
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	m := NewToolManager().BinDir(tmp.Join("bin")).LockFile(tmp.Join("tools.lock"))
+	m.DownloadTool("mytool", "1.0.0", srv.URL+"/v${VERSION}/mytool_${OS}_${ARCH}.tar.gz").SHA256(platform, checksum)
+	m.DownloadTool("rawtool", "1.0.0", srv.URL+"/v${VERSION}/raw")
+	ctx := context.Background()
+
+	require.NoError(t, m.Install().Run(ctx))
+	assert.Equal(t, 2, requestCount())
+	assert.True(t, m.Path("mytool").IsFile())
+	data, err := m.Path("rawtool").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "raw tool", string(data))
+	fi, err := m.Path("mytool").Stat()
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Mode()&0100, "Tools should be executable")
+
+	lock := readToolLock(t, m)
+	assert.Equal(t, checksum, lock.Tools["mytool"].SHA256[platform])
+	assert.Equal(t, "1.0.0", lock.Tools["mytool"].Version)
+	assert.Len(t, lock.Tools["rawtool"].SHA256[platform], 64, "Unconfigured checksums should be recorded")
+
+	require.NoError(t, m.Install().Run(ctx))
+	assert.Equal(t, 2, requestCount(), "Installed tools should be reused")
+	require.NoError(t, m.Update().Run(ctx))
+	assert.Equal(t, 4, requestCount(), "Update should reinstall tools")
+
+	// Tamper with the locked checksum to simulate a changed download.
+	lock.Tools["rawtool"].SHA256[platform] = checksum
+	data, err = json.Marshal(lock)
+	require.NoError(t, err)
+	require.NoError(t, m.lockFile.WriteFile(data, 0644))
+	require.NoError(t, m.binDir.Join(".rawtool.stamp").Remove())
+	assert.ErrorIs(t, m.InstallTool("rawtool").Run(ctx), ErrChecksumMismatch)
+
+	assert.ErrorIs(t, m.InstallTool("missing").Run(ctx), ErrToolNotDeclared)
+	cmd := m.Command("mytool")
+	assert.Equal(t, m.Path("mytool").String(), cmd.cmd)
+	assert.ErrorIs(t, m.Command("missing").err, ErrToolNotDeclared)
+}
+
+func TestToolManager_GoTool(t *testing.T) {
+	tmp := Path(t.TempDir())
+	m := NewToolManager().BinDir(tmp.Join("bin")).LockFile(tmp.Join("tools.lock"))
+	pkg := Go().ToModulePath("testingbuild")
+	ctx := context.Background()
+
+	// Pre-build the tool, since installing a module version would require network access.
+	require.NoError(t, Go().Build(pkg).OutputFilename(m.Path("testingbuild")).Run(ctx))
+	info, err := buildinfo.ReadFile(m.Path("testingbuild").String())
+	require.NoError(t, err)
+	m.GoTool("testingbuild", pkg, info.Main.Version)
+	require.NoError(t, m.Install().Run(ctx))
+	lock := readToolLock(t, m)
+	assert.Equal(t, LockedTool{
+		Source:    "go",
+		Package:   pkg,
+		Requested: info.Main.Version,
+		Version:   info.Main.Version,
+	}, lock.Tools["testingbuild"])
+}
+
+func TestBuild_Lint_uses_tool_manager(t *testing.T) {
+	b := NewBuild()
+	linter := b.Lint("v2.1.0")
+	require.NotNil(t, linter.tools)
+	tool, err := linter.tools.tool(linterToolName)
+	require.NoError(t, err)
+	assert.Equal(t, "v2.1.0", tool.version)
+	assert.Equal(t, linterV2Package, tool.module)
+	_, ok := b.StepOk("install-tools")
+	assert.True(t, ok)
+	assert.Equal(t, Go().ModuleRoot().Join(DefaultToolsDir, "golangci-lint"), linter.tools.Path(linterToolName))
+}
@@ -0,0 +1,550 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf16"
+)
+
+const (
+	rtIcon      = 3
+	rtGroupIcon = 14
+	rtVersion   = 16
+	rtManifest  = 24
+	langEnUS    = 0x0409
+	codepageUTF = 1200
+)
+
+var defaultWindowsManifest = template.Must(template.New("manifest").Parse(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="{{ .Name }}" version="{{ .Version }}" processorArchitecture="*"/>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{35138b9a-5d96-4fbd-8e2d-a2440225f93a}"/>
+      <supportedOS Id="{4a2f28e3-53b9-4441-ba9c-d69d4a4a6e38}"/>
+      <supportedOS Id="{1f676c76-80e1-4239-95bb-83d0f6d0da78}"/>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+    </windowsSettings>
+  </application>
+</assembly>
+`))
+
+// coffMachine describes how to write a COFF object for a GOARCH.
+type coffMachine struct {
+	machine         uint16
+	characteristics uint16
+	relocType       uint16
+}
+
+var coffMachines = map[string]coffMachine{
+	"386":   {machine: 0x14c, characteristics: 0x0100, relocType: 7}, // IMAGE_REL_I386_DIR32NB
+	"amd64": {machine: 0x8664, characteristics: 0, relocType: 3},     // IMAGE_REL_AMD64_ADDR32NB
+	"arm":   {machine: 0x1c4, characteristics: 0x0100, relocType: 2}, // IMAGE_REL_ARM_ADDR32NB
+	"arm64": {machine: 0xaa64, characteristics: 0, relocType: 2},     // IMAGE_REL_ARM64_ADDR32NB
+}
+
+// WindowsResources describes resources embedded in a Windows executable, like an icon, version information, and an application manifest.
+// Resources are written as a COFF object (.syso file) that is linked into the executable by 'go build'.
+// Use NewWindowsResources to create a WindowsResources, and [AppBuild.WindowsResources] or [AppVariant.WindowsResources] to apply it to an AppBuild.
+type WindowsResources struct {
+	icon     PathString
+	manifest PathString
+	strings  map[string]string
+}
+
+// NewWindowsResources creates a new WindowsResources.
+// By default, version information is populated from the application name and version, and a default manifest is included that runs the application as the invoking user.
+func NewWindowsResources() *WindowsResources {
+	return &WindowsResources{
+		strings: map[string]string{},
+	}
+}
+
+// Icon sets the application icon from either an ICO file, or a PNG file.
+// PNG images will be scaled to the standard icon sizes.
+func (r *WindowsResources) Icon(icon PathString) *WindowsResources {
+	r.icon = icon
+	return r
+}
+
+// Manifest overrides the default application manifest with the contents of the given file.
+func (r *WindowsResources) Manifest(manifest PathString) *WindowsResources {
+	r.manifest = manifest
+	return r
+}
+
+// CompanyName sets the CompanyName version string.
+func (r *WindowsResources) CompanyName(name string) *WindowsResources {
+	return r.VersionString("CompanyName", name)
+}
+
+// Description sets the FileDescription version string, which defaults to the application name.
+func (r *WindowsResources) Description(description string) *WindowsResources {
+	return r.VersionString("FileDescription", description)
+}
+
+// Copyright sets the LegalCopyright version string.
+func (r *WindowsResources) Copyright(copyright string) *WindowsResources {
+	return r.VersionString("LegalCopyright", copyright)
+}
+
+// ProductName sets the ProductName version string, which defaults to the application name.
+func (r *WindowsResources) ProductName(name string) *WindowsResources {
+	return r.VersionString("ProductName", name)
+}
+
+// VersionString sets an arbitrary string in the version information, overriding any default value.
+func (r *WindowsResources) VersionString(key, value string) *WindowsResources {
+	key = strings.TrimSpace(key)
+	if len(key) == 0 {
+		panic("empty version string key")
+	}
+	r.strings[key] = value
+	return r
+}
+
+// WriteSyso creates a Task that writes the resources as a COFF object for the given GOARCH.
+// The output file name should end in "_windows_${GOARCH}.syso" so that it's only linked into matching builds.
+func (r *WindowsResources) WriteSyso(output PathString, arch, appName, version string) Task {
+	return func(ctx context.Context) error {
+		_, log := WithGroup(ctx, "windows resources")
+		data, err := r.syso(arch, appName, version)
+		if err != nil {
+			return log.WrapErr(err)
+		}
+		if err := output.WriteFile(data, 0644); err != nil {
+			return log.WrapErr(fmt.Errorf("failed to write resource object '%s': %w", output, err))
+		}
+		log.Debug("Wrote Windows resources to '%s'", output)
+		return nil
+	}
+}
+
+type resource struct {
+	typ, id uint32
+	data    []byte
+}
+
+func (r *WindowsResources) syso(arch, appName, version string) ([]byte, error) {
+	machine, ok := coffMachines[arch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported windows architecture '%s'", arch)
+	}
+	fileVersion := windowsVersion(version)
+	var resources []resource
+
+	if len(r.icon) > 0 {
+		icons, err := loadIcon(r.icon)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load icon '%s': %w", r.icon, err)
+		}
+		group := new(bytes.Buffer)
+		writeLE(group, uint16(0), uint16(1), uint16(len(icons)))
+		for i, icon := range icons {
+			id := uint16(i + 1)
+			writeLE(group, icon.width, icon.height, icon.colors, uint8(0), icon.planes, icon.bitCount, uint32(len(icon.data)), id)
+			resources = append(resources, resource{typ: rtIcon, id: uint32(id), data: icon.data})
+		}
+		resources = append(resources, resource{typ: rtGroupIcon, id: 1, data: group.Bytes()})
+	}
+
+	resources = append(resources, resource{typ: rtVersion, id: 1, data: r.versionInfo(appName, version, fileVersion)})
+
+	var manifest []byte
+	if len(r.manifest) > 0 {
+		data, err := r.manifest.ReadFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest '%s': %w", r.manifest, err)
+		}
+		manifest = data
+	} else {
+		var buf bytes.Buffer
+		err := defaultWindowsManifest.Execute(&buf, map[string]string{
+			"Name":    appName,
+			"Version": fmt.Sprintf("%d.%d.%d.%d", fileVersion[0], fileVersion[1], fileVersion[2], fileVersion[3]),
+		})
+		if err != nil {
+			return nil, err
+		}
+		manifest = buf.Bytes()
+	}
+	resources = append(resources, resource{typ: rtManifest, id: 1, data: manifest})
+	return writeResourceObject(machine, resources), nil
+}
+
+// windowsVersion converts a semantic version to the four part version number used by Windows.
+// Pre-release and build information is dropped.
+func windowsVersion(version string) [4]uint16 {
+	var parts [4]uint16
+	version = strings.TrimPrefix(version, "v")
+	if idx := strings.IndexAny(version, "-+"); idx >= 0 {
+		version = version[:idx]
+	}
+	for i, part := range strings.SplitN(version, ".", 4) {
+		n, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			break
+		}
+		parts[i] = uint16(n)
+	}
+	return parts
+}
+
+func (r *WindowsResources) versionInfo(appName, version string, fileVersion [4]uint16) []byte {
+	values := map[string]string{
+		"FileDescription":  appName,
+		"FileVersion":      version,
+		"InternalName":     appName,
+		"OriginalFilename": appName + ".exe",
+		"ProductName":      appName,
+		"ProductVersion":   version,
+	}
+	for k, v := range r.strings {
+		values[k] = v
+	}
+	keys := keySlice(values)
+	sort.Strings(keys)
+	var stringNodes [][]byte
+	for _, key := range keys {
+		value := utf16z(values[key])
+		stringNodes = append(stringNodes, versionNode(key, value, uint16(len(value)/2), 1))
+	}
+	table := versionNode(fmt.Sprintf("%04X%04X", langEnUS, codepageUTF), nil, 0, 1, stringNodes...)
+	stringFileInfo := versionNode("StringFileInfo", nil, 0, 1, table)
+
+	translation := new(bytes.Buffer)
+	writeLE(translation, uint16(langEnUS), uint16(codepageUTF))
+	varNode := versionNode("Translation", translation.Bytes(), uint16(translation.Len()), 0)
+	varFileInfo := versionNode("VarFileInfo", nil, 0, 1, varNode)
+
+	fixed := new(bytes.Buffer)
+	ms := uint32(fileVersion[0])<<16 | uint32(fileVersion[1])
+	ls := uint32(fileVersion[2])<<16 | uint32(fileVersion[3])
+	writeLE(fixed,
+		uint32(0xFEEF04BD), // Signature
+		uint32(0x00010000), // Structure version
+		ms, ls,             // File version
+		ms, ls, // Product version
+		uint32(0x3F),         // File flags mask
+		uint32(0),            // File flags
+		uint32(0x00040004),   // VOS_NT_WINDOWS32
+		uint32(1),            // VFT_APP
+		uint32(0),            // File subtype
+		uint32(0), uint32(0), // File date
+	)
+	return versionNode("VS_VERSION_INFO", fixed.Bytes(), uint16(fixed.Len()), 0, stringFileInfo, varFileInfo)
+}
+
+// versionNode encodes a structure of the VS_VERSIONINFO tree, where each node has a length, value length, type, key, value, and children.
+func versionNode(key string, value []byte, valueLength, typ uint16, children ...[]byte) []byte {
+	buf := new(bytes.Buffer)
+	writeLE(buf, uint16(0), valueLength, typ)
+	buf.Write(utf16z(key))
+	pad32(buf)
+	buf.Write(value)
+	for _, child := range children {
+		pad32(buf)
+		buf.Write(child)
+	}
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint16(data, uint16(len(data)))
+	return data
+}
+
+func utf16z(s string) []byte {
+	encoded := utf16.Encode([]rune(s))
+	buf := make([]byte, 0, (len(encoded)+1)*2)
+	for _, c := range append(encoded, 0) {
+		buf = binary.LittleEndian.AppendUint16(buf, c)
+	}
+	return buf
+}
+
+func pad32(buf *bytes.Buffer) {
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+func writeLE(buf *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		// Writing to a bytes.Buffer will not fail for fixed size values.
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+// writeResourceObject lays out a COFF object file with a single .rsrc section holding the resource directory tree.
+func writeResourceObject(machine coffMachine, resources []resource) []byte {
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].typ != resources[j].typ {
+			return resources[i].typ < resources[j].typ
+		}
+		return resources[i].id < resources[j].id
+	})
+	var types []uint32
+	byType := map[uint32][]resource{}
+	for _, res := range resources {
+		if _, ok := byType[res.typ]; !ok {
+			types = append(types, res.typ)
+		}
+		byType[res.typ] = append(byType[res.typ], res)
+	}
+
+	const (
+		dirSize       = 16
+		entrySize     = 8
+		dataEntrySize = 16
+		subdirFlag    = 0x80000000
+	)
+	// Compute the size of the directory tables: one root, one per type, and one language table per resource.
+	tableSize := func(entries int) int { return dirSize + entries*entrySize }
+	dirBytes := tableSize(len(types))
+	for _, typ := range types {
+		dirBytes += tableSize(len(byType[typ]))
+		dirBytes += len(byType[typ]) * tableSize(1)
+	}
+	dataEntriesOffset := dirBytes
+	dataOffset := dataEntriesOffset + len(resources)*dataEntrySize
+
+	var (
+		section     = make([]byte, dataOffset)
+		relocations []uint32
+		dataEntry   = 0
+		nextTable   = tableSize(len(types))
+	)
+	putTable := func(offset, entries int) {
+		binary.LittleEndian.PutUint16(section[offset+14:], uint16(entries))
+	}
+	putEntry := func(offset int, id, target uint32) {
+		binary.LittleEndian.PutUint32(section[offset:], id)
+		binary.LittleEndian.PutUint32(section[offset+4:], target)
+	}
+	putTable(0, len(types))
+	for i, typ := range types {
+		typeTable := nextTable
+		putEntry(dirSize+i*entrySize, typ, subdirFlag|uint32(typeTable))
+		entries := byType[typ]
+		putTable(typeTable, len(entries))
+		nextTable += tableSize(len(entries))
+		for j, res := range entries {
+			langTable := nextTable
+			putEntry(typeTable+dirSize+j*entrySize, res.id, subdirFlag|uint32(langTable))
+			putTable(langTable, 1)
+			nextTable += tableSize(1)
+
+			entryOffset := dataEntriesOffset + dataEntry*dataEntrySize
+			putEntry(langTable+dirSize, langEnUS, uint32(entryOffset))
+			dataEntry++
+
+			for len(section)%8 != 0 {
+				section = append(section, 0)
+			}
+			// The data RVA is relative to the section until relocated by the linker.
+			binary.LittleEndian.PutUint32(section[entryOffset:], uint32(len(section)))
+			binary.LittleEndian.PutUint32(section[entryOffset+4:], uint32(len(res.data)))
+			relocations = append(relocations, uint32(entryOffset))
+			section = append(section, res.data...)
+		}
+	}
+	for len(section)%4 != 0 {
+		section = append(section, 0)
+	}
+
+	const (
+		fileHeaderSize    = 20
+		sectionHeaderSize = 40
+		relocationSize    = 10
+	)
+	sectionStart := fileHeaderSize + sectionHeaderSize
+	relocStart := sectionStart + len(section)
+	symbolStart := relocStart + len(relocations)*relocationSize
+
+	obj := new(bytes.Buffer)
+	// File header
+	writeLE(obj,
+		machine.machine,
+		uint16(1),           // Number of sections
+		uint32(0),           // Timestamp, zero for reproducible builds
+		uint32(symbolStart), // Symbol table offset
+		uint32(1),           // Number of symbols
+		uint16(0),           // Optional header size
+		machine.characteristics,
+	)
+	// Section header
+	obj.WriteString(".rsrc\x00\x00\x00")
+	writeLE(obj,
+		uint32(0), // Virtual size
+		uint32(0), // Virtual address
+		uint32(len(section)),
+		uint32(sectionStart),
+		uint32(relocStart),
+		uint32(0), // Line numbers
+		uint16(len(relocations)),
+		uint16(0),          // Number of line numbers
+		uint32(0x40000040), // IMAGE_SCN_CNT_INITIALIZED_DATA | IMAGE_SCN_MEM_READ
+	)
+	obj.Write(section)
+	for _, reloc := range relocations {
+		writeLE(obj, reloc, uint32(0), machine.relocType)
+	}
+	// Section symbol that relocations reference
+	obj.WriteString(".rsrc\x00\x00\x00")
+	writeLE(obj,
+		uint32(0), // Value
+		uint16(1), // Section number
+		uint16(0), // Type
+		uint8(3),  // IMAGE_SYM_CLASS_STATIC
+		uint8(0),  // Aux symbols
+	)
+	// Empty string table
+	writeLE(obj, uint32(4))
+	return obj.Bytes()
+}
+
+type iconImage struct {
+	width, height, colors uint8
+	planes, bitCount      uint16
+	data                  []byte
+}
+
+var iconSizes = []int{256, 64, 48, 32, 16}
+
+func loadIcon(file PathString) ([]iconImage, error) {
+	data, err := file.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("\x89PNG")) {
+		return pngIcon(data)
+	}
+	return icoImages(data)
+}
+
+func icoImages(data []byte) ([]iconImage, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data) != 0 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errors.New("not an ICO or PNG file")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < 6+count*16 {
+		return nil, errors.New("invalid ICO header")
+	}
+	images := make([]iconImage, count)
+	for i := 0; i < count; i++ {
+		entry := data[6+i*16:]
+		size := binary.LittleEndian.Uint32(entry[8:])
+		offset := binary.LittleEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("ICO image %d extends past the end of the file", i)
+		}
+		images[i] = iconImage{
+			width:    entry[0],
+			height:   entry[1],
+			colors:   entry[2],
+			planes:   binary.LittleEndian.Uint16(entry[4:]),
+			bitCount: binary.LittleEndian.Uint16(entry[6:]),
+			data:     data[offset : offset+size],
+		}
+	}
+	return images, nil
+}
+
+func pngIcon(data []byte) ([]iconImage, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	largest := bounds.Dx()
+	if bounds.Dy() > largest {
+		largest = bounds.Dy()
+	}
+	var images []iconImage
+	for _, size := range iconSizes {
+		if size > largest && size != iconSizes[len(iconSizes)-1] {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, scaleImage(src, size)); err != nil {
+			return nil, err
+		}
+		dim := uint8(size)
+		if size >= 256 {
+			dim = 0 // Zero means 256 in icon directories.
+		}
+		images = append(images, iconImage{width: dim, height: dim, planes: 1, bitCount: 32, data: buf.Bytes()})
+	}
+	return images, nil
+}
+
+// scaleImage scales the source image to a size x size square with area averaging, centering it if it isn't square.
+func scaleImage(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	scale := float64(size) / float64(w)
+	if h > w {
+		scale = float64(size) / float64(h)
+	}
+	offX := (float64(size) - float64(w)*scale) / 2
+	offY := (float64(size) - float64(h)*scale) / 2
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			x0 := int((float64(x) - offX) / scale)
+			y0 := int((float64(y) - offY) / scale)
+			x1 := int((float64(x+1) - offX) / scale)
+			y1 := int((float64(y+1) - offY) / scale)
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			if y1 <= y0 {
+				y1 = y0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					if sx < 0 || sy < 0 || sx >= w || sy >= h {
+						continue
+					}
+					c := color.NRGBAModel.Convert(src.At(bounds.Min.X+sx, bounds.Min.Y+sy)).(color.NRGBA)
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					b += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			if n == 0 || a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / a),
+				G: uint8(g / a),
+				B: uint8(b / a),
+				A: uint8(a / n),
+			})
+		}
+	}
+	return dst
+}
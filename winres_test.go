@@ -0,0 +1,133 @@
+package modmake
+
+import (
+	"bytes"
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIcon(t *testing.T) PathString {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	icon := Path(t.TempDir(), "icon.png")
+	require.NoError(t, icon.WriteFile(buf.Bytes(), 0644))
+	return icon
+}
+
+// resourceIDs walks the resource directory tree, returning the resource IDs found for each type.
+// The base is the virtual address of the section, which is zero in an unlinked object.
+func resourceIDs(t *testing.T, data []byte, base uint32) map[uint32][]uint32 {
+	entries := func(offset uint32) [][2]uint32 {
+		named := binary.LittleEndian.Uint16(data[offset+12:])
+		ids := binary.LittleEndian.Uint16(data[offset+14:])
+		var result [][2]uint32
+		for i := uint32(0); i < uint32(named+ids); i++ {
+			entry := data[offset+16+i*8:]
+			result = append(result, [2]uint32{binary.LittleEndian.Uint32(entry), binary.LittleEndian.Uint32(entry[4:])})
+		}
+		return result
+	}
+	found := map[uint32][]uint32{}
+	for _, typ := range entries(0) {
+		require.NotZero(t, typ[1]&0x80000000, "Type entries should point to a subdirectory")
+		for _, id := range entries(typ[1] &^ 0x80000000) {
+			require.NotZero(t, id[1]&0x80000000, "Name entries should point to a subdirectory")
+			langs := entries(id[1] &^ 0x80000000)
+			require.Len(t, langs, 1)
+			assert.Equal(t, uint32(langEnUS), langs[0][0])
+			dataEntry := data[langs[0][1]:]
+			rva := binary.LittleEndian.Uint32(dataEntry) - base
+			size := binary.LittleEndian.Uint32(dataEntry[4:])
+			require.LessOrEqual(t, int(rva+size), len(data), "Resource data should be within the section")
+			found[typ[0]] = append(found[typ[0]], id[0])
+		}
+	}
+	return found
+}
+
+func TestWindowsResources_Syso(t *testing.T) {
+	res := NewWindowsResources().
+		Icon(testIcon(t)).
+		CompanyName("Saylor Solutions").
+		Copyright("Copyright 2024")
+
+	for arch, machine := range map[string]uint16{
+		"386":   pe.IMAGE_FILE_MACHINE_I386,
+		"amd64": pe.IMAGE_FILE_MACHINE_AMD64,
+		"arm64": pe.IMAGE_FILE_MACHINE_ARM64,
+	} {
+		t.Run(arch, func(t *testing.T) {
+			data, err := res.syso(arch, "testapp", "1.2.3-rc.1")
+			require.NoError(t, err)
+			f, err := pe.NewFile(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, machine, f.Machine)
+			rsrc := f.Section(".rsrc")
+			require.NotNil(t, rsrc)
+			section, err := rsrc.Data()
+			require.NoError(t, err)
+
+			ids := resourceIDs(t, section, 0)
+			assert.Equal(t, []uint32{1, 2, 3, 4, 5}, ids[rtIcon], "PNG should be scaled to all icon sizes")
+			assert.Equal(t, []uint32{1}, ids[rtGroupIcon])
+			assert.Equal(t, []uint32{1}, ids[rtVersion])
+			assert.Equal(t, []uint32{1}, ids[rtManifest])
+			assert.Len(t, rsrc.Relocs, 8, "Every data entry should be relocated")
+			assert.Len(t, f.Symbols, 1)
+			assert.True(t, bytes.Contains(section, utf16z("Saylor Solutions")))
+			assert.True(t, bytes.Contains(section, []byte(`version="1.2.3.0"`)))
+		})
+	}
+
+	_, err := res.syso("mips", "testapp", "1.0.0")
+	assert.Error(t, err, "Unsupported architectures should be rejected")
+}
+
+func TestWindowsVersion(t *testing.T) {
+	assert.Equal(t, [4]uint16{1, 2, 3, 0}, windowsVersion("v1.2.3-rc.1+build"))
+	assert.Equal(t, [4]uint16{0, 1, 0, 0}, windowsVersion("0.1.0"))
+}
+
+func TestAppBuild_WindowsResources(t *testing.T) {
+	prev := BuildPath
+	BuildPath = Path(t.TempDir()).String()
+	defer func() {
+		BuildPath = prev
+	}()
+	a := NewAppBuild("testingbuild", "testingbuild", "1.0.0").
+		WindowsResources(NewWindowsResources().Icon(testIcon(t)).CompanyName("Saylor Solutions"))
+	v := a.Variant("windows", "amd64").Build(func(gb *GoBuild) {
+		gb.CgoEnabled(false)
+	})
+	ctx := context.Background()
+	require.NoError(t, a.cleanTask(v).Then(a.buildTask(v)).Run(ctx))
+	assert.False(t, a.sysoPath(v, a.binaries()[0]).Exists(), "Resource object should be removed after building")
+
+	f, err := pe.Open(v.buildOutput.String())
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	rsrc := f.Section(".rsrc")
+	require.NotNil(t, rsrc, "Executable should include resources")
+	data, err := rsrc.Data()
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(data, utf16z("Saylor Solutions")))
+	ids := resourceIDs(t, data, rsrc.VirtualAddress)
+	assert.Equal(t, []uint32{1}, ids[rtVersion], "Data entries should be relocated to the linked section")
+	assert.Equal(t, []uint32{1}, ids[rtManifest])
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"strings"
+	"time"
 )
 
 // ZipArchive represents a Runner that performs operations on a tar archive that uses gzip compression.
@@ -15,6 +17,9 @@ type ZipArchive struct {
 	err      error
 	path     PathString
 	addFiles map[PathString]string
+	addDirs  []archiveDir
+	addSets  []archiveFileSet
+	modTime  time.Time
 }
 
 // Zip will create a new ZipArchive to contextualize follow-on operations that act on a zip file.
@@ -52,6 +57,36 @@ func (z *ZipArchive) AddFileWithPath(sourcePath, archivePath PathString) *ZipArc
 	return z
 }
 
+// AddDir recursively adds the contents of the source directory, with archive paths under the archivePrefix.
+// The archivePrefix will be converted to slash format, and may be empty to add the contents at the root of the archive.
+// Directory entries are included in the archive, and symlinks are preserved unless options say otherwise.
+// The directory is walked when the archive is written, so it doesn't need to exist until then.
+func (z *ZipArchive) AddDir(sourceDir, archivePrefix PathString, options ...ArchiveDirOption) *ZipArchive {
+	if z.err != nil {
+		return z
+	}
+	z.addDirs = append(z.addDirs, newArchiveDir(sourceDir, archivePrefix, options))
+	return z
+}
+
+// AddFileSet adds the files selected by the [FileSet], with archive paths relative to its root under the archivePrefix.
+// The archivePrefix will be converted to slash format, and may be empty to add the files at the root of the archive.
+// The FileSet is evaluated when the archive is written.
+func (z *ZipArchive) AddFileSet(set *FileSet, archivePrefix PathString) *ZipArchive {
+	if z.err != nil {
+		return z
+	}
+	z.addSets = append(z.addSets, newArchiveFileSet(set, archivePrefix))
+	return z
+}
+
+// ModTime sets a fixed modification time for all entries, so the archive is reproducible.
+// Entries are always written in sorted order.
+func (z *ZipArchive) ModTime(modTime time.Time) *ZipArchive {
+	z.modTime = modTime
+	return z
+}
+
 // Create will return a Runner that creates a new zip file with the given files loaded.
 // If a file with the given name already exists, then it will be truncated first.
 // Ensure that all files referenced with AddFile (or AddFileWithPath) and directories exist before running this Runner, because it doesn't try to create them.
@@ -105,28 +140,52 @@ func (z *ZipArchive) Update() Task {
 
 func (z *ZipArchive) writeFilesToZipArchive(ctx context.Context, zw *zip.Writer) error {
 	ctx, log := WithGroup(ctx, "write files")
-	for source, target := range z.addFiles {
+	entries, err := collectArchiveEntries(z.addFiles, z.addDirs, z.addSets)
+	if err != nil {
+		return log.WrapErr(err)
+	}
+	for _, entry := range entries {
 		if err := ctx.Err(); err != nil {
 			return log.WrapErr(err)
 		}
-		source, target := source, target
+		entry := entry
 		err := func() error {
-			f, err := source.Open()
+			header, err := zip.FileInfoHeader(entry.info)
 			if err != nil {
+				return fmt.Errorf("failed to get file info for '%s': %w", entry.source, err)
+			}
+			header.Name = entry.name
+			header.SetMode(entry.info.Mode().Type() | entry.perm())
+			if !z.modTime.IsZero() {
+				header.Modified = z.modTime
+			}
+			switch {
+			case entry.isDir():
+				header.Method = zip.Store
+				_, err := zw.CreateHeader(header)
+				return err
+			case entry.isSymlink():
+				w, err := zw.CreateHeader(header)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(w, entry.linkname)
 				return err
 			}
-			defer func() {
-				_ = f.Close()
-			}()
-			t, err := zw.Create(target)
+			header.Method = zip.Deflate
+			f, err := entry.source.Open()
 			if err != nil {
 				return err
 			}
-			_, err = io.Copy(t, f)
+			defer func() {
+				_ = f.Close()
+			}()
+			w, err := zw.CreateHeader(header)
 			if err != nil {
 				return err
 			}
-			return nil
+			_, err = io.Copy(w, f)
+			return err
 		}()
 		if err != nil {
 			return log.WrapErr(err)
@@ -137,7 +196,11 @@ func (z *ZipArchive) writeFilesToZipArchive(ctx context.Context, zw *zip.Writer)
 
 // Extract will extract the named zip archive to the given directory.
 // Any errors encountered while doing so will be immediately returned.
-func (z *ZipArchive) Extract(extractDir PathString) Task {
+//
+// Entries that would be written outside the extraction directory are rejected with [ErrUnsafeArchiveEntry], including through symlinks.
+// File modes and modification times are restored, and size and entry limits are enforced to guard against decompression bombs.
+// Options may be passed to change these limits, or to allow unsafe entries from a trusted archive.
+func (z *ZipArchive) Extract(extractDir PathString, options ...ExtractOption) Task {
 	runner := Task(func(ctx context.Context) error {
 		ctx, log := WithGroup(ctx, "zip extract")
 		src, err := z.path.Open()
@@ -151,9 +214,9 @@ func (z *ZipArchive) Extract(extractDir PathString) Task {
 		if err != nil {
 			return log.WrapErr(fmt.Errorf("unable to get file information for the source zip file: %w", err))
 		}
-		err = extractDir.MkdirAll(0700)
+		ex, err := newExtractor(extractDir, options)
 		if err != nil {
-			return log.WrapErr(fmt.Errorf("unable to create extraction directory: %w", err))
+			return log.WrapErr(err)
 		}
 		zr, err := zip.NewReader(src, fi.Size())
 		if err != nil {
@@ -165,17 +228,9 @@ func (z *ZipArchive) Extract(extractDir PathString) Task {
 				return err
 			}
 			err := func() error {
-				output := extractDir.Join(f.Name)
-				if strings.HasSuffix(output.String(), "/") {
-					err := output.MkdirAll(0700)
-					if err != nil {
-						return fmt.Errorf("failed to create parent directory '%s': %w", output, err)
-					}
-					return nil
-				}
-				outputDir := output.Dir()
-				if err := outputDir.MkdirAll(0700); err != nil {
-					return fmt.Errorf("failed to make parent directory for file '%s' at '%s': %w", f.Name, outputDir, err)
+				mode := f.Mode()
+				if mode.IsDir() || strings.HasSuffix(f.Name, "/") {
+					return ex.dir(f.Name, mode, f.Modified)
 				}
 				zipFile, err := f.Open()
 				if err != nil {
@@ -184,24 +239,26 @@ func (z *ZipArchive) Extract(extractDir PathString) Task {
 				defer func() {
 					_ = zipFile.Close()
 				}()
-				out, err := output.Create()
-				if err != nil {
-					return fmt.Errorf("failed to create file '%s': %w", output, err)
-				}
-				defer func() {
-					_ = out.Close()
-				}()
-				_, err = io.Copy(out, zipFile) //nolint:gosec // It's the user's responsibility to verify the safety of zip inputs in this case.
-				if err != nil {
-					return fmt.Errorf("failed to extract '%s': %w", output, err)
+				switch {
+				case mode&fs.ModeSymlink != 0:
+					// The content of a symlink entry is its target, which is only a path.
+					linkname, err := io.ReadAll(io.LimitReader(zipFile, 4096))
+					if err != nil {
+						return fmt.Errorf("failed to read symlink '%s': %w", f.Name, err)
+					}
+					return ex.symlink(f.Name, string(linkname))
+				case mode.IsRegular():
+					return ex.file(f.Name, mode, f.Modified, zipFile)
+				default:
+					log.Warn("Skipping unsupported entry '%s' with mode '%s'", f.Name, mode)
+					return nil
 				}
-				return nil
 			}()
 			if err != nil {
 				return log.WrapErr(err)
 			}
 		}
-		return nil
+		return log.WrapErr(ex.finish())
 	})
 	return ContextAware(runner)
 }